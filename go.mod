@@ -1,9 +1,16 @@
 module github.com/vulpine-io/midl
 
-go 1.14
+go 1.23
 
 require (
-	github.com/gorilla/mux v1.7.4
 	github.com/smartystreets/goconvey v1.6.4
 	github.com/xeipuuv/gojsonschema v1.2.0
 )
+
+require (
+	github.com/gopherjs/gopherjs v0.0.0-20181017120253-0766667cb4d1 // indirect
+	github.com/jtolds/gls v4.20.0+incompatible // indirect
+	github.com/smartystreets/assertions v0.0.0-20180927180507-b2de0cb4f26d // indirect
+	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
+	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
+)
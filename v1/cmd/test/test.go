@@ -4,10 +4,8 @@ import (
 	"log"
 	"net/http"
 
-	"github.com/gorilla/mux"
-	gjs "github.com/xeipuuv/gojsonschema"
-
 	"github.com/vulpine-io/midl/v1/pkg/midl"
+	"github.com/vulpine-io/midl/v1/pkg/midlschema"
 )
 
 // input is a simple JSON schema example which will be used
@@ -35,22 +33,14 @@ const input = `{
 }`
 
 func main() {
-	schema, err := gjs.NewSchema(gjs.NewStringLoader(input))
-	if err != nil {
-		panic(err)
-	}
-
-	r := mux.NewRouter()
-
-	r.Handle("/combine", midl.XMLAdapter(
-		Validator{schema},
-		midl.MiddlewareFunc(Controller),
-	)).Queries("xml", "").Methods(http.MethodPost)
+	r := midl.NewRouter(func(next ...midl.Middleware) midl.Adapter {
+		return midl.JSONXMLAdapter(next...)
+	})
 
-	r.Handle("/combine", midl.JSONAdapter(
-		Validator{schema},
+	r.Route(http.MethodPost, "/combine",
+		midlschema.MustBody(midlschema.String(input)),
 		midl.MiddlewareFunc(Controller),
-	)).Methods(http.MethodPost)
+	)
 
 	log.Fatal(http.ListenAndServe(":8080", r))
 }
@@ -0,0 +1,151 @@
+package midl
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// mediaRange is a single parsed entry from an Accept
+// header.
+type mediaRange struct {
+	kind    string
+	subKind string
+	quality float64
+}
+
+// specificity ranks how closely the media range targets a
+// concrete type; exact types beat "type/*" which beats
+// "*/*".
+func (m mediaRange) specificity() int {
+	switch {
+	case m.kind == "*":
+		return 0
+	case m.subKind == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// matches returns whether the given type/subtype pair is
+// covered by the media range.
+func (m mediaRange) matches(kind, subKind string) bool {
+	if m.kind == "*" {
+		return true
+	}
+
+	if m.kind != kind {
+		return false
+	}
+
+	return m.subKind == "*" || m.subKind == subKind
+}
+
+// parseAccept parses the values of one or more Accept
+// headers into a list of media ranges.
+//
+// Malformed entries are skipped rather than rejecting the
+// whole header.
+func parseAccept(values []string) []mediaRange {
+	var out []mediaRange
+
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if rng, ok := parseMediaRange(part); ok {
+				out = append(out, rng)
+			}
+		}
+	}
+
+	return out
+}
+
+func parseMediaRange(in string) (mediaRange, bool) {
+	params := strings.Split(in, ";")
+	kind, subKind, ok := splitMediaType(params[0])
+	if !ok {
+		return mediaRange{}, false
+	}
+
+	q, ok := parseQuality(params[1:])
+	if !ok {
+		return mediaRange{}, false
+	}
+
+	return mediaRange{kind: kind, subKind: subKind, quality: q}, true
+}
+
+// parseQuality returns the value of the "q" parameter from
+// the given header parameters, defaulting to 1.
+func parseQuality(params []string) (float64, bool) {
+	out := 1.0
+
+	for _, param := range params {
+		key := strings.TrimSpace(param)
+		pos := strings.IndexByte(key, '=')
+		if pos < 0 || !strings.EqualFold(strings.TrimSpace(key[:pos]), "q") {
+			continue
+		}
+
+		q, err := strconv.ParseFloat(strings.TrimSpace(key[pos+1:]), 64)
+		if err != nil || q < 0 || q > 1 {
+			return 0, false
+		}
+		out = q
+	}
+
+	return out, true
+}
+
+// splitMediaType splits a media type string, with or
+// without parameters, into its lower cased type and
+// subtype.
+func splitMediaType(in string) (kind, subKind string, ok bool) {
+	if pos := strings.IndexByte(in, ';'); pos > -1 {
+		in = in[:pos]
+	}
+
+	in = strings.ToLower(strings.TrimSpace(in))
+	pos := strings.IndexByte(in, '/')
+	if pos < 1 || pos == len(in)-1 {
+		return "", "", false
+	}
+
+	kind, subKind = in[:pos], in[pos+1:]
+	if kind == "*" && subKind != "*" {
+		return "", "", false
+	}
+
+	return kind, subKind, true
+}
+
+// acceptQuality returns the quality value the given media
+// ranges assign to the given media type, using the most
+// specific matching range.  Returns 0 if no range matches.
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	full, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		full = mediaType
+	}
+
+	kind, subKind, ok := splitMediaType(full)
+	if !ok {
+		return 0
+	}
+
+	best := -1
+	quality := 0.0
+	for _, rng := range ranges {
+		if !rng.matches(kind, subKind) {
+			continue
+		}
+
+		if spec := rng.specificity(); spec > best {
+			best = spec
+			quality = rng.quality
+		}
+	}
+
+	return quality
+}
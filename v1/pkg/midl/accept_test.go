@@ -0,0 +1,63 @@
+package midl
+
+import (
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseAccept(t *testing.T) {
+	c.Convey("parses media ranges and quality values", t, func() {
+		out := parseAccept([]string{"text/html, application/*;q=0.5", "*/*;q=0.1"})
+
+		c.So(out, c.ShouldResemble, []mediaRange{
+			{kind: "text", subKind: "html", quality: 1},
+			{kind: "application", subKind: "*", quality: 0.5},
+			{kind: "*", subKind: "*", quality: 0.1},
+		})
+	})
+
+	c.Convey("skips malformed entries", t, func() {
+		out := parseAccept([]string{"text, */json, application/json;q=2, text/plain"})
+
+		c.So(out, c.ShouldResemble, []mediaRange{
+			{kind: "text", subKind: "plain", quality: 1},
+		})
+	})
+
+	c.Convey("ignores non quality parameters", t, func() {
+		out := parseAccept([]string{"Text/HTML;level=1;q=0.3"})
+
+		c.So(out, c.ShouldResemble, []mediaRange{
+			{kind: "text", subKind: "html", quality: 0.3},
+		})
+	})
+}
+
+func TestAcceptQuality(t *testing.T) {
+	c.Convey("uses the most specific matching range", t, func() {
+		ranges := parseAccept([]string{"*/*;q=0.1, application/*;q=0.5, application/xml;q=0.8"})
+
+		c.So(acceptQuality(ranges, "application/xml"), c.ShouldEqual, 0.8)
+		c.So(acceptQuality(ranges, "application/json"), c.ShouldEqual, 0.5)
+		c.So(acceptQuality(ranges, "text/plain"), c.ShouldEqual, 0.1)
+	})
+
+	c.Convey("ignores parameters on the offered type", t, func() {
+		ranges := parseAccept([]string{"application/json"})
+
+		c.So(acceptQuality(ranges, "application/json; charset=utf-8"), c.ShouldEqual, 1)
+	})
+
+	c.Convey("returns zero when nothing matches", t, func() {
+		ranges := parseAccept([]string{"text/html"})
+
+		c.So(acceptQuality(ranges, "application/json"), c.ShouldEqual, 0)
+	})
+
+	c.Convey("honors explicit exclusions", t, func() {
+		ranges := parseAccept([]string{"*/*, application/xml;q=0"})
+
+		c.So(acceptQuality(ranges, "application/xml"), c.ShouldEqual, 0)
+	})
+}
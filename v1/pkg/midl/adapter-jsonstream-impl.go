@@ -0,0 +1,487 @@
+package midl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// DefaultFlushInterval is the interval at which a
+// JSONStreamAdapter flushes buffered records to the client
+// when no other interval is configured.
+const DefaultFlushInterval = time.Second
+
+// JSONStreamFormat selects how a JSONStreamAdapter frames
+// the records it writes.
+type JSONStreamFormat int
+
+const (
+	// NDJSON writes each record on its own line, served as
+	// "application/x-ndjson".
+	NDJSON JSONStreamFormat = iota
+
+	// JSONArray writes the records as the elements of a
+	// single JSON array, served as "application/json".
+	JSONArray
+)
+
+func (f JSONStreamFormat) contentType() string {
+	if f == JSONArray {
+		return "application/json"
+	}
+	return "application/x-ndjson"
+}
+
+// RecordProducer is a response body which produces the
+// records streamed by a JSONStreamAdapter.
+//
+// The producer calls emit once per record.  Emit returns an
+// error once the stream cannot continue, either because the
+// client went away or the record could not be serialized,
+// in which case the producer should return that error.
+// Emit must not be called concurrently, nor after the
+// producer has returned.
+//
+// The given context is the request's context, and is
+// cancelled when the client disconnects.
+type RecordProducer func(ctx context.Context, emit func(record interface{}) error) error
+
+// JSONStreamAdapter is an Adapter which serializes each
+// element of a response body as it is produced, rather than
+// the body as a whole.
+//
+//   handler := StreamingJSONAdapter(midl.MiddlewareFunc(func(req midl.Request) midl.Response {
+//       return midl.MakeResponse(http.StatusOK, db.Rows(req.Context())) // iter.Seq2[Row, error]
+//   })).Format(midl.JSONArray)
+//
+// Middleware return a Response whose body is one of:
+//
+//   a receive capable channel of any element type
+//   iter.Seq[T], or any func(yield func(T) bool)
+//   iter.Seq2[T, error], or any func(yield func(T, error) bool)
+//   RecordProducer
+//
+// Records are serialized with the adapter's Serializer,
+// which must not produce multi-line output when writing
+// NDJSON.
+//
+// Records are buffered and flushed at the configured
+// interval, as well as when the stream ends.
+//
+// Once the status line has been sent, errors can no longer
+// change the response status.  An error returned by a
+// producer or yielded by an iter.Seq2, a failure to
+// serialize a record, or a panic, ends the stream with a
+// final record holding the output of the ErrorSerializer;
+// with the JSONArray format this record is the last element
+// of the array.
+//
+// The stream ends without an error record if the client
+// disconnects, which is detected through cancellation of
+// the request's context or a failed write.
+type JSONStreamAdapter interface {
+	Adapter
+
+	// Format sets how records are framed.  Defaults to NDJSON.
+	Format(format JSONStreamFormat) JSONStreamAdapter
+
+	// FlushInterval sets the interval at which buffered
+	// records are flushed to the client.  A value of zero
+	// flushes after every record.  Defaults to
+	// DefaultFlushInterval.
+	FlushInterval(interval time.Duration) JSONStreamAdapter
+}
+
+// StreamingJSONAdapter creates a new JSONStreamAdapter
+// instance which serializes records and errors as JSON.
+func StreamingJSONAdapter(handlers ...Middleware) JSONStreamAdapter {
+	return &jsonStreamAdapter{
+		adapter: adapter{
+			contentType:   "application/json",
+			serializer:    SerializerFunc(json.Marshal),
+			errSerializer: DefaultJSONErrorSerializer(),
+			emptyHandler:  DefaultEmptyHandler(),
+			handlers:      handlers,
+		},
+		flush: DefaultFlushInterval,
+	}
+}
+
+var (
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	producerType = reflect.TypeOf(RecordProducer(nil))
+)
+
+type jsonStreamAdapter struct {
+	adapter
+
+	format JSONStreamFormat
+	flush  time.Duration
+}
+
+func (s jsonStreamAdapter) ServeHTTP(w writer, r *http.Request) {
+	d := s.adapter
+
+	if d.cors.handle(w, r) {
+		return
+	}
+
+	w, finish := startCompression(d.compress, w, r)
+	defer finish()
+
+	req, err := NewRequest(r)
+	if err != nil {
+		d.writeError(w, err, req, NewResponse())
+		return
+	}
+	defer closeRequest(req)
+	setFormOptions(req, d.form)
+	setCompression(req, d.compress)
+
+	if err := limitBody(w, r, d.maxBody); err != nil {
+		d.writeError(w, err, req, MakeErrorResponse(http.StatusRequestEntityTooLarge, err))
+		return
+	}
+
+	if err := decodeBody(r, d.decompress); err != nil {
+		d.writeError(w, err, req, unsupportedEncoding(d.decompress, err))
+		return
+	}
+
+	res := runPipeline(req, d.wrappers, d.handlers, d.panicHandler)
+
+	if res.Error() != nil {
+		d.writeError(w, res.Error(), req, res)
+		return
+	}
+
+	if res.Body() == nil {
+		d.writeEmpty(w, req, res)
+		return
+	}
+
+	produce, ok := recordSource(res.Body())
+	if !ok {
+		err := fmt.Errorf("midl: unsupported record stream body type %T", res.Body())
+		d.writeError(w, err, req, res.SetCode(http.StatusInternalServerError))
+		return
+	}
+
+	if s.precondition(w, req, res) {
+		return
+	}
+
+	s.stream(w, req, res, produce)
+
+	for _, fn := range res.Callbacks() {
+		go fn()
+	}
+}
+
+// recordSource converts a supported response body into a
+// RecordProducer.
+func recordSource(body interface{}) (RecordProducer, bool) {
+	switch v := body.(type) {
+	case RecordProducer:
+		return v, true
+	case func(context.Context, func(interface{}) error) error:
+		return v, true
+	}
+
+	val := reflect.ValueOf(body)
+	typ := val.Type()
+
+	switch {
+	case typ.Kind() == reflect.Chan && typ.ChanDir()&reflect.RecvDir != 0:
+		return chanRecords(val), true
+
+	case typ.Kind() != reflect.Func:
+		return nil, false
+
+	case typ.CanSeq() && typ.In(0).NumIn() == 1:
+		return func(ctx context.Context, emit func(interface{}) error) (err error) {
+			for rec := range val.Seq() {
+				if err = emit(rec.Interface()); err != nil {
+					return
+				}
+			}
+			return
+		}, true
+
+	case typ.CanSeq2() && typ.In(0).In(1) == errorType:
+		return func(ctx context.Context, emit func(interface{}) error) (err error) {
+			for rec, cause := range val.Seq2() {
+				if !cause.IsNil() {
+					return cause.Interface().(error)
+				}
+				if err = emit(rec.Interface()); err != nil {
+					return
+				}
+			}
+			return
+		}, true
+
+	case typ.ConvertibleTo(producerType):
+		return val.Convert(producerType).Interface().(RecordProducer), true
+	}
+
+	return nil, false
+}
+
+// chanRecords returns a RecordProducer which emits the values
+// received from the given channel until it is closed or the
+// request context is cancelled.
+func chanRecords(ch reflect.Value) RecordProducer {
+	return func(ctx context.Context, emit func(interface{}) error) error {
+		cases := []reflect.SelectCase{
+			{Dir: reflect.SelectRecv, Chan: ch},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		}
+
+		for {
+			chosen, rec, ok := reflect.Select(cases)
+			if chosen == 1 {
+				return ctx.Err()
+			}
+			if !ok {
+				return nil
+			}
+			if err := emit(rec.Interface()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// precondition answers conditional requests with a 304 or
+// 412 in place of the given response, returning whether it
+// did so.  Only validators supplied by the handler are
+// evaluated, as the records have not been produced.
+func (s jsonStreamAdapter) precondition(w writer, q Request, res Response) bool {
+	mode := s.conditional
+	if mode != ConditionalOff {
+		mode = ConditionalSupplied
+	}
+
+	contentType := s.format.contentType()
+	if _, ok := res.RawHeaders()["Content-Type"]; ok {
+		contentType = res.RawHeaders().Get("Content-Type")
+	}
+	coding := s.compress.coding(q.RawRequest(), res.RawHeaders(), contentType, -1)
+
+	switch conditionalStatus(mode, q.RawRequest(), coding, res, nil) {
+	case http.StatusNotModified:
+		writeNotModified(w, res.RawHeaders(), coding)
+		return true
+	case http.StatusPreconditionFailed:
+		s.writeError(w, preconditionFailed(), q, res.SetCode(http.StatusPreconditionFailed))
+		return true
+	}
+
+	return false
+}
+
+// stream writes the records produced by the given producer
+// to the client.
+func (s jsonStreamAdapter) stream(w writer, q Request, res Response, produce RecordProducer) {
+	head := res.RawHeaders()
+	if _, ok := head["Content-Type"]; !ok {
+		w.Header().Set("Content-Type", s.format.contentType())
+	}
+	for key, values := range head {
+		for _, val := range values {
+			w.Header().Add(key, val)
+		}
+	}
+	w.WriteHeader(res.Code())
+
+	policy := FlushPolicy{Interval: s.flush}
+	if s.flush <= 0 {
+		policy = FlushPolicy{EachWrite: true}
+	}
+
+	out := newFlushWriter(w, policy)
+	defer out.close()
+
+	ctx := q.Context()
+	line := new(bytes.Buffer)
+	first := true
+
+	// write frames a single record so that each record is
+	// written, and possibly flushed, as a whole.
+	write := func(rec []byte) error {
+		line.Reset()
+		switch {
+		case s.format == NDJSON:
+			line.Write(rec)
+			line.WriteByte('\n')
+		case first:
+			line.WriteByte('[')
+			line.Write(rec)
+		default:
+			line.WriteByte(',')
+			line.Write(rec)
+		}
+		first = false
+
+		_, err := out.Write(line.Bytes())
+		return err
+	}
+
+	emit := func(v interface{}) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rec, err := s.serializer.Serialize(v)
+		if err != nil {
+			return fmt.Errorf("midl: serializing record: %w", err)
+		}
+
+		return write(rec)
+	}
+
+	var err error
+	if pe := protect(func() { err = produce(ctx, emit) }); pe != nil {
+		notifyPanic(s.panicHandler, pe, q)
+		err = pe
+	}
+
+	// The client went away; there is no one left to tell.
+	if ctx.Err() != nil || out.err() != nil {
+		return
+	}
+
+	if err != nil {
+		_ = write(s.errorRecord(err, q))
+	}
+
+	if s.format == JSONArray {
+		if first {
+			_, _ = out.Write([]byte("[]"))
+		} else {
+			_, _ = out.Write([]byte("]"))
+		}
+	}
+}
+
+// errorRecord serializes the given mid-stream error as a
+// single line JSON value.
+func (s jsonStreamAdapter) errorRecord(err error, q Request) []byte {
+	var body []byte
+
+	res := MakeErrorResponse(http.StatusInternalServerError, err)
+	res.SetCode(resolveStatus(err, res.Code(), s.statuses))
+
+	if pe := protect(func() { body = s.errSerializer.Serialize(err, q, res) }); pe != nil {
+		notifyPanic(s.panicHandler, pe, q)
+		body = nil
+	}
+
+	var buf bytes.Buffer
+	if body == nil || json.Compact(&buf, body) != nil {
+		buf.Reset()
+		body, _ = json.Marshal(jsonError{Error: http.StatusText(http.StatusInternalServerError)})
+		buf.Write(body)
+	}
+
+	return buf.Bytes()
+}
+
+func (s *jsonStreamAdapter) Format(format JSONStreamFormat) JSONStreamAdapter {
+	s.format = format
+	return s
+}
+
+func (s *jsonStreamAdapter) FlushInterval(interval time.Duration) JSONStreamAdapter {
+	s.flush = interval
+	return s
+}
+
+func (s *jsonStreamAdapter) EmptyHandler(handler EmptyHandler) Adapter {
+	s.adapter.EmptyHandler(handler)
+	return s
+}
+
+func (s *jsonStreamAdapter) ContentType(contentType string) Adapter {
+	s.adapter.ContentType(contentType)
+	return s
+}
+
+func (s *jsonStreamAdapter) ErrorSerializer(err ErrorSerializer) Adapter {
+	s.adapter.ErrorSerializer(err)
+	return s
+}
+
+func (s *jsonStreamAdapter) Serializer(ser Serializer) Adapter {
+	s.adapter.Serializer(ser)
+	return s
+}
+
+func (s *jsonStreamAdapter) PanicHandler(handler PanicHandler) Adapter {
+	s.adapter.PanicHandler(handler)
+	return s
+}
+
+func (s *jsonStreamAdapter) ErrorStatus(target error, code int) Adapter {
+	s.adapter.ErrorStatus(target, code)
+	return s
+}
+
+func (s *jsonStreamAdapter) MaxBodySize(max int64) Adapter {
+	s.adapter.MaxBodySize(max)
+	return s
+}
+
+func (s *jsonStreamAdapter) FormOptions(opts FormOptions) Adapter {
+	s.adapter.FormOptions(opts)
+	return s
+}
+
+func (s *jsonStreamAdapter) Compression(c *Compression) Adapter {
+	s.adapter.Compression(c)
+	return s
+}
+
+func (s *jsonStreamAdapter) Decompression(c *Decompression) Adapter {
+	s.adapter.Decompression(c)
+	return s
+}
+
+func (s *jsonStreamAdapter) Conditional(mode ConditionalMode) Adapter {
+	s.adapter.Conditional(mode)
+	return s
+}
+
+func (s *jsonStreamAdapter) Cache(*Cache) Adapter {
+	return s
+}
+
+func (s *jsonStreamAdapter) CORS(c *CORS) Adapter {
+	s.adapter.CORS(c)
+	return s
+}
+
+func (s *jsonStreamAdapter) AddHandlers(mid ...Middleware) Adapter {
+	s.adapter.AddHandlers(mid...)
+	return s
+}
+
+func (s *jsonStreamAdapter) SetHandlers(mid ...Middleware) Adapter {
+	s.adapter.SetHandlers(mid...)
+	return s
+}
+
+func (s *jsonStreamAdapter) AddWrappers(w ...RequestWrapper) Adapter {
+	s.adapter.AddWrappers(w...)
+	return s
+}
+
+func (s *jsonStreamAdapter) SetWrappers(w ...RequestWrapper) Adapter {
+	s.adapter.SetWrappers(w...)
+	return s
+}
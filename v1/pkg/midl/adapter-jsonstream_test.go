@@ -0,0 +1,242 @@
+package midl
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+type record struct {
+	ID int `json:"id"`
+}
+
+func jsonStreamTestAdapter(body interface{}) JSONStreamAdapter {
+	return StreamingJSONAdapter(MiddlewareFunc(func(Request) Response {
+		return MakeResponse(http.StatusOK, body)
+	}))
+}
+
+func records(n int) iter.Seq[record] {
+	return func(yield func(record) bool) {
+		for i := 1; i <= n; i++ {
+			if !yield(record{ID: i}) {
+				return
+			}
+		}
+	}
+}
+
+func TestStreamingJSONAdapter_ServeHTTP(t *testing.T) {
+	c.Convey("writes NDJSON records from an iterator", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		jsonStreamTestAdapter(records(3)).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "application/x-ndjson")
+		c.So(w.Body.String(), c.ShouldEqual, "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n")
+	})
+
+	c.Convey("writes a JSON array", t, func() {
+		for n, expect := range []string{`[]`, `[{"id":1}]`, `[{"id":1},{"id":2}]`} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+			jsonStreamTestAdapter(records(n)).Format(JSONArray).ServeHTTP(w, r)
+
+			c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "application/json")
+			c.So(w.Body.String(), c.ShouldEqual, expect)
+		}
+	})
+
+	c.Convey("writes records from a channel", t, func() {
+		ch := make(chan record, 2)
+		ch <- record{ID: 1}
+		ch <- record{ID: 2}
+		close(ch)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		jsonStreamTestAdapter((<-chan record)(ch)).ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "{\"id\":1}\n{\"id\":2}\n")
+	})
+
+	c.Convey("writes records from a producer", t, func() {
+		produce := RecordProducer(func(_ context.Context, emit func(interface{}) error) error {
+			for i := 1; i <= 2; i++ {
+				if err := emit(record{ID: i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		jsonStreamTestAdapter(produce).ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "{\"id\":1}\n{\"id\":2}\n")
+	})
+
+	c.Convey("ends the stream with an error record", t, func() {
+		seq := iter.Seq2[record, error](func(yield func(record, error) bool) {
+			if yield(record{ID: 1}, nil) {
+				yield(record{}, NewHTTPError(http.StatusConflict, "gone stale"))
+			}
+		})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		jsonStreamTestAdapter(seq).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, "{\"id\":1}\n{\"error\":\"gone stale\"}\n")
+
+		w = httptest.NewRecorder()
+		jsonStreamTestAdapter(seq).Format(JSONArray).ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, `[{"id":1},{"error":"gone stale"}]`)
+	})
+
+	c.Convey("reports panics and ends the stream with an error record", t, func() {
+		var reported interface{}
+		seq := func(yield func(record) bool) {
+			yield(record{ID: 1})
+			panic("boom")
+		}
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		a := jsonStreamTestAdapter(seq)
+		a.PanicHandler(PanicHandlerFunc(func(err *PanicError, _ Request) {
+			reported = err.Value
+		}))
+
+		c.So(func() { a.ServeHTTP(w, r) }, c.ShouldNotPanic)
+		c.So(reported, c.ShouldEqual, "boom")
+		c.So(w.Body.String(), c.ShouldEqual, "{\"id\":1}\n{\"error\":\"Internal Server Error\"}\n")
+	})
+
+	c.Convey("reports serialization failures as an error record", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		a := jsonStreamTestAdapter(records(2))
+		a.Serializer(SerializerFunc(func(interface{}) ([]byte, error) {
+			return nil, errors.New("bad record")
+		}))
+		a.ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "{\"error\":\"midl: serializing record: bad record\"}\n")
+	})
+
+	c.Convey("stops once the client disconnects", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		seq := func(yield func(record) bool) {
+			for i := 1; yield(record{ID: i}); i++ {
+				if i == 2 {
+					cancel()
+				}
+			}
+		}
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil).WithContext(ctx)
+
+		jsonStreamTestAdapter(seq).Format(JSONArray).ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, `[{"id":1},{"id":2}`)
+	})
+
+	c.Convey("stops waiting on a channel once the client disconnects", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil).WithContext(ctx)
+
+		jsonStreamTestAdapter(make(chan record)).ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "")
+	})
+
+	c.Convey("flushes records periodically", t, func() {
+		ch := make(chan record)
+		done := make(chan struct{})
+
+		w := &syncRecorder{ResponseRecorder: httptest.NewRecorder()}
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		go func() {
+			defer close(done)
+			jsonStreamTestAdapter(ch).FlushInterval(5*time.Millisecond).ServeHTTP(w, r)
+		}()
+
+		ch <- record{ID: 1}
+		time.Sleep(50 * time.Millisecond)
+		c.So(w.flushed(), c.ShouldEqual, "{\"id\":1}\n")
+
+		close(ch)
+		<-done
+	})
+
+	c.Convey("rejects unsupported bodies", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		jsonStreamTestAdapter([]record{{ID: 1}}).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "application/json")
+	})
+}
+
+// syncRecorder is a ResponseRecorder which can be inspected
+// while a response is being written.
+type syncRecorder struct {
+	*httptest.ResponseRecorder
+
+	mu      sync.Mutex
+	sent    string
+	flushes int
+}
+
+func (s *syncRecorder) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ResponseRecorder.Write(p)
+}
+
+func (s *syncRecorder) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ResponseRecorder.Flush()
+	s.sent = s.Body.String()
+	s.flushes++
+}
+
+// flushed returns the body written as of the last flush.
+func (s *syncRecorder) flushed() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sent
+}
+
+// flushCount returns the number of times the response was
+// flushed.
+func (s *syncRecorder) flushCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushes
+}
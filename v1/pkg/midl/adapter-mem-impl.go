@@ -1,6 +1,9 @@
 package midl
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 // NewAdapter creates a new Adapter instance with the provided settings
 func NewAdapter(
@@ -26,42 +29,52 @@ type adapter struct {
 	errSerializer ErrorSerializer
 	contentType   string
 	emptyHandler  EmptyHandler
+	panicHandler  PanicHandler
+	statuses      []errorStatus
+	maxBody       int64
+	form          FormOptions
+	compress      *Compression
+	decompress    *Decompression
+	conditional   ConditionalMode
+	cache         *Cache
+	cors          *CORS
 }
 
 func (d adapter) ServeHTTP(w writer, r *http.Request) {
-	var res Response
-	var wrapLen int
+	if d.cors.handle(w, r) {
+		return
+	}
+
+	w, finish := startCompression(d.compress, w, r)
+	defer finish()
 
 	req, err := NewRequest(r)
 	if err != nil {
 		d.writeError(w, err, req, NewResponse())
 		return
 	}
+	defer closeRequest(req)
+	setFormOptions(req, d.form)
+	setCompression(req, d.compress)
 
-	wrapLen = len(d.wrappers)
-	for i := 0; i < wrapLen; i++ {
-		d.wrappers[i].Request(req)
+	if err := limitBody(w, r, d.maxBody); err != nil {
+		d.writeError(w, err, req, MakeErrorResponse(http.StatusRequestEntityTooLarge, err))
+		return
 	}
 
-	for _, hand := range d.handlers {
-		if hand == nil {
-			continue
-		}
-
-		res = hand.Handle(req)
-
-		if res != nil {
-			break
-		}
+	if err := decodeBody(r, d.decompress); err != nil {
+		d.writeError(w, err, req, unsupportedEncoding(d.decompress, err))
+		return
 	}
 
-	res = ensureResponse(res)
-
-	for i := wrapLen - 1; i > -1; i-- {
-		res = d.wrappers[i].Response(req, res)
+	if key, ok := d.cache.key(r, d.contentType); ok {
+		if entry, ok := d.cache.lookup(r, key, time.Now()); ok {
+			d.writeCached(w, req, entry)
+			return
+		}
 	}
 
-	res = ensureResponse(res)
+	res := runPipeline(req, d.wrappers, d.handlers, d.panicHandler)
 
 	if res.Error() != nil {
 		d.writeError(w, res.Error(), req, res)
@@ -100,6 +113,51 @@ func (d *adapter) Serializer(ser Serializer) Adapter {
 	return d
 }
 
+func (d *adapter) PanicHandler(handler PanicHandler) Adapter {
+	d.panicHandler = handler
+	return d
+}
+
+func (d *adapter) ErrorStatus(target error, code int) Adapter {
+	d.statuses = append(d.statuses, errorStatus{target: target, code: code})
+	return d
+}
+
+func (d *adapter) MaxBodySize(max int64) Adapter {
+	d.maxBody = max
+	return d
+}
+
+func (d *adapter) FormOptions(opts FormOptions) Adapter {
+	d.form = opts
+	return d
+}
+
+func (d *adapter) Compression(c *Compression) Adapter {
+	d.compress = c
+	return d
+}
+
+func (d *adapter) Decompression(c *Decompression) Adapter {
+	d.decompress = c
+	return d
+}
+
+func (d *adapter) Conditional(mode ConditionalMode) Adapter {
+	d.conditional = mode
+	return d
+}
+
+func (d *adapter) Cache(c *Cache) Adapter {
+	d.cache = c
+	return d
+}
+
+func (d *adapter) CORS(c *CORS) Adapter {
+	d.cors = c
+	return d
+}
+
 func (d *adapter) AddHandlers(mid ...Middleware) Adapter {
 	d.handlers = append(d.handlers, mid...)
 	return d
@@ -111,26 +169,110 @@ func (d *adapter) SetHandlers(mid ...Middleware) Adapter {
 }
 
 func (d adapter) writeEmpty(w writer, q Request, s Response) {
-	body := d.emptyHandler.Handle(q, s)
+	var body []byte
+
+	if err := protect(func() { body = d.emptyHandler.Handle(q, s) }); err != nil {
+		d.writePanic(w, err, q)
+		return
+	}
+
+	d.cacheResponse(q, s, body)
+	if d.precondition(w, q, s, body) {
+		return
+	}
+
 	d.writeResponse(w, s.Code(), s.RawHeaders(), body)
 }
 
 func (d adapter) writeBody(w writer, q Request, s Response) {
-	body, err := d.serializer.Serialize(s.Body())
+	var body []byte
+	var err error
+
+	if pe := protect(func() { body, err = d.serializer.Serialize(s.Body()) }); pe != nil {
+		d.writePanic(w, pe, q)
+		return
+	}
 
 	if err != nil {
 		d.writeError(w, err, q, s)
 		return
 	}
 
+	d.cacheResponse(q, s, body)
+	if d.precondition(w, q, s, body) {
+		return
+	}
+
 	d.writeResponse(w, s.Code(), s.RawHeaders(), body)
 }
 
+// writeCached writes the given cached response in place of
+// calling the handlers.
+func (d adapter) writeCached(w writer, q Request, e *CacheEntry) {
+	s := e.response(time.Now())
+	if d.precondition(w, q, s, e.Body) {
+		return
+	}
+
+	d.writeResponse(w, s.Code(), s.RawHeaders(), e.Body)
+}
+
+// cacheResponse stores the given serialized response if
+// caching is enabled and allows it.
+func (d adapter) cacheResponse(q Request, s Response, body []byte) {
+	r := q.RawRequest()
+	if key, ok := d.cache.key(r, d.contentType); ok {
+		d.cache.save(r, key, s, body, time.Now())
+	}
+}
+
+// precondition answers conditional requests with a 304 or
+// 412 in place of the given response, returning whether it
+// did so.
+func (d adapter) precondition(w writer, q Request, s Response, body []byte) bool {
+	coding := d.compress.coding(q.RawRequest(), s.RawHeaders(), d.responseType(s), len(body))
+
+	switch conditionalStatus(d.conditional, q.RawRequest(), coding, s, body) {
+	case http.StatusNotModified:
+		writeNotModified(w, s.RawHeaders(), coding)
+		return true
+	case http.StatusPreconditionFailed:
+		d.writeError(w, preconditionFailed(), q, s.SetCode(http.StatusPreconditionFailed))
+		return true
+	}
+
+	return false
+}
+
+func (d adapter) writePanic(w writer, e *PanicError, q Request) {
+	d.writeError(w, e, q, panicResponse(d.panicHandler, e, q))
+}
+
 func (d adapter) writeError(w writer, e error, q Request, s Response) {
-	body := d.errSerializer.Serialize(e, q, s)
+	var body []byte
+
+	s.SetCode(resolveStatus(e, s.Code(), d.statuses))
+
+	if err := protect(func() { body = d.errSerializer.Serialize(e, q, s) }); err != nil {
+		notifyPanic(d.panicHandler, err, q)
+		writeFallbackError(w)
+		return
+	}
+
 	d.writeResponse(w, s.Code(), s.RawHeaders(), body)
 }
 
+// responseType returns the content type the given response
+// is written with.
+func (d adapter) responseType(s Response) string {
+	if head := s.RawHeaders(); head != nil {
+		if _, ok := head["Content-Type"]; ok {
+			return head.Get("Content-Type")
+		}
+	}
+	return d.contentType
+}
+
 func (d adapter) writeResponse(w writer, code int, head header, body []byte) {
 
 	// Don't override user provided header if present.
@@ -163,13 +305,3 @@ func (d *adapter) SetWrappers(w ...RequestWrapper) Adapter {
 	d.wrappers = w
 	return d
 }
-
-func ensureResponse(res Response) Response {
-	if res == nil {
-		return NewResponse().
-			SetCode(http.StatusInternalServerError).
-			SetError(ErrNoHandlers)
-	}
-
-	return res
-}
@@ -0,0 +1,254 @@
+package midl
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+)
+
+// NegotiatingAdapter is an Adapter which selects the
+// Serializer and ErrorSerializer used for each request
+// based on the request's Accept header.
+//
+//   handler := JSONXMLAdapter(NewInputValidator(), NewResponder())
+//   http.Handle("/", handler)
+//
+// Requests without an Accept header are served using the
+// default settings configured through the Adapter methods.
+// Requests whose Accept header matches none of the
+// registered media types receive a 406 (Not Acceptable)
+// written with the default ErrorSerializer.
+type NegotiatingAdapter interface {
+	Adapter
+
+	// Register adds a media type to the list of formats this
+	// adapter can produce.
+	//
+	// When two media types are equally acceptable to a
+	// client, the one registered first is preferred.
+	//
+	// A nil Serializer or ErrorSerializer will fall back to
+	// the adapter's default.
+	Register(mediaType string, ser Serializer, err ErrorSerializer) NegotiatingAdapter
+}
+
+// NewNegotiatingAdapter creates a new NegotiatingAdapter
+// instance using the provided settings as its default
+// format.  The default format is registered as the first,
+// and therefore preferred, media type.
+func NewNegotiatingAdapter(
+	content string,
+	serial Serializer,
+	error ErrorSerializer,
+	next ...Middleware,
+) NegotiatingAdapter {
+	out := &negotiatingAdapter{
+		adapter: adapter{
+			contentType:   content,
+			serializer:    serial,
+			errSerializer: error,
+			emptyHandler:  DefaultEmptyHandler(),
+			handlers:      next,
+		},
+	}
+
+	return out.Register(content, serial, error)
+}
+
+// JSONXMLAdapter creates a new NegotiatingAdapter which
+// serves JSON by default and XML to clients which prefer
+// it.
+func JSONXMLAdapter(handlers ...Middleware) NegotiatingAdapter {
+	return NewNegotiatingAdapter(
+		"application/json",
+		SerializerFunc(json.Marshal),
+		DefaultJSONErrorSerializer(),
+		handlers...,
+	).Register(
+		"application/xml",
+		SerializerFunc(xml.Marshal),
+		DefaultXMLErrorSerializer(),
+	)
+}
+
+type format struct {
+	mediaType     string
+	serializer    Serializer
+	errSerializer ErrorSerializer
+}
+
+type negotiatingAdapter struct {
+	adapter
+
+	formats []format
+}
+
+func (n negotiatingAdapter) ServeHTTP(w writer, r *http.Request) {
+	if r == nil {
+		n.adapter.ServeHTTP(w, r)
+		return
+	}
+
+	if n.cors.handle(w, r) {
+		return
+	}
+	n.cors = nil
+
+	w.Header().Add("Vary", "Accept")
+
+	ranges := parseAccept(r.Header["Accept"])
+	if len(ranges) == 0 || len(n.formats) == 0 {
+		n.adapter.ServeHTTP(w, r)
+		return
+	}
+
+	form, ok := n.negotiate(ranges)
+	if !ok {
+		n.writeNotAcceptable(w, r)
+		return
+	}
+
+	inner := n.adapter
+	inner.contentType = form.mediaType
+	if form.serializer != nil {
+		inner.serializer = form.serializer
+	}
+	if form.errSerializer != nil {
+		inner.errSerializer = form.errSerializer
+	}
+	inner.ServeHTTP(w, r)
+}
+
+// negotiate picks the registered format with the highest
+// quality value from the given media ranges.
+func (n negotiatingAdapter) negotiate(ranges []mediaRange) (format, bool) {
+	var best format
+	var bestQ float64
+
+	for _, form := range n.formats {
+		if q := acceptQuality(ranges, form.mediaType); q > bestQ {
+			best = form
+			bestQ = q
+		}
+	}
+
+	return best, bestQ > 0
+}
+
+func (n negotiatingAdapter) writeNotAcceptable(w writer, r *http.Request) {
+	req, _ := NewRequest(r)
+	n.writeError(
+		w,
+		ErrNotAcceptable,
+		req,
+		MakeErrorResponse(http.StatusNotAcceptable, ErrNotAcceptable),
+	)
+}
+
+func (n *negotiatingAdapter) Register(
+	mediaType string,
+	ser Serializer,
+	err ErrorSerializer,
+) NegotiatingAdapter {
+	n.formats = append(n.formats, format{
+		mediaType:     mediaType,
+		serializer:    ser,
+		errSerializer: err,
+	})
+	return n
+}
+
+func (n *negotiatingAdapter) EmptyHandler(handler EmptyHandler) Adapter {
+	n.adapter.EmptyHandler(handler)
+	return n
+}
+
+func (n *negotiatingAdapter) ContentType(contentType string) Adapter {
+	n.adapter.ContentType(contentType)
+	return n
+}
+
+// ErrorSerializer replaces the ErrorSerializer of the
+// adapter and of its default format.
+func (n *negotiatingAdapter) ErrorSerializer(err ErrorSerializer) Adapter {
+	n.adapter.ErrorSerializer(err)
+	if len(n.formats) > 0 {
+		n.formats[0].errSerializer = err
+	}
+	return n
+}
+
+// Serializer replaces the Serializer of the adapter and of
+// its default format.
+func (n *negotiatingAdapter) Serializer(ser Serializer) Adapter {
+	n.adapter.Serializer(ser)
+	if len(n.formats) > 0 {
+		n.formats[0].serializer = ser
+	}
+	return n
+}
+
+func (n *negotiatingAdapter) PanicHandler(handler PanicHandler) Adapter {
+	n.adapter.PanicHandler(handler)
+	return n
+}
+
+func (n *negotiatingAdapter) ErrorStatus(target error, code int) Adapter {
+	n.adapter.ErrorStatus(target, code)
+	return n
+}
+
+func (n *negotiatingAdapter) MaxBodySize(max int64) Adapter {
+	n.adapter.MaxBodySize(max)
+	return n
+}
+
+func (n *negotiatingAdapter) FormOptions(opts FormOptions) Adapter {
+	n.adapter.FormOptions(opts)
+	return n
+}
+
+func (n *negotiatingAdapter) Compression(c *Compression) Adapter {
+	n.adapter.Compression(c)
+	return n
+}
+
+func (n *negotiatingAdapter) Decompression(c *Decompression) Adapter {
+	n.adapter.Decompression(c)
+	return n
+}
+
+func (n *negotiatingAdapter) Conditional(mode ConditionalMode) Adapter {
+	n.adapter.Conditional(mode)
+	return n
+}
+
+func (n *negotiatingAdapter) Cache(c *Cache) Adapter {
+	n.adapter.Cache(c)
+	return n
+}
+
+func (n *negotiatingAdapter) CORS(c *CORS) Adapter {
+	n.adapter.CORS(c)
+	return n
+}
+
+func (n *negotiatingAdapter) AddHandlers(mid ...Middleware) Adapter {
+	n.adapter.AddHandlers(mid...)
+	return n
+}
+
+func (n *negotiatingAdapter) SetHandlers(mid ...Middleware) Adapter {
+	n.adapter.SetHandlers(mid...)
+	return n
+}
+
+func (n *negotiatingAdapter) AddWrappers(w ...RequestWrapper) Adapter {
+	n.adapter.AddWrappers(w...)
+	return n
+}
+
+func (n *negotiatingAdapter) SetWrappers(w ...RequestWrapper) Adapter {
+	n.adapter.SetWrappers(w...)
+	return n
+}
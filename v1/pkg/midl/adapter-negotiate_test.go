@@ -0,0 +1,148 @@
+package midl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func negotiationTestAdapter() NegotiatingAdapter {
+	return NewNegotiatingAdapter(
+		"application/json",
+		SerializerFunc(func(interface{}) ([]byte, error) {
+			return []byte("json"), nil
+		}),
+		ErrorSerializerFunc(func(err error, _ Request, _ Response) []byte {
+			return []byte("json:" + err.Error())
+		}),
+		MiddlewareFunc(func(Request) Response {
+			return MakeResponse(http.StatusOK, "body")
+		}),
+	).Register(
+		"application/xml",
+		SerializerFunc(func(interface{}) ([]byte, error) {
+			return []byte("xml"), nil
+		}),
+		ErrorSerializerFunc(func(err error, _ Request, _ Response) []byte {
+			return []byte("xml:" + err.Error())
+		}),
+	)
+}
+
+func TestNegotiatingAdapter_ServeHTTP(t *testing.T) {
+	c.Convey("uses the default format without an Accept header", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		negotiationTestAdapter().ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, "json")
+		c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "application/json")
+		c.So(w.Header().Get("Vary"), c.ShouldEqual, "Accept")
+	})
+
+	c.Convey("selects the best matching format", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept", "application/json;q=0.5, application/xml")
+
+		negotiationTestAdapter().ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, "xml")
+		c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "application/xml")
+	})
+
+	c.Convey("prefers registration order for equal quality", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept", "*/*")
+
+		negotiationTestAdapter().ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "json")
+	})
+
+	c.Convey("writes a 406 when nothing matches", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept", "text/html")
+
+		negotiationTestAdapter().ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusNotAcceptable)
+		c.So(w.Body.String(), c.ShouldEqual, "json:"+ErrNotAcceptable.Error())
+		c.So(w.Header().Get("Vary"), c.ShouldEqual, "Accept")
+	})
+
+	c.Convey("uses the matched error serializer", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept", "application/xml")
+
+		negotiationTestAdapter().
+			SetHandlers().
+			ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "xml:"+ErrNoHandlers.Error())
+	})
+
+	c.Convey("applies replaced serializers to the default format", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept", "application/json")
+
+		a := negotiationTestAdapter().
+			Serializer(SerializerFunc(func(interface{}) ([]byte, error) {
+				return []byte("other"), nil
+			})).
+			ErrorSerializer(ErrorSerializerFunc(func(err error, _ Request, _ Response) []byte {
+				return []byte("other:" + err.Error())
+			}))
+		a.ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "other")
+
+		w = httptest.NewRecorder()
+		a.SetHandlers().ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "other:"+ErrNoHandlers.Error())
+	})
+
+	c.Convey("writes nil request error if input request is nil", t, func() {
+		w := httptest.NewRecorder()
+
+		negotiationTestAdapter().ServeHTTP(w, nil)
+
+		c.So(w.Body.String(), c.ShouldEqual, "json:"+ErrWrappedNil.Error())
+	})
+}
+
+func TestNegotiatingAdapter_builders(t *testing.T) {
+	c.Convey("builder methods return the negotiating adapter", t, func() {
+		test := JSONXMLAdapter()
+
+		c.So(test.ContentType("text/plain"), c.ShouldEqual, test)
+		c.So(test.Serializer(nil), c.ShouldEqual, test)
+		c.So(test.ErrorSerializer(nil), c.ShouldEqual, test)
+		c.So(test.EmptyHandler(nil), c.ShouldEqual, test)
+		c.So(test.AddHandlers(), c.ShouldEqual, test)
+		c.So(test.SetHandlers(), c.ShouldEqual, test)
+		c.So(test.AddWrappers(), c.ShouldEqual, test)
+		c.So(test.SetWrappers(), c.ShouldEqual, test)
+	})
+}
+
+func TestJSONXMLAdapter(t *testing.T) {
+	c.Convey("registers JSON ahead of XML", t, func() {
+		tst := JSONXMLAdapter().(*negotiatingAdapter)
+
+		c.So(len(tst.formats), c.ShouldEqual, 2)
+		c.So(tst.formats[0].mediaType, c.ShouldEqual, "application/json")
+		c.So(tst.formats[1].mediaType, c.ShouldEqual, "application/xml")
+		c.So(tst.contentType, c.ShouldEqual, "application/json")
+	})
+}
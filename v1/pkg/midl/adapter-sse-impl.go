@@ -0,0 +1,479 @@
+package midl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a single Server-Sent Event written by an
+// EventAdapter.
+type Event struct {
+
+	// ID sets the client's last event ID, which is sent back
+	// in the Last-Event-ID header when the client reconnects.
+	ID string
+
+	// Event is the event type.  If empty, clients dispatch the
+	// event as a "message".
+	Event string
+
+	// Data is the event payload.  Strings and byte slices are
+	// written as is, other values are serialized with the
+	// adapter's Serializer.  Multi-line data is split across
+	// several data fields.
+	Data interface{}
+
+	// Retry, if positive, sets the client's reconnection
+	// delay.
+	Retry time.Duration
+}
+
+// EventAdapter is an Adapter which streams Server-Sent
+// Events to the client.
+//
+//   handler := SSEAdapter(midl.MiddlewareFunc(func(req midl.Request) midl.Response {
+//       return midl.MakeResponse(http.StatusOK, subscribe(midl.LastEventID(req)))
+//   })).Heartbeat(15 * time.Second)
+//
+// Middleware return a Response whose body is the source of
+// events, one of:
+//
+//   <-chan Event, chan Event
+//   func(yield func(Event) bool), or any function type with
+//       the same underlying type such as iter.Seq[Event]
+//   []Event
+//   Event
+//
+// The stream ends when the channel is closed or the iterator
+// returns, or when the client disconnects, which is detected
+// through cancellation of the request's context.  Iterators
+// should stop once yield returns false.
+//
+// Each event is flushed to the client as soon as it is
+// written.  Responses with an error or without a body are
+// written as with the buffered Adapters, using the content
+// type set with ContentType, which defaults to
+// "application/json".
+type EventAdapter interface {
+	Adapter
+
+	// Heartbeat sets the interval at which a comment line is
+	// written to idle streams to keep intermediaries from
+	// closing the connection.  A value of zero or less, the
+	// default, disables heartbeats.
+	Heartbeat(interval time.Duration) EventAdapter
+
+	// StreamHook sets a hook notified each time an event
+	// stream ends, successfully or not.  Event data the
+	// Serializer fails to serialize is reported with a
+	// StreamSerializeFailed result before the response is
+	// aborted.
+	StreamHook(hook StreamHook) EventAdapter
+}
+
+// SSEAdapter creates a new EventAdapter instance which
+// serializes event data and errors as JSON.
+func SSEAdapter(handlers ...Middleware) EventAdapter {
+	return &sseAdapter{
+		adapter: adapter{
+			contentType:   "application/json",
+			serializer:    SerializerFunc(json.Marshal),
+			errSerializer: DefaultJSONErrorSerializer(),
+			emptyHandler:  DefaultEmptyHandler(),
+			handlers:      handlers,
+		},
+	}
+}
+
+// LastEventID returns the ID of the last event received by a
+// reconnecting Server-Sent Events client, or the empty
+// string for new connections.
+func LastEventID(req Request) string {
+	return req.RawRequest().Header.Get("Last-Event-ID")
+}
+
+var (
+	eventSeqType = reflect.TypeOf((func(func(Event) bool))(nil))
+	lineBreaks   = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+	fieldBreaks  = strings.NewReplacer("\r", "", "\n", "")
+)
+
+type sseAdapter struct {
+	adapter
+
+	heartbeat time.Duration
+	hook      StreamHook
+}
+
+func (s sseAdapter) ServeHTTP(w writer, r *http.Request) {
+	d := s.adapter
+
+	if d.cors.handle(w, r) {
+		return
+	}
+
+	w, finish := startCompression(d.compress, w, r)
+	defer finish()
+
+	req, err := NewRequest(r)
+	if err != nil {
+		d.writeError(w, err, req, NewResponse())
+		return
+	}
+	defer closeRequest(req)
+	setFormOptions(req, d.form)
+	setCompression(req, d.compress)
+
+	if err := limitBody(w, r, d.maxBody); err != nil {
+		d.writeError(w, err, req, MakeErrorResponse(http.StatusRequestEntityTooLarge, err))
+		return
+	}
+
+	if err := decodeBody(r, d.decompress); err != nil {
+		d.writeError(w, err, req, unsupportedEncoding(d.decompress, err))
+		return
+	}
+
+	res := runPipeline(req, d.wrappers, d.handlers, d.panicHandler)
+
+	if res.Error() != nil {
+		d.writeError(w, res.Error(), req, res)
+		return
+	}
+
+	if res.Body() == nil {
+		d.writeEmpty(w, req, res)
+		return
+	}
+
+	done := make(chan struct{})
+	events, panics, ok := eventSource(res.Body(), done)
+	if !ok {
+		err := fmt.Errorf("midl: unsupported event stream body type %T", res.Body())
+		d.writeError(w, err, req, res.SetCode(http.StatusInternalServerError))
+		return
+	}
+
+	result := func() StreamResult {
+		defer close(done)
+		return s.stream(w, req, res, events, panics)
+	}()
+
+	if s.hook != nil {
+		notifyStreamHook(s.hook, req, result, d.panicHandler)
+	}
+
+	// The status line has already been sent, so failures can
+	// only be reported, then the response aborted so the
+	// client does not mistake it as complete and reconnects.
+	switch result.Reason {
+	case StreamPanicked:
+		notifyPanic(d.panicHandler, result.Err.(*PanicError), req)
+		panic(http.ErrAbortHandler)
+	case StreamSerializeFailed:
+		panic(http.ErrAbortHandler)
+	}
+
+	for _, fn := range res.Callbacks() {
+		go fn()
+	}
+}
+
+// eventSource converts a supported response body into a
+// channel of events.  Iterators are run on their own
+// goroutine until they return or done is closed; a panic
+// raised by an iterator is sent on the returned panic
+// channel.
+func eventSource(body interface{}, done <-chan struct{}) (<-chan Event, <-chan *PanicError, bool) {
+	var seq func(func(Event) bool)
+
+	switch v := body.(type) {
+	case chan Event:
+		return v, nil, true
+	case <-chan Event:
+		return v, nil, true
+	case Event:
+		seq = func(yield func(Event) bool) { yield(v) }
+	case []Event:
+		seq = func(yield func(Event) bool) {
+			for _, e := range v {
+				if !yield(e) {
+					return
+				}
+			}
+		}
+	case func(func(Event) bool):
+		seq = v
+	default:
+		val := reflect.ValueOf(body)
+		if val.Kind() != reflect.Func || !val.Type().ConvertibleTo(eventSeqType) {
+			return nil, nil, false
+		}
+		seq = val.Convert(eventSeqType).Interface().(func(func(Event) bool))
+	}
+
+	events := make(chan Event)
+	panics := make(chan *PanicError, 1)
+
+	go func() {
+		defer close(events)
+		err := protect(func() {
+			seq(func(e Event) bool {
+				select {
+				case events <- e:
+					return true
+				case <-done:
+					return false
+				}
+			})
+		})
+		if err != nil {
+			panics <- err
+		}
+	}()
+
+	return events, panics, true
+}
+
+// stream writes the given events to the client until the
+// event channel is closed, the event source fails or the
+// client goes away.
+func (s sseAdapter) stream(
+	w writer,
+	q Request,
+	res Response,
+	events <-chan Event,
+	panics <-chan *PanicError,
+) (out StreamResult) {
+	for key, values := range res.RawHeaders() {
+		for _, val := range values {
+			w.Header().Add(key, val)
+		}
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	ctl := http.NewResponseController(w)
+	w.WriteHeader(res.Code())
+	_ = ctl.Flush()
+
+	var beat <-chan time.Time
+	if s.heartbeat > 0 {
+		ticker := time.NewTicker(s.heartbeat)
+		defer ticker.Stop()
+		beat = ticker.C
+	}
+
+	ctx := q.Context()
+	buf := new(bytes.Buffer)
+
+	write := func(data []byte) error {
+		n, err := w.Write(data)
+		out.Written += int64(n)
+		if err == nil {
+			_ = ctl.Flush()
+		}
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			out.Reason, out.Err = StreamDisconnected, ctx.Err()
+			return
+
+		case err := <-panics:
+			out.Reason, out.Err = StreamPanicked, err
+			return
+
+		case e, ok := <-events:
+			if !ok {
+				select {
+				case err := <-panics:
+					out.Reason, out.Err = StreamPanicked, err
+				default:
+					out.Reason = StreamCompleted
+				}
+				return
+			}
+
+			buf.Reset()
+			switch err := s.writeEvent(buf, e).(type) {
+			case nil:
+			case *PanicError:
+				out.Reason, out.Err = StreamPanicked, err
+				return
+			default:
+				out.Reason, out.Err = StreamSerializeFailed, err
+				return
+			}
+
+			if err := write(buf.Bytes()); err != nil {
+				out.Reason, out.Err = StreamDisconnected, err
+				return
+			}
+
+		case <-beat:
+			if err := write([]byte(": heartbeat\n\n")); err != nil {
+				out.Reason, out.Err = StreamDisconnected, err
+				return
+			}
+		}
+	}
+}
+
+// writeEvent writes the given event in the text/event-stream
+// format.  Serialization failures are returned as a
+// *SerializeError, or a *PanicError if the Serializer
+// panicked.
+func (s sseAdapter) writeEvent(buf *bytes.Buffer, e Event) error {
+	if e.ID != "" {
+		writeEventField(buf, "id", e.ID)
+	}
+
+	if e.Event != "" {
+		writeEventField(buf, "event", e.Event)
+	}
+
+	if e.Retry > 0 {
+		writeEventField(buf, "retry", strconv.FormatInt(e.Retry.Milliseconds(), 10))
+	}
+
+	var data []byte
+	switch v := e.Data.(type) {
+	case nil:
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		var err error
+		if pe := protect(func() { data, err = s.serializer.Serialize(v) }); pe != nil {
+			return pe
+		}
+		if err != nil {
+			return &SerializeError{Err: err}
+		}
+	}
+
+	if e.Data != nil {
+		for _, line := range strings.Split(lineBreaks.Replace(string(data)), "\n") {
+			buf.WriteString("data: ")
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+
+	buf.WriteByte('\n')
+	return nil
+}
+
+// writeEventField writes a single line field, dropping any
+// line breaks which would otherwise end the field early.
+func writeEventField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(fieldBreaks.Replace(value))
+	buf.WriteByte('\n')
+}
+
+func (s *sseAdapter) Heartbeat(interval time.Duration) EventAdapter {
+	s.heartbeat = interval
+	return s
+}
+
+func (s *sseAdapter) StreamHook(hook StreamHook) EventAdapter {
+	s.hook = hook
+	return s
+}
+
+func (s *sseAdapter) EmptyHandler(handler EmptyHandler) Adapter {
+	s.adapter.EmptyHandler(handler)
+	return s
+}
+
+func (s *sseAdapter) ContentType(contentType string) Adapter {
+	s.adapter.ContentType(contentType)
+	return s
+}
+
+func (s *sseAdapter) ErrorSerializer(err ErrorSerializer) Adapter {
+	s.adapter.ErrorSerializer(err)
+	return s
+}
+
+func (s *sseAdapter) Serializer(ser Serializer) Adapter {
+	s.adapter.Serializer(ser)
+	return s
+}
+
+func (s *sseAdapter) PanicHandler(handler PanicHandler) Adapter {
+	s.adapter.PanicHandler(handler)
+	return s
+}
+
+func (s *sseAdapter) ErrorStatus(target error, code int) Adapter {
+	s.adapter.ErrorStatus(target, code)
+	return s
+}
+
+func (s *sseAdapter) MaxBodySize(max int64) Adapter {
+	s.adapter.MaxBodySize(max)
+	return s
+}
+
+func (s *sseAdapter) FormOptions(opts FormOptions) Adapter {
+	s.adapter.FormOptions(opts)
+	return s
+}
+
+func (s *sseAdapter) Compression(c *Compression) Adapter {
+	s.adapter.Compression(c)
+	return s
+}
+
+func (s *sseAdapter) Decompression(c *Decompression) Adapter {
+	s.adapter.Decompression(c)
+	return s
+}
+
+func (s *sseAdapter) Conditional(mode ConditionalMode) Adapter {
+	s.adapter.Conditional(mode)
+	return s
+}
+
+func (s *sseAdapter) Cache(*Cache) Adapter {
+	return s
+}
+
+func (s *sseAdapter) CORS(c *CORS) Adapter {
+	s.adapter.CORS(c)
+	return s
+}
+
+func (s *sseAdapter) AddHandlers(mid ...Middleware) Adapter {
+	s.adapter.AddHandlers(mid...)
+	return s
+}
+
+func (s *sseAdapter) SetHandlers(mid ...Middleware) Adapter {
+	s.adapter.SetHandlers(mid...)
+	return s
+}
+
+func (s *sseAdapter) AddWrappers(w ...RequestWrapper) Adapter {
+	s.adapter.AddWrappers(w...)
+	return s
+}
+
+func (s *sseAdapter) SetWrappers(w ...RequestWrapper) Adapter {
+	s.adapter.SetWrappers(w...)
+	return s
+}
@@ -0,0 +1,190 @@
+package midl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+type eventSeq func(func(Event) bool)
+
+func sseTestAdapter(body interface{}) EventAdapter {
+	return SSEAdapter(MiddlewareFunc(func(Request) Response {
+		return MakeResponse(http.StatusOK, body)
+	}))
+}
+
+func TestSSEAdapter_ServeHTTP(t *testing.T) {
+	c.Convey("writes events from a closed channel", t, func() {
+		events := make(chan Event, 3)
+		events <- Event{ID: "1", Event: "greeting", Data: "hello"}
+		events <- Event{Data: map[string]int{"a": 1}, Retry: 2 * time.Second}
+		events <- Event{Data: "line one\nline two\r\nline three"}
+		close(events)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		sseTestAdapter(events).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "text/event-stream")
+		c.So(w.Header().Get("Cache-Control"), c.ShouldEqual, "no-cache")
+		c.So(w.Flushed, c.ShouldBeTrue)
+		c.So(w.Body.String(), c.ShouldEqual, "id: 1\nevent: greeting\ndata: hello\n\n"+
+			"retry: 2000\ndata: {\"a\":1}\n\n"+
+			"data: line one\ndata: line two\ndata: line three\n\n")
+	})
+
+	c.Convey("strips line breaks from single line fields", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		sseTestAdapter(Event{ID: "a\nb", Event: "c\rd"}).ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "id: ab\nevent: cd\n\n")
+	})
+
+	c.Convey("writes events from iterators", t, func() {
+		seq := func(yield func(Event) bool) {
+			for _, id := range []string{"1", "2", "3"} {
+				if !yield(Event{ID: id}) {
+					return
+				}
+			}
+		}
+
+		for _, body := range []interface{}{seq, eventSeq(seq), []Event{{ID: "1"}, {ID: "2"}, {ID: "3"}}} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+			sseTestAdapter(body).ServeHTTP(w, r)
+
+			c.So(w.Body.String(), c.ShouldEqual, "id: 1\n\nid: 2\n\nid: 3\n\n")
+		}
+	})
+
+	c.Convey("writes heartbeats until the client disconnects", t, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+		defer cancel()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil).WithContext(ctx)
+
+		sseTestAdapter(make(chan Event)).Heartbeat(10*time.Millisecond).ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldStartWith, ": heartbeat\n\n")
+	})
+
+	c.Convey("stops iterators once the client disconnects", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan struct{})
+		seq := func(yield func(Event) bool) {
+			defer close(stopped)
+			for yield(Event{Data: "tick"}) {
+				cancel()
+			}
+		}
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil).WithContext(ctx)
+
+		sseTestAdapter(seq).ServeHTTP(w, r)
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("iterator was not stopped")
+		}
+	})
+
+	c.Convey("aborts the response when an iterator panics", t, func() {
+		var reported interface{}
+		seq := func(yield func(Event) bool) {
+			yield(Event{Data: "first"})
+			panic("boom")
+		}
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		a := sseTestAdapter(seq)
+		a.PanicHandler(PanicHandlerFunc(func(err *PanicError, _ Request) {
+			reported = err.Value
+		}))
+
+		c.So(func() { a.ServeHTTP(w, r) }, c.ShouldPanicWith, http.ErrAbortHandler)
+		c.So(reported, c.ShouldEqual, "boom")
+		c.So(w.Body.String(), c.ShouldEqual, "data: first\n\n")
+	})
+
+	c.Convey("reports serialization failures through the stream hook", t, func() {
+		var result StreamResult
+		var panicked bool
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		a := sseTestAdapter([]Event{{Data: "first"}, {Data: func() {}}, {Data: "last"}})
+		a.PanicHandler(PanicHandlerFunc(func(*PanicError, Request) { panicked = true }))
+		a.StreamHook(StreamHookFunc(func(_ Request, res StreamResult) { result = res }))
+
+		c.So(func() { a.ServeHTTP(w, r) }, c.ShouldPanicWith, http.ErrAbortHandler)
+		c.So(panicked, c.ShouldBeFalse)
+		c.So(result.Reason, c.ShouldEqual, StreamSerializeFailed)
+		c.So(result.Err, c.ShouldHaveSameTypeAs, &SerializeError{})
+		c.So(result.Written, c.ShouldEqual, len("data: first\n\n"))
+		c.So(w.Body.String(), c.ShouldEqual, "data: first\n\n")
+	})
+
+	c.Convey("reports completed streams through the stream hook", t, func() {
+		var result StreamResult
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		a := sseTestAdapter(Event{Data: "only"})
+		a.StreamHook(StreamHookFunc(func(_ Request, res StreamResult) { result = res }))
+		a.ServeHTTP(w, r)
+
+		c.So(result.Reason, c.ShouldEqual, StreamCompleted)
+		c.So(result.Err, c.ShouldBeNil)
+		c.So(result.Written, c.ShouldEqual, len("data: only\n\n"))
+	})
+
+	c.Convey("writes errors as JSON", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		SSEAdapter(MiddlewareFunc(func(Request) Response {
+			return MakeErrorResponse(http.StatusForbidden, errors.New("nope"))
+		})).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusForbidden)
+		c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "application/json")
+	})
+
+	c.Convey("rejects unsupported bodies", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		sseTestAdapter("not events").ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(strings.Contains(w.Header().Get("Content-Type"), "event-stream"), c.ShouldBeFalse)
+	})
+}
+
+func TestLastEventID(t *testing.T) {
+	c.Convey("returns the Last-Event-ID header", t, func() {
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		req, _ := NewRequest(r)
+		c.So(LastEventID(req), c.ShouldEqual, "")
+
+		r.Header.Set("Last-Event-ID", "42")
+		c.So(LastEventID(req), c.ShouldEqual, "42")
+	})
+}
@@ -2,18 +2,130 @@ package midl
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 )
 
-// StreamAdapter creates a new streaming output Adapter instance with the
-// provided settings.
+// StreamingAdapter is an Adapter which copies response
+// bodies to the client as they are read rather than
+// serializing them as a whole.
+//
+//   handler := StreamAdapter("text/csv", DefaultJSONErrorSerializer(), NewExporter()).
+//       FlushPolicy(FlushPolicy{Bytes: 64 << 10, Interval: time.Second}).
+//       StreamHook(StreamHookFunc(func(req Request, res StreamResult) {
+//           log.Printf("%s: %d bytes, %s", req.RawRequest().URL, res.Written, res.Reason)
+//       }))
+//
+// Copying stops as soon as the client disconnects, which is
+// detected through cancellation of the request's context or
+// a failed write.  Bodies implementing io.ReadCloser are
+// closed once copying stops, including while blocked in a
+// read when the client disconnects.
+//
+// Once the status line has been sent a failure to read the
+// body can no longer be reported to the client, so the
+// response is aborted to keep the client from mistaking it
+// as complete.
+type StreamingAdapter interface {
+	Adapter
+
+	// FlushPolicy sets when copied data is flushed to the
+	// client.  Defaults to the zero FlushPolicy.
+	FlushPolicy(policy FlushPolicy) StreamingAdapter
+
+	// StreamHook sets a hook notified each time a response
+	// body has been streamed, successfully or not.
+	StreamHook(hook StreamHook) StreamingAdapter
+}
+
+// StreamReason describes why the streaming of a response
+// body ended.
+type StreamReason int
+
+const (
+	// StreamCompleted indicates the whole body was written.
+	StreamCompleted StreamReason = iota
+
+	// StreamDisconnected indicates the client went away
+	// before the whole body was written.
+	StreamDisconnected
+
+	// StreamReadFailed indicates reading the body failed.
+	StreamReadFailed
+
+	// StreamPanicked indicates reading the body panicked.
+	StreamPanicked
+
+	// StreamSerializeFailed indicates a streamed value could
+	// not be serialized.
+	StreamSerializeFailed
+)
+
+// String returns a short description of the reason.
+func (s StreamReason) String() string {
+	switch s {
+	case StreamCompleted:
+		return "completed"
+	case StreamDisconnected:
+		return "disconnected"
+	case StreamReadFailed:
+		return "read failed"
+	case StreamPanicked:
+		return "panicked"
+	case StreamSerializeFailed:
+		return "serialize failed"
+	}
+	return fmt.Sprintf("StreamReason(%d)", int(s))
+}
+
+// StreamResult describes a streamed response body.
+type StreamResult struct {
+
+	// Written is the number of body bytes written to the
+	// client.
+	Written int64
+
+	// Reason is why streaming ended.
+	Reason StreamReason
+
+	// Err is the error which ended streaming, if any.  For a
+	// StreamPanicked result this is a *PanicError, and for a
+	// StreamSerializeFailed result a *SerializeError.
+	Err error
+}
+
+// StreamHook defines a service which will be notified each
+// time a StreamingAdapter finishes streaming a response
+// body.
+//
+// Useful for logging and metrics; a StreamHook cannot alter
+// the response sent to the client.
+type StreamHook interface {
+
+	// StreamEnded is called with the request being handled
+	// and the outcome of streaming its response body.
+	StreamEnded(Request, StreamResult)
+}
+
+// StreamHookFunc provides a function wrapper for simple
+// StreamHooks.
+type StreamHookFunc func(Request, StreamResult)
+
+// StreamEnded calls the wrapped hook function.
+func (s StreamHookFunc) StreamEnded(q Request, res StreamResult) {
+	s(q, res)
+}
+
+// StreamAdapter creates a new StreamingAdapter instance with
+// the provided settings.
 func StreamAdapter(
 	content string,
 	error ErrorSerializer,
 	next ...Middleware,
-) Adapter {
+) StreamingAdapter {
 	return &streamAdapter{
 		contentType:   content,
 		errSerializer: error,
@@ -29,42 +141,46 @@ type streamAdapter struct {
 	errSerializer ErrorSerializer
 	contentType   string
 	emptyHandler  EmptyHandler
+	panicHandler  PanicHandler
+	statuses      []errorStatus
+	maxBody       int64
+	form          FormOptions
+	compress      *Compression
+	decompress    *Decompression
+	conditional   ConditionalMode
+	cors          *CORS
+	flush         FlushPolicy
+	hook          StreamHook
 }
 
 func (d streamAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	var res Response
-	var wrapLen int
+	if d.cors.handle(w, r) {
+		return
+	}
+
+	w, finish := startCompression(d.compress, w, r)
+	defer finish()
 
 	req, err := NewRequest(r)
 	if err != nil {
 		d.writeError(w, err, req, NewResponse())
 		return
 	}
+	defer closeRequest(req)
+	setFormOptions(req, d.form)
+	setCompression(req, d.compress)
 
-	wrapLen = len(d.wrappers)
-	for i := 0; i < wrapLen; i++ {
-		d.wrappers[i].Request(req)
-	}
-
-	for _, hand := range d.handlers {
-		if hand == nil {
-			continue
-		}
-
-		res = hand.Handle(req)
-
-		if res != nil {
-			break
-		}
+	if err := limitBody(w, r, d.maxBody); err != nil {
+		d.writeError(w, err, req, MakeErrorResponse(http.StatusRequestEntityTooLarge, err))
+		return
 	}
 
-	res = ensureResponse(res)
-
-	for i := wrapLen - 1; i > -1; i-- {
-		res = d.wrappers[i].Response(req, res)
+	if err := decodeBody(r, d.decompress); err != nil {
+		d.writeError(w, err, req, unsupportedEncoding(d.decompress, err))
+		return
 	}
 
-	res = ensureResponse(res)
+	res := runPipeline(req, d.wrappers, d.handlers, d.panicHandler)
 
 	if res.Error() != nil {
 		d.writeError(w, res.Error(), req, res)
@@ -83,6 +199,16 @@ func (d streamAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (d *streamAdapter) FlushPolicy(policy FlushPolicy) StreamingAdapter {
+	d.flush = policy
+	return d
+}
+
+func (d *streamAdapter) StreamHook(hook StreamHook) StreamingAdapter {
+	d.hook = hook
+	return d
+}
+
 func (d *streamAdapter) EmptyHandler(handler EmptyHandler) Adapter {
 	d.emptyHandler = handler
 	return d
@@ -102,6 +228,50 @@ func (d *streamAdapter) Serializer(Serializer) Adapter {
 	return d
 }
 
+func (d *streamAdapter) PanicHandler(handler PanicHandler) Adapter {
+	d.panicHandler = handler
+	return d
+}
+
+func (d *streamAdapter) ErrorStatus(target error, code int) Adapter {
+	d.statuses = append(d.statuses, errorStatus{target: target, code: code})
+	return d
+}
+
+func (d *streamAdapter) MaxBodySize(max int64) Adapter {
+	d.maxBody = max
+	return d
+}
+
+func (d *streamAdapter) FormOptions(opts FormOptions) Adapter {
+	d.form = opts
+	return d
+}
+
+func (d *streamAdapter) Compression(c *Compression) Adapter {
+	d.compress = c
+	return d
+}
+
+func (d *streamAdapter) Decompression(c *Decompression) Adapter {
+	d.decompress = c
+	return d
+}
+
+func (d *streamAdapter) Conditional(mode ConditionalMode) Adapter {
+	d.conditional = mode
+	return d
+}
+
+func (d *streamAdapter) Cache(*Cache) Adapter {
+	return d
+}
+
+func (d *streamAdapter) CORS(c *CORS) Adapter {
+	d.cors = c
+	return d
+}
+
 func (d *streamAdapter) AddHandlers(mid ...Middleware) Adapter {
 	d.handlers = append(d.handlers, mid...)
 	return d
@@ -113,17 +283,27 @@ func (d *streamAdapter) SetHandlers(mid ...Middleware) Adapter {
 }
 
 func (d streamAdapter) writeEmpty(w http.ResponseWriter, q Request, s Response) {
-	body := d.emptyHandler.Handle(q, s)
-	d.writeResponse(w, s.Code(), s.RawHeaders(), bytes.NewBuffer(body))
+	var body []byte
+
+	if err := protect(func() { body = d.emptyHandler.Handle(q, s) }); err != nil {
+		d.writePanic(w, err, q)
+		return
+	}
+
+	d.writeResponse(w, q, s.Code(), s.RawHeaders(), bytes.NewBuffer(body), nil)
 }
 
-func (d streamAdapter) writeBody(w http.ResponseWriter, _ Request, s Response) {
+func (d streamAdapter) writeBody(w http.ResponseWriter, q Request, s Response) {
+	if d.precondition(w, q, s) {
+		if closer, ok := s.Body().(io.Closer); ok {
+			_ = closer.Close()
+		}
+		return
+	}
+
 	var read io.Reader
 
 	switch v := s.Body().(type) {
-	case io.ReadCloser:
-		defer v.Close()
-		read = v
 	case io.Reader:
 		read = v
 	case string:
@@ -131,22 +311,75 @@ func (d streamAdapter) writeBody(w http.ResponseWriter, _ Request, s Response) {
 	case []byte:
 		read = bytes.NewBuffer(v)
 	default:
-		read = bytes.NewBufferString(fmt.Sprint(v))
+		if err := protect(func() { read = bytes.NewBufferString(fmt.Sprint(v)) }); err != nil {
+			d.writePanic(w, err, q)
+			return
+		}
+	}
+
+	d.writeResponse(w, q, s.Code(), s.RawHeaders(), read, d.hook)
+}
+
+// precondition answers conditional requests with a 304 or
+// 412 in place of the given response, returning whether it
+// did so.  Only validators supplied by the handler are
+// evaluated, as the body has not been read.
+func (d streamAdapter) precondition(w http.ResponseWriter, q Request, s Response) bool {
+	mode := d.conditional
+	if mode != ConditionalOff {
+		mode = ConditionalSupplied
+	}
+
+	coding := d.compress.coding(q.RawRequest(), s.RawHeaders(), d.responseType(s), -1)
+
+	switch conditionalStatus(mode, q.RawRequest(), coding, s, nil) {
+	case http.StatusNotModified:
+		writeNotModified(w, s.RawHeaders(), coding)
+		return true
+	case http.StatusPreconditionFailed:
+		d.writeError(w, preconditionFailed(), q, s.SetCode(http.StatusPreconditionFailed))
+		return true
 	}
 
-	d.writeResponse(w, s.Code(), s.RawHeaders(), read)
+	return false
+}
+
+func (d streamAdapter) writePanic(w http.ResponseWriter, e *PanicError, q Request) {
+	d.writeError(w, e, q, panicResponse(d.panicHandler, e, q))
 }
 
 func (d streamAdapter) writeError(w http.ResponseWriter, e error, q Request, s Response) {
-	body := d.errSerializer.Serialize(e, q, s)
-	d.writeResponse(w, s.Code(), s.RawHeaders(), bytes.NewBuffer(body))
+	var body []byte
+
+	s.SetCode(resolveStatus(e, s.Code(), d.statuses))
+
+	if err := protect(func() { body = d.errSerializer.Serialize(e, q, s) }); err != nil {
+		notifyPanic(d.panicHandler, err, q)
+		writeFallbackError(w)
+		return
+	}
+
+	d.writeResponse(w, q, s.Code(), s.RawHeaders(), bytes.NewBuffer(body), nil)
+}
+
+// responseType returns the content type the given response
+// is written with.
+func (d streamAdapter) responseType(s Response) string {
+	if head := s.RawHeaders(); head != nil {
+		if _, ok := head["Content-Type"]; ok {
+			return head.Get("Content-Type")
+		}
+	}
+	return d.contentType
 }
 
 func (d streamAdapter) writeResponse(
 	w http.ResponseWriter,
+	q Request,
 	code int,
 	head http.Header,
 	body io.Reader,
+	hook StreamHook,
 ) {
 
 	// Don't override user provided header if present.
@@ -165,8 +398,85 @@ func (d streamAdapter) writeResponse(
 	w.WriteHeader(code)
 	if body == nil {
 		_, _ = w.Write([]byte{})
-	} else {
-		_, _ = io.Copy(w, body)
+		return
+	}
+
+	res := d.copyBody(w, q, body)
+	if hook != nil {
+		notifyStreamHook(hook, q, res, d.panicHandler)
+	}
+
+	// The status line has already been sent, so a failure of
+	// the body reader can only be reported, then the response
+	// aborted so the client does not mistake it as complete.
+	switch res.Reason {
+	case StreamPanicked:
+		notifyPanic(d.panicHandler, res.Err.(*PanicError), q)
+		panic(http.ErrAbortHandler)
+	case StreamReadFailed:
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// copyBody copies the given body to the client according to
+// the flush policy, until the body is exhausted or fails, or
+// the client goes away.
+func (d streamAdapter) copyBody(w http.ResponseWriter, q Request, body io.Reader) (res StreamResult) {
+	out := newFlushWriter(w, d.flush)
+	defer func() {
+		out.close()
+		res.Written = out.count()
+	}()
+
+	ctx := q.Context()
+
+	// Close the body as soon as the client goes away to
+	// unblock any pending read.
+	if closer, ok := body.(io.ReadCloser); ok {
+		var once sync.Once
+		closeBody := func() { once.Do(func() { _ = closer.Close() }) }
+		stop := context.AfterFunc(ctx, closeBody)
+		defer func() {
+			stop()
+			closeBody()
+		}()
+	}
+
+	buf := make([]byte, 32<<10)
+	for {
+		if err := ctx.Err(); err != nil {
+			return StreamResult{Reason: StreamDisconnected, Err: err}
+		}
+
+		var n int
+		var err error
+		if pe := protect(func() { n, err = body.Read(buf) }); pe != nil {
+			return StreamResult{Reason: StreamPanicked, Err: pe}
+		}
+
+		if n > 0 {
+			if _, werr := out.Write(buf[:n]); werr != nil {
+				return StreamResult{Reason: StreamDisconnected, Err: werr}
+			}
+		}
+
+		switch {
+		case err == io.EOF:
+			return StreamResult{Reason: StreamCompleted}
+		case err != nil && ctx.Err() != nil:
+			return StreamResult{Reason: StreamDisconnected, Err: ctx.Err()}
+		case err != nil:
+			return StreamResult{Reason: StreamReadFailed, Err: err}
+		}
+	}
+}
+
+// notifyStreamHook passes the given result to the StreamHook.
+//
+// Panics raised by the hook are passed to the PanicHandler.
+func notifyStreamHook(hook StreamHook, q Request, res StreamResult, ph PanicHandler) {
+	if err := protect(func() { hook.StreamEnded(q, res) }); err != nil {
+		notifyPanic(ph, err, q)
 	}
 }
 
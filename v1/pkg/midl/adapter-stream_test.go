@@ -0,0 +1,189 @@
+package midl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+type failWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func streamTestAdapter(body interface{}, results *[]StreamResult) StreamingAdapter {
+	return StreamAdapter("text/plain", DefaultJSONErrorSerializer(),
+		MiddlewareFunc(func(Request) Response {
+			return MakeResponse(http.StatusOK, body)
+		}),
+	).StreamHook(StreamHookFunc(func(_ Request, res StreamResult) {
+		*results = append(*results, res)
+	}))
+}
+
+func TestStreamAdapter_ServeHTTP(t *testing.T) {
+	c.Convey("streams the body and reports the bytes written", t, func() {
+		var results []StreamResult
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		streamTestAdapter(strings.NewReader("hello world"), &results).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, "hello world")
+		c.So(results, c.ShouldResemble, []StreamResult{{Written: 11, Reason: StreamCompleted}})
+	})
+
+	c.Convey("does not report error responses", t, func() {
+		var results []StreamResult
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		StreamAdapter("text/plain", DefaultJSONErrorSerializer(),
+			MiddlewareFunc(func(Request) Response {
+				return MakeErrorResponse(http.StatusTeapot, errors.New("short"))
+			}),
+		).StreamHook(StreamHookFunc(func(_ Request, res StreamResult) {
+			results = append(results, res)
+		})).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusTeapot)
+		c.So(results, c.ShouldBeEmpty)
+	})
+
+	c.Convey("flushes after each read", t, func() {
+		var results []StreamResult
+		w := &syncRecorder{ResponseRecorder: httptest.NewRecorder()}
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		streamTestAdapter(iotest.OneByteReader(strings.NewReader("abc")), &results).
+			FlushPolicy(FlushPolicy{EachWrite: true}).
+			ServeHTTP(w, r)
+
+		c.So(w.flushCount(), c.ShouldEqual, 3)
+		c.So(w.Body.String(), c.ShouldEqual, "abc")
+	})
+
+	c.Convey("flushes every N bytes", t, func() {
+		var results []StreamResult
+		w := &syncRecorder{ResponseRecorder: httptest.NewRecorder()}
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		streamTestAdapter(iotest.OneByteReader(strings.NewReader("abcde")), &results).
+			FlushPolicy(FlushPolicy{Bytes: 2}).
+			ServeHTTP(w, r)
+
+		// Two flushes at the threshold and one for the remainder.
+		c.So(w.flushCount(), c.ShouldEqual, 3)
+	})
+
+	c.Convey("flushes pending data on an interval", t, func() {
+		var results []StreamResult
+		read, write := io.Pipe()
+		done := make(chan struct{})
+
+		w := &syncRecorder{ResponseRecorder: httptest.NewRecorder()}
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		go func() {
+			defer close(done)
+			streamTestAdapter(read, &results).
+				FlushPolicy(FlushPolicy{Interval: 5 * time.Millisecond}).
+				ServeHTTP(w, r)
+		}()
+
+		_, _ = write.Write([]byte("tick"))
+		time.Sleep(50 * time.Millisecond)
+		c.So(w.flushed(), c.ShouldEqual, "tick")
+
+		_ = write.Close()
+		<-done
+		c.So(results[0].Reason, c.ShouldEqual, StreamCompleted)
+	})
+
+	c.Convey("closes a blocked body when the client disconnects", t, func() {
+		var results []StreamResult
+		read, write := io.Pipe()
+		ctx, cancel := context.WithCancel(context.Background())
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil).WithContext(ctx)
+
+		go func() {
+			_, _ = write.Write([]byte("partial"))
+			cancel()
+		}()
+
+		streamTestAdapter(read, &results).ServeHTTP(w, r)
+
+		c.So(results, c.ShouldHaveLength, 1)
+		c.So(results[0].Reason, c.ShouldEqual, StreamDisconnected)
+		c.So(results[0].Written, c.ShouldEqual, 7)
+		c.So(results[0].Err, c.ShouldEqual, context.Canceled)
+
+		_, err := write.Write([]byte("more"))
+		c.So(err, c.ShouldEqual, io.ErrClosedPipe)
+	})
+
+	c.Convey("stops when writing to the client fails", t, func() {
+		var results []StreamResult
+		read, write := io.Pipe()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		go func() { _, _ = write.Write([]byte("data")) }()
+
+		streamTestAdapter(read, &results).
+			ServeHTTP(failWriter{httptest.NewRecorder()}, r)
+
+		c.So(results[0].Reason, c.ShouldEqual, StreamDisconnected)
+		c.So(results[0].Err, c.ShouldBeError, "broken pipe")
+
+		_, err := write.Write([]byte("more"))
+		c.So(err, c.ShouldEqual, io.ErrClosedPipe)
+	})
+
+	c.Convey("aborts the response when reading the body fails", t, func() {
+		var results []StreamResult
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		test := streamTestAdapter(iotest.ErrReader(errors.New("disk")), &results)
+
+		c.So(func() { test.ServeHTTP(w, r) }, c.ShouldPanicWith, http.ErrAbortHandler)
+		c.So(results[0].Reason, c.ShouldEqual, StreamReadFailed)
+		c.So(results[0].Err, c.ShouldBeError, "disk")
+	})
+
+	c.Convey("reports panics from the body reader", t, func() {
+		var results []StreamResult
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		test := streamTestAdapter(panicReader{}, &results)
+
+		c.So(func() { test.ServeHTTP(w, r) }, c.ShouldPanicWith, http.ErrAbortHandler)
+		c.So(results[0].Reason, c.ShouldEqual, StreamPanicked)
+		c.So(results[0].Err, c.ShouldHaveSameTypeAs, &PanicError{})
+	})
+}
+
+func TestStreamReason_String(t *testing.T) {
+	c.Convey("describes each reason", t, func() {
+		c.So(StreamCompleted.String(), c.ShouldEqual, "completed")
+		c.So(StreamDisconnected.String(), c.ShouldEqual, "disconnected")
+		c.So(StreamReadFailed.String(), c.ShouldEqual, "read failed")
+		c.So(StreamPanicked.String(), c.ShouldEqual, "panicked")
+		c.So(StreamReason(9).String(), c.ShouldEqual, "StreamReason(9)")
+	})
+}
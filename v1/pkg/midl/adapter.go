@@ -32,6 +32,111 @@ type Adapter interface {
 	// Serializer registers the default body serializer.
 	Serializer(Serializer) Adapter
 
+	// PanicHandler registers a handler which will be notified
+	// of any panic recovered while handling a request.
+	//
+	// Recovered panics are always written to the client as a
+	// 500 through the ErrorSerializer, whether or not a
+	// PanicHandler is registered.
+	PanicHandler(PanicHandler) Adapter
+
+	// ErrorStatus registers the HTTP status code to use for
+	// error responses whose error matches the given target
+	// according to errors.Is.
+	//
+	// Before an error is passed to the ErrorSerializer the
+	// response status code is resolved in the following
+	// order:
+	//
+	// * The status of the first error in the chain
+	// implementing StatusCoder, such as HTTPError.
+	// * The status registered for the first matching target,
+	// in the order they were registered.
+	// * The status already set on the response if it is 400
+	// or above, otherwise 500.
+	ErrorStatus(target error, code int) Adapter
+
+	// MaxBodySize sets the maximum number of bytes that will be
+	// read from a request body.
+	//
+	// Requests declaring a larger Content-Length are rejected
+	// with a 413 (Request Entity Too Large) through the
+	// ErrorSerializer before any Middleware is called.  For
+	// other requests, reads past the limit fail with an
+	// *http.MaxBytesError, which resolves to a 413.
+	//
+	// A value of zero or less disables the limit, which is
+	// the default.
+	MaxBodySize(max int64) Adapter
+
+	// FormOptions sets the memory threshold and size limits
+	// used when parsing form request bodies with
+	// Request.FormValue, Request.File and related methods.
+	//
+	// Temporary files created for uploaded files are removed
+	// once the response has been written.
+	FormOptions(FormOptions) Adapter
+
+	// Compression enables compression of response bodies
+	// using the given settings, negotiated per request from
+	// the Accept-Encoding header.
+	//
+	// A nil Compression disables compression, which is the
+	// default.
+	Compression(*Compression) Adapter
+
+	// Decompression sets how compressed request bodies are
+	// decoded, according to their Content-Encoding header,
+	// before being read by any Middleware.
+	//
+	// A nil Decompression uses the settings returned by
+	// NewDecompression, which is the default.
+	Decompression(*Decompression) Adapter
+
+	// Conditional sets how conditional requests are answered.
+	//
+	// Once a response has been produced its ETag and
+	// Last-Modified headers, supplied by the handler or
+	// generated from the serialized body as selected by the
+	// mode, are evaluated against the request's If-Match,
+	// If-Unmodified-Since, If-None-Match and If-Modified-Since
+	// headers.  Requests whose representation has not changed
+	// receive a 304 (Not Modified) without a body; requests
+	// whose preconditions fail receive a 412 (Precondition
+	// Failed) through the ErrorSerializer.
+	//
+	// Only successful responses to GET and HEAD requests are
+	// evaluated.  Handlers of other methods must check
+	// preconditions with CheckPreconditions before changing
+	// the resource.  Streaming Adapters only evaluate
+	// validators supplied by the handler, and event streams
+	// are not evaluated at all.
+	//
+	// Defaults to ConditionalOff.
+	Conditional(ConditionalMode) Adapter
+
+	// Cache enables caching of serialized responses, serving
+	// cached responses to matching requests without calling
+	// any RequestWrapper or Middleware.  See Cache for the
+	// responses which are stored.
+	//
+	// Conditional requests are evaluated against cached
+	// responses as they are for fresh ones.  Streaming
+	// Adapters ignore this setting.
+	//
+	// A nil Cache disables caching, which is the default.
+	Cache(*Cache) Adapter
+
+	// CORS enables Cross-Origin Resource Sharing, answering
+	// preflight requests before any RequestWrapper or
+	// Middleware is called and setting the Access-Control-*
+	// headers on every other response, including error
+	// responses.
+	//
+	// A nil CORS disables CORS handling, which is the
+	// default.
+	CORS(*CORS) Adapter
+
 	// AddHandlers appends handlers to the list of Middleware
 	// handlers.
 	AddHandlers(...Middleware) Adapter
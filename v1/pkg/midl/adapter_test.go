@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"errors"
+	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	c "github.com/smartystreets/goconvey/convey"
@@ -335,3 +337,62 @@ func TestNewAdapter(t *testing.T) {
 		c.So(tst.contentType, c.ShouldEqual, "something/custom")
 	})
 }
+
+func TestDefaultAdapter_MaxBodySize(t *testing.T) {
+	echo := MiddlewareFunc(func(r Request) Response {
+		body := r.Body()
+		if r.Error() != nil {
+			return MakeErrorResponse(http.StatusInternalServerError, r.Error())
+		}
+		return MakeResponse(http.StatusOK, string(body))
+	})
+	text := SerializerFunc(func(in interface{}) ([]byte, error) {
+		return []byte(in.(string)), nil
+	})
+
+	c.Convey("rejects declared lengths over the limit", t, func() {
+		called := false
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("123456"))
+
+		JSONAdapter(MiddlewareFunc(func(Request) Response {
+			called = true
+			return nil
+		})).MaxBodySize(5).ServeHTTP(w, r)
+
+		c.So(called, c.ShouldBeFalse)
+		c.So(w.Code, c.ShouldEqual, http.StatusRequestEntityTooLarge)
+		c.So(w.Body.String(), c.ShouldEqual, `{"error":"http: request body too large"}`)
+	})
+
+	c.Convey("rejects bodies which read past the limit", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("123456"))
+		r.ContentLength = -1
+
+		JSONAdapter(echo).Serializer(text).MaxBodySize(5).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+
+	c.Convey("accepts bodies within the limit", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("12345"))
+
+		JSONAdapter(echo).Serializer(text).MaxBodySize(5).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, "12345")
+	})
+
+	c.Convey("applies to the stream adapter", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("123456"))
+
+		StreamAdapter("text/plain", DefaultJSONErrorSerializer(), echo).
+			MaxBodySize(5).
+			ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+}
@@ -0,0 +1,179 @@
+package midl
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// bindSources lists the struct tags read by Request.Bind
+// in the order they are applied.
+var bindSources = []string{"path", "query", "header", "form"}
+
+// BindError is returned by Request.Bind for targets which
+// cannot be bound, such as fields of an unsupported type.
+//
+// A BindError describes a programming error rather than a
+// problem with the request, so it resolves to a 500 even
+// when passed to MakeErrorResponse with a 400.
+type BindError struct {
+
+	// Field is the name of the offending struct field, or
+	// empty if the target itself is not a struct pointer.
+	Field string
+
+	// Type is the type of the offending field or target.
+	Type reflect.Type
+}
+
+// Error describes the unbindable field or target.
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("midl: Bind target must be a non-nil struct pointer, got %v", e.Type)
+	}
+	return fmt.Sprintf("midl: cannot bind field %s of unsupported type %v", e.Field, e.Type)
+}
+
+// StatusCode returns 500 (Internal Server Error).
+func (e *BindError) StatusCode() int {
+	return http.StatusInternalServerError
+}
+
+// binder fills struct fields from the parts of a request.
+type binder struct {
+	req  *request
+	errs *ValidationError
+}
+
+func (r *request) Bind(dst interface{}) Request {
+	if r.error != nil {
+		return r
+	}
+
+	val := reflect.ValueOf(dst)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		r.error = &BindError{Type: reflect.TypeOf(dst)}
+		return r
+	}
+
+	b := binder{req: r, errs: new(ValidationError)}
+	if err := b.bindStruct(val.Elem()); err != nil {
+		r.error = err
+	} else if !b.errs.Empty() {
+		r.error = b.errs
+	}
+
+	return r
+}
+
+func (b *binder) bindStruct(dst reflect.Value) error {
+	typ := dst.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		embedded := field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag == ""
+
+		if embedded {
+			if err := b.bindStruct(dst.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if _, ok := field.Tag.Lookup("body"); ok {
+			if err := b.bindBody(dst.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		for _, source := range bindSources {
+			name, ok := field.Tag.Lookup(source)
+			if !ok || name == "-" {
+				continue
+			}
+
+			if !bindable(field.Type) {
+				return &BindError{Field: fieldName(typ, field), Type: field.Type}
+			}
+
+			values := b.values(source, name)
+			if err := setValues(dst.Field(i), values, field.Tag.Get("layout")); err != nil {
+				b.errs.Add(source, name, err.Error())
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// fieldName returns the qualified name of the given field.
+func fieldName(typ reflect.Type, field reflect.StructField) string {
+	if typ.Name() == "" {
+		return field.Name
+	}
+	return typ.Name() + "." + field.Name
+}
+
+// values returns the raw values for the given name from the
+// given request source.
+func (b *binder) values(source, name string) []string {
+	switch source {
+	case "path":
+		if val := b.req.PathParam(name); val != "" {
+			return []string{val}
+		}
+	case "query":
+		return b.req.queryValues()[name]
+	case "header":
+		return b.req.raw.Header.Values(name)
+	case "form":
+		values, _ := b.req.FormValues(name)
+		return values
+	}
+
+	return nil
+}
+
+// bindBody decodes the request body into the given field
+// using the decoder for the request's Content-Type.  Form
+// bodies are left to the form tagged fields.
+func (b *binder) bindBody(dst reflect.Value) error {
+	if b.req.isForm() {
+		return nil
+	}
+
+	body := b.req.Body()
+	if b.req.error != nil {
+		return b.req.error
+	}
+
+	if len(body) == 0 {
+		return nil
+	}
+
+	contentType, ok := b.req.Header("Content-Type")
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+
+	dec, ok := findDecoder(DefaultDecoders(), contentType)
+	if !ok {
+		return &HTTPError{
+			Status:  http.StatusUnsupportedMediaType,
+			Message: ErrUnsupportedMediaType.Error(),
+			Cause:   ErrUnsupportedMediaType,
+		}
+	}
+
+	if err := dec.Decode(body, dst.Addr().Interface()); err != nil {
+		b.errs.Add("body", "", err.Error())
+	}
+
+	return nil
+}
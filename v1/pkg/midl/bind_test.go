@@ -0,0 +1,177 @@
+package midl
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+type bindPage struct {
+	Page  int    `query:"page"`
+	Limit *uint8 `query:"limit"`
+}
+
+type bindTarget struct {
+	bindPage
+
+	ID      int64         `path:"id"`
+	Tags    []string      `query:"tag"`
+	Debug   bool          `query:"debug"`
+	Ratio   float64       `query:"ratio"`
+	Since   time.Time     `query:"since" layout:"2006-01-02"`
+	Timeout time.Duration `query:"timeout"`
+	Addr    net.IP        `query:"addr"`
+	Token   *string       `header:"X-Token"`
+	Accept  []string      `header:"Accept"`
+	Ignored string        `query:"-"`
+	Name    string        `form:"name"`
+	Body    typedInput    `body:""`
+}
+
+func bindTestRequest(url, contentType, body string) *http.Request {
+	raw := httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(body))
+	if contentType != "" {
+		raw.Header.Set("Content-Type", contentType)
+	}
+	return raw
+}
+
+func TestRequest_Bind(t *testing.T) {
+	c.Convey("binds query, header and path values", t, func() {
+		raw := bindTestRequest("http://foo.bar/?page=3&limit=20&tag=a&tag=b&debug=true"+
+			"&ratio=0.5&since=2020-01-02&timeout=1m30s&addr=10.0.0.1&Ignored=x", "", "")
+		raw.Header.Set("X-Token", "secret")
+		raw.Header.Add("Accept", "text/plain")
+		raw.Header.Add("Accept", "application/json")
+		raw.SetPathValue("id", "42")
+
+		var out bindTarget
+		req, _ := NewRequest(raw)
+
+		c.So(req.Bind(&out).Error(), c.ShouldBeNil)
+		c.So(out.Page, c.ShouldEqual, 3)
+		c.So(*out.Limit, c.ShouldEqual, 20)
+		c.So(out.ID, c.ShouldEqual, 42)
+		c.So(out.Tags, c.ShouldResemble, []string{"a", "b"})
+		c.So(out.Debug, c.ShouldBeTrue)
+		c.So(out.Ratio, c.ShouldEqual, 0.5)
+		c.So(out.Since, c.ShouldEqual, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+		c.So(out.Timeout, c.ShouldEqual, 90*time.Second)
+		c.So(out.Addr.String(), c.ShouldEqual, "10.0.0.1")
+		c.So(*out.Token, c.ShouldEqual, "secret")
+		c.So(out.Accept, c.ShouldResemble, []string{"text/plain", "application/json"})
+		c.So(out.Ignored, c.ShouldEqual, "")
+	})
+
+	c.Convey("leaves missing values unchanged", t, func() {
+		out := bindTarget{Tags: []string{"x"}}
+		out.Page = 1
+		req, _ := NewRequest(bindTestRequest("http://foo.bar", "", ""))
+
+		c.So(req.Bind(&out).Error(), c.ShouldBeNil)
+		c.So(out.Page, c.ShouldEqual, 1)
+		c.So(out.Limit, c.ShouldBeNil)
+		c.So(out.Tags, c.ShouldResemble, []string{"x"})
+		c.So(out.Token, c.ShouldBeNil)
+	})
+
+	c.Convey("binds URL encoded form values", t, func() {
+		var out bindTarget
+		req, _ := NewRequest(bindTestRequest("http://foo.bar",
+			"application/x-www-form-urlencoded; charset=utf-8", "name=foo+bar"))
+
+		c.So(req.Bind(&out).Error(), c.ShouldBeNil)
+		c.So(out.Name, c.ShouldEqual, "foo bar")
+		c.So(out.Body, c.ShouldResemble, typedInput{})
+	})
+
+	c.Convey("decodes the body by content type", t, func() {
+		var out bindTarget
+		req, _ := NewRequest(bindTestRequest("http://foo.bar", "application/json", `{"name":"foo"}`))
+
+		c.So(req.Bind(&out).Error(), c.ShouldBeNil)
+		c.So(out.Body.Name, c.ShouldEqual, "foo")
+		c.So(req.Body(), c.ShouldResemble, []byte(`{"name":"foo"}`))
+	})
+
+	c.Convey("rejects unsupported body types with a 415", t, func() {
+		var out bindTarget
+		req, _ := NewRequest(bindTestRequest("http://foo.bar", "text/csv", "a,b"))
+
+		err := req.Bind(&out).Error()
+		c.So(err, c.ShouldBeError)
+		c.So(ResolveStatus(err, 0), c.ShouldEqual, http.StatusUnsupportedMediaType)
+	})
+
+	c.Convey("aggregates conversion failures", t, func() {
+		var out bindTarget
+		req, _ := NewRequest(bindTestRequest("http://foo.bar/?page=x&limit=300&since=yesterday",
+			"application/json", `{"name":`))
+
+		err := req.Bind(&out).Error()
+		val, ok := err.(*ValidationError)
+		c.So(ok, c.ShouldBeTrue)
+		c.So(val.StatusCode(), c.ShouldEqual, http.StatusBadRequest)
+		c.So(val.Fields, c.ShouldHaveLength, 4)
+		c.So(val.Fields[0], c.ShouldResemble, FieldError{In: "query", Field: "page", Message: "must be an integer"})
+		c.So(val.Fields[1], c.ShouldResemble, FieldError{In: "query", Field: "limit", Message: "is out of range"})
+		c.So(val.Fields[2], c.ShouldResemble,
+			FieldError{In: "query", Field: "since", Message: "must be a time in the format 2006-01-02"})
+		c.So(val.Fields[3].In, c.ShouldEqual, "body")
+	})
+
+	c.Convey("rejects non struct pointer targets", t, func() {
+		var out bindTarget
+		req, _ := NewRequest(bindTestRequest("http://foo.bar", "", ""))
+
+		err := req.Bind(out).Error()
+		c.So(err, c.ShouldHaveSameTypeAs, &BindError{})
+		c.So(ResolveStatus(err, http.StatusBadRequest), c.ShouldEqual, http.StatusInternalServerError)
+	})
+
+	c.Convey("rejects fields of unsupported types as a programming error", t, func() {
+		var out struct {
+			Filter map[string]string `query:"filter"`
+		}
+		req, _ := NewRequest(bindTestRequest("http://foo.bar", "", ""))
+
+		err := req.Bind(&out).Error()
+		c.So(err, c.ShouldResemble, &BindError{Field: "Filter", Type: reflect.TypeOf(out.Filter)})
+		c.So(ResolveStatus(err, http.StatusBadRequest), c.ShouldEqual, http.StatusInternalServerError)
+	})
+
+	c.Convey("does nothing after a previous error", t, func() {
+		var out bindTarget
+		req, _ := NewRequest(bindTestRequest("http://foo.bar/?page=x&ratio=1", "", ""))
+		req.Bind(&out)
+		first := req.Error()
+
+		c.So(req.Bind(&out).Error(), c.ShouldEqual, first)
+	})
+
+	c.Convey("renders binding errors through the adapter", t, func() {
+		rec := httptest.NewRecorder()
+		JSONAdapter(MiddlewareFunc(func(req Request) Response {
+			var out bindTarget
+			if err := req.Bind(&out).Error(); err != nil {
+				return MakeErrorResponse(http.StatusInternalServerError, err)
+			}
+			return MakeResponse(http.StatusOK, out)
+		})).ServeHTTP(rec, bindTestRequest("http://foo.bar/?ratio=high", "", ""))
+
+		var body map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &body)
+
+		c.So(rec.Code, c.ShouldEqual, http.StatusBadRequest)
+		c.So(body["code"], c.ShouldEqual, "invalid_request")
+		c.So(body["details"], c.ShouldResemble, []interface{}{map[string]interface{}{
+			"in": "query", "field": "ratio", "message": "must be a number"}})
+	})
+}
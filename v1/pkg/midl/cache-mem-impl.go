@@ -0,0 +1,128 @@
+package midl
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheMaxEntries is the number of entries held by
+	// the MemoryCacheStore created by NewCache.
+	DefaultCacheMaxEntries = 1024
+
+	// DefaultCacheMaxBytes is the number of bytes held by the
+	// MemoryCacheStore created by NewCache.
+	DefaultCacheMaxBytes int64 = 32 << 20
+)
+
+// MemoryCacheStore is an in-memory CacheStore evicting the
+// least recently used entries once its entry or size limits
+// are reached.
+type MemoryCacheStore struct {
+	mut        sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	size       int64
+	order      *list.List
+	entries    map[string]*list.Element
+
+	now func() time.Time
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry *CacheEntry
+	size  int64
+}
+
+// NewMemoryCacheStore creates a new MemoryCacheStore holding
+// at most the given number of entries and bytes.  A limit of
+// zero or less disables it.
+func NewMemoryCacheStore(maxEntries int, maxBytes int64) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+		now:        time.Now,
+	}
+}
+
+func (m *MemoryCacheStore) Get(key string) (*CacheEntry, bool) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := el.Value.(*memoryCacheItem)
+	if !m.now().Before(item.entry.Expires) {
+		m.remove(el)
+		return nil, false
+	}
+
+	m.order.MoveToFront(el)
+	return item.entry, true
+}
+
+func (m *MemoryCacheStore) Set(key string, entry *CacheEntry) {
+	size := entry.Size() + int64(len(key))
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+
+	if m.maxBytes > 0 && size > m.maxBytes {
+		return
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryCacheItem{key: key, entry: entry, size: size})
+	m.size += size
+
+	for m.over() {
+		m.remove(m.order.Back())
+	}
+}
+
+func (m *MemoryCacheStore) Delete(key string) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+}
+
+// Len returns the number of entries held by the store.
+func (m *MemoryCacheStore) Len() int {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	return m.order.Len()
+}
+
+// Size returns the approximate number of bytes held by the
+// store.
+func (m *MemoryCacheStore) Size() int64 {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	return m.size
+}
+
+func (m *MemoryCacheStore) over() bool {
+	return (m.maxEntries > 0 && m.order.Len() > m.maxEntries) ||
+		(m.maxBytes > 0 && m.size > m.maxBytes)
+}
+
+func (m *MemoryCacheStore) remove(el *list.Element) {
+	item := m.order.Remove(el).(*memoryCacheItem)
+	delete(m.entries, item.key)
+	m.size -= item.size
+}
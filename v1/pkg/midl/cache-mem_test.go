@@ -0,0 +1,99 @@
+package midl
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func memoryCacheEntry(body string) *CacheEntry {
+	return &CacheEntry{Body: []byte(body), Expires: time.Now().Add(time.Hour)}
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	c.Convey("stores and deletes entries", t, func() {
+		store := NewMemoryCacheStore(0, 0)
+		entry := memoryCacheEntry("body")
+
+		store.Set("a", entry)
+		got, ok := store.Get("a")
+		c.So(ok, c.ShouldBeTrue)
+		c.So(got, c.ShouldEqual, entry)
+		c.So(store.Size(), c.ShouldEqual, 5)
+
+		store.Delete("a")
+		_, ok = store.Get("a")
+		c.So(ok, c.ShouldBeFalse)
+		c.So(store.Len(), c.ShouldEqual, 0)
+		c.So(store.Size(), c.ShouldEqual, 0)
+	})
+
+	c.Convey("drops expired entries", t, func() {
+		store := NewMemoryCacheStore(0, 0)
+		store.Set("a", memoryCacheEntry("body"))
+		store.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+		_, ok := store.Get("a")
+		c.So(ok, c.ShouldBeFalse)
+		c.So(store.Len(), c.ShouldEqual, 0)
+	})
+
+	c.Convey("evicts the least recently used entries", t, func() {
+		c.Convey("past the entry limit", func() {
+			store := NewMemoryCacheStore(2, 0)
+			store.Set("a", memoryCacheEntry("1"))
+			store.Set("b", memoryCacheEntry("2"))
+			store.Get("a")
+			store.Set("c", memoryCacheEntry("3"))
+
+			_, okA := store.Get("a")
+			_, okB := store.Get("b")
+			_, okC := store.Get("c")
+			c.So(okA, c.ShouldBeTrue)
+			c.So(okB, c.ShouldBeFalse)
+			c.So(okC, c.ShouldBeTrue)
+		})
+
+		c.Convey("past the size limit", func() {
+			store := NewMemoryCacheStore(0, 20)
+			store.Set("a", memoryCacheEntry("123456789"))
+			store.Set("b", memoryCacheEntry("123456789"))
+			store.Set("c", memoryCacheEntry("123456789"))
+
+			c.So(store.Len(), c.ShouldEqual, 2)
+			c.So(store.Size(), c.ShouldEqual, 20)
+			_, ok := store.Get("a")
+			c.So(ok, c.ShouldBeFalse)
+		})
+	})
+
+	c.Convey("skips entries larger than the size limit", t, func() {
+		store := NewMemoryCacheStore(0, 4)
+		store.Set("a", memoryCacheEntry("too large"))
+
+		c.So(store.Len(), c.ShouldEqual, 0)
+	})
+
+	c.Convey("is safe for concurrent use", t, func() {
+		store := NewMemoryCacheStore(16, 0)
+		var wg sync.WaitGroup
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					key := fmt.Sprint(i * j % 32)
+					store.Set(key, memoryCacheEntry(key))
+					store.Get(key)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		c.So(store.Len(), c.ShouldBeLessThanOrEqualTo, 16)
+	})
+}
@@ -0,0 +1,343 @@
+package midl
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL is how long a response is cached for when
+// neither the Cache nor the response sets a lifetime.
+const DefaultCacheTTL = time.Minute
+
+// CacheEntry is a serialized response held by a CacheStore.
+type CacheEntry struct {
+
+	// Code is the HTTP status code of the response.
+	Code int
+
+	// Header holds the headers set on the response.
+	Header http.Header
+
+	// Body is the serialized response body.
+	Body []byte
+
+	// Vary holds the values of the request headers named by
+	// the response's Vary header, which must match for the
+	// entry to be used.
+	Vary http.Header
+
+	// Stored is the time the entry was created.
+	Stored time.Time
+
+	// Expires is the time after which the entry must no
+	// longer be used.
+	Expires time.Time
+}
+
+// Size returns the approximate number of bytes held by the
+// entry.
+func (e *CacheEntry) Size() int64 {
+	size := int64(len(e.Body))
+
+	for _, head := range []http.Header{e.Header, e.Vary} {
+		for key, values := range head {
+			size += int64(len(key))
+			for _, val := range values {
+				size += int64(len(val))
+			}
+		}
+	}
+
+	return size
+}
+
+// CacheStore defines a backend holding cached responses.
+//
+// Implementations must be safe for concurrent use and must
+// not modify entries once stored.
+type CacheStore interface {
+
+	// Get returns the entry stored under the given key, if
+	// present and not expired.
+	Get(key string) (*CacheEntry, bool)
+
+	// Set stores the given entry under the given key,
+	// replacing any existing entry.
+	Set(key string, entry *CacheEntry)
+
+	// Delete removes the entry stored under the given key.
+	Delete(key string)
+}
+
+// Cache configures the caching of serialized responses.
+//
+//   handler := JSONAdapter(NewController()).
+//       Cache(NewCache(nil).TTL(5 * time.Minute).Vary("Accept-Language"))
+//
+// Successful GET and HEAD responses are stored along with
+// their status and headers, keyed on the request method,
+// host, path, query, response content type and the values
+// of the configured request headers.  Cached responses are served
+// without calling any RequestWrapper or Middleware.
+//
+// The Cache-Control headers of both sides are honoured:
+//
+// * Requests with no-store, or any of the credential
+// headers configured with Bypass, bypass the cache.
+// * Requests with no-cache or a max-age older than the
+// entry are handled and the fresh response is stored.
+// * Responses with no-store, no-cache or private, a
+// Set-Cookie header or a Vary header of "*" are not stored.
+// * Responses with s-maxage or max-age are stored for that
+// long in place of the configured TTL.
+//
+// Responses varying on request headers not configured with
+// Vary are stored once per key, and only used by requests
+// with the same values for those headers.
+//
+// A Cache must not be modified once in use.
+type Cache struct {
+	store  CacheStore
+	ttl    time.Duration
+	vary   []string
+	bypass []string
+}
+
+// NewCache creates a new Cache keeping responses in the
+// given store for DefaultCacheTTL.  A nil store uses a new
+// MemoryCacheStore with the default limits.
+//
+// Requests with an Authorization, Cookie or X-API-Key header
+// bypass the cache.
+func NewCache(store CacheStore) *Cache {
+	if store == nil {
+		store = NewMemoryCacheStore(DefaultCacheMaxEntries, DefaultCacheMaxBytes)
+	}
+
+	return &Cache{
+		store:  store,
+		ttl:    DefaultCacheTTL,
+		bypass: []string{"Authorization", "Cookie", "X-Api-Key"},
+	}
+}
+
+// TTL sets how long responses without a max-age or s-maxage
+// directive are cached for.  A value of zero or less only
+// caches responses which set a lifetime.
+func (c *Cache) TTL(ttl time.Duration) *Cache {
+	c.ttl = ttl
+	return c
+}
+
+// Vary adds request headers whose values are part of the
+// cache key.
+func (c *Cache) Vary(headers ...string) *Cache {
+	for _, h := range headers {
+		c.vary = append(c.vary, http.CanonicalHeaderKey(h))
+	}
+	return c
+}
+
+// Bypass adds request headers carrying credentials.
+// Requests with any of them are neither served from nor
+// stored in the cache, as their responses may be specific to
+// the client.
+func (c *Cache) Bypass(headers ...string) *Cache {
+	for _, h := range headers {
+		c.bypass = append(c.bypass, http.CanonicalHeaderKey(h))
+	}
+	return c
+}
+
+// key returns the cache key for the given request, or false
+// if the request may not use the cache.
+func (c *Cache) key(r *http.Request, contentType string) (string, bool) {
+	if c == nil || r == nil || r.URL == nil {
+		return "", false
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return "", false
+	}
+
+	for _, name := range c.bypass {
+		if r.Header.Get(name) != "" {
+			return "", false
+		}
+	}
+
+	if _, ok := cacheDirectives(r.Header)["no-store"]; ok {
+		return "", false
+	}
+
+	var key strings.Builder
+	key.WriteString(r.Method)
+	key.WriteByte(' ')
+	key.WriteString(r.Host)
+	key.WriteString(r.URL.Path)
+	key.WriteByte('?')
+	key.WriteString(r.URL.Query().Encode())
+	key.WriteByte('\n')
+	key.WriteString(contentType)
+
+	for _, name := range c.vary {
+		key.WriteByte('\n')
+		key.WriteString(name)
+		key.WriteByte(':')
+		key.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+
+	return key.String(), true
+}
+
+// lookup returns the entry usable for the given request.
+func (c *Cache) lookup(r *http.Request, key string, now time.Time) (*CacheEntry, bool) {
+	dirs := cacheDirectives(r.Header)
+	if _, ok := dirs["no-cache"]; ok {
+		return nil, false
+	}
+
+	entry, ok := c.store.Get(key)
+	if !ok || !now.Before(entry.Expires) {
+		return nil, false
+	}
+
+	if age, ok := directiveSeconds(dirs, "max-age"); ok && now.Sub(entry.Stored) > age {
+		return nil, false
+	}
+
+	for name, values := range entry.Vary {
+		if strings.Join(r.Header.Values(name), ",") != strings.Join(values, ",") {
+			return nil, false
+		}
+	}
+
+	return entry, true
+}
+
+// save stores the given serialized response if it may be
+// cached.
+func (c *Cache) save(r *http.Request, key string, s Response, body []byte, now time.Time) {
+	if !cacheableStatus(s.Code()) {
+		return
+	}
+
+	head := s.RawHeaders()
+	if len(head.Values("Set-Cookie")) > 0 {
+		return
+	}
+
+	dirs := cacheDirectives(head)
+	for _, d := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := dirs[d]; ok {
+			return
+		}
+	}
+
+	ttl, ok := directiveSeconds(dirs, "s-maxage")
+	if !ok {
+		ttl, ok = directiveSeconds(dirs, "max-age")
+	}
+	if !ok {
+		ttl = c.ttl
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	vary := http.Header{}
+	for _, value := range head.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return
+			}
+			if name != "" {
+				vary[http.CanonicalHeaderKey(name)] = r.Header.Values(name)
+			}
+		}
+	}
+
+	c.store.Set(key, &CacheEntry{
+		Code:    s.Code(),
+		Header:  head.Clone(),
+		Body:    append([]byte(nil), body...),
+		Vary:    vary,
+		Stored:  now,
+		Expires: now.Add(ttl),
+	})
+}
+
+// response rebuilds a Response from the given entry, with
+// an Age header.
+func (e *CacheEntry) response(now time.Time) Response {
+	out := NewResponse().SetCode(e.Code)
+
+	for key, values := range e.Header {
+		out.SetHeaders(key, append([]string(nil), values...))
+	}
+
+	return out.SetHeader("Age", strconv.FormatInt(int64(now.Sub(e.Stored)/time.Second), 10))
+}
+
+// cacheableStatus returns whether responses with the given
+// status may be cached without explicit freshness
+// information, as listed by RFC 9110.
+func cacheableStatus(code int) bool {
+	switch code {
+	case http.StatusOK,
+		http.StatusNonAuthoritativeInfo,
+		http.StatusNoContent,
+		http.StatusMultipleChoices,
+		http.StatusMovedPermanently,
+		http.StatusPermanentRedirect,
+		http.StatusNotFound,
+		http.StatusMethodNotAllowed,
+		http.StatusGone,
+		http.StatusRequestURITooLong,
+		http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
+
+// cacheDirectives parses the Cache-Control header of the
+// given headers into a map of lower cased directive names to
+// their unquoted values.
+func cacheDirectives(head http.Header) map[string]string {
+	out := map[string]string{}
+
+	for _, value := range head.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			name, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+			out[strings.ToLower(name)] = strings.Trim(val, `"`)
+		}
+	}
+
+	return out
+}
+
+// directiveSeconds returns the value of a delta-seconds
+// directive as a duration.
+func directiveSeconds(dirs map[string]string, name string) (time.Duration, bool) {
+	val, ok := dirs[name]
+	if !ok {
+		return 0, false
+	}
+
+	secs, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	if max := int64(1<<63-1) / int64(time.Second); secs > max {
+		secs = max
+	}
+
+	return time.Duration(secs) * time.Second, true
+}
@@ -0,0 +1,209 @@
+package midl
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func cacheTestAdapter(cache *Cache, calls *int, edit func(Request, Response)) Adapter {
+	return JSONAdapter(MiddlewareFunc(func(r Request) Response {
+		*calls++
+		res := MakeResponse(http.StatusOK, map[string]interface{}{"call": *calls})
+		if edit != nil {
+			edit(r, res)
+		}
+		return res
+	})).Cache(cache)
+}
+
+func cacheTestRequest(h http.Handler, method, url string, head ...string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, url, nil)
+	for i := 0; i < len(head); i += 2 {
+		r.Header.Set(head[i], head[i+1])
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestCache(t *testing.T) {
+	c.Convey("serves hits without calling the middleware", t, func() {
+		var calls int
+		h := cacheTestAdapter(NewCache(nil), &calls, func(_ Request, s Response) {
+			s.SetHeader("X-Custom", "yes")
+		})
+
+		first := cacheTestRequest(h, http.MethodGet, "http://foo.bar/a?x=1&y=2")
+		second := cacheTestRequest(h, http.MethodGet, "http://foo.bar/a?y=2&x=1")
+
+		c.So(calls, c.ShouldEqual, 1)
+		c.So(first.Header().Get("Age"), c.ShouldEqual, "")
+		c.So(second.Code, c.ShouldEqual, http.StatusOK)
+		c.So(second.Body.String(), c.ShouldEqual, `{"call":1}`)
+		c.So(second.Header().Get("Age"), c.ShouldEqual, "0")
+		c.So(second.Header().Get("X-Custom"), c.ShouldEqual, "yes")
+		c.So(second.Header().Get("Content-Type"), c.ShouldEqual, "application/json")
+	})
+
+	c.Convey("keys on method, host, path, query and configured headers", t, func() {
+		var calls int
+		h := cacheTestAdapter(NewCache(nil).Vary("accept-language"), &calls, nil)
+
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a")
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/b")
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a?x=1")
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "Accept-Language", "fr")
+		cacheTestRequest(h, http.MethodGet, "http://other.bar/a")
+		cacheTestRequest(h, http.MethodHead, "http://foo.bar/a")
+		c.So(calls, c.ShouldEqual, 6)
+
+		w := cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "Accept-Language", "fr")
+		c.So(calls, c.ShouldEqual, 6)
+		c.So(w.Body.String(), c.ShouldEqual, `{"call":4}`)
+	})
+
+	c.Convey("matches the request headers named by the response Vary header", t, func() {
+		var calls int
+		h := cacheTestAdapter(NewCache(nil), &calls, func(_ Request, s Response) {
+			s.SetHeader("Vary", "X-Tenant")
+		})
+
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "X-Tenant", "a")
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "X-Tenant", "a")
+		c.So(calls, c.ShouldEqual, 1)
+
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "X-Tenant", "b")
+		c.So(calls, c.ShouldEqual, 2)
+	})
+
+	c.Convey("keys on the negotiated content type", t, func() {
+		var calls int
+		h := JSONXMLAdapter(MiddlewareFunc(func(Request) Response {
+			calls++
+			return MakeResponse(http.StatusOK, struct{ A int }{calls})
+		})).Cache(NewCache(nil))
+
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "Accept", "application/json")
+		w := cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "Accept", "application/xml")
+		c.So(calls, c.ShouldEqual, 2)
+		c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "application/xml")
+
+		w = cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "Accept", "application/json")
+		c.So(calls, c.ShouldEqual, 2)
+		c.So(w.Body.String(), c.ShouldEqual, `{"A":1}`)
+	})
+
+	c.Convey("bypasses the cache", t, func() {
+		for _, test := range []struct {
+			name   string
+			method string
+			head   []string
+		}{
+			{"for unsafe methods", http.MethodPost, nil},
+			{"for authorized requests", http.MethodGet, []string{"Authorization", "Bearer x"}},
+			{"for requests with cookies", http.MethodGet, []string{"Cookie", "session=x"}},
+			{"for requests with API keys", http.MethodGet, []string{"X-API-Key", "x"}},
+			{"for requests with configured credentials", http.MethodGet, []string{"X-Session", "x"}},
+			{"for no-store requests", http.MethodGet, []string{"Cache-Control", "no-store"}},
+		} {
+			var calls int
+			h := cacheTestAdapter(NewCache(nil).Bypass("x-session"), &calls, nil)
+
+			cacheTestRequest(h, test.method, "http://foo.bar/a", test.head...)
+			cacheTestRequest(h, test.method, "http://foo.bar/a", test.head...)
+			c.So(calls, c.ShouldEqual, 2)
+		}
+
+		c.Convey("without storing responses to credentialed requests", func() {
+			var calls int
+			h := cacheTestAdapter(NewCache(nil), &calls, nil)
+
+			cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "Cookie", "session=x")
+			w := cacheTestRequest(h, http.MethodGet, "http://foo.bar/a")
+			c.So(calls, c.ShouldEqual, 2)
+			c.So(w.Header().Get("Age"), c.ShouldEqual, "")
+		})
+	})
+
+	c.Convey("refreshes entries for no-cache and max-age requests", t, func() {
+		var calls int
+		store := NewMemoryCacheStore(0, 0)
+		h := cacheTestAdapter(NewCache(store), &calls, nil)
+
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a")
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "Cache-Control", "no-cache")
+		c.So(calls, c.ShouldEqual, 2)
+
+		w := cacheTestRequest(h, http.MethodGet, "http://foo.bar/a")
+		c.So(calls, c.ShouldEqual, 2)
+		c.So(w.Body.String(), c.ShouldEqual, `{"call":2}`)
+
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "Cache-Control", "max-age=60")
+		c.So(calls, c.ShouldEqual, 2)
+
+		for _, el := range store.entries {
+			el.Value.(*memoryCacheItem).entry.Stored = time.Now().Add(-2 * time.Minute)
+			el.Value.(*memoryCacheItem).entry.Expires = time.Now().Add(time.Minute)
+		}
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "Cache-Control", "max-age=60")
+		c.So(calls, c.ShouldEqual, 3)
+	})
+
+	c.Convey("does not store", t, func() {
+		for _, test := range []struct {
+			name string
+			edit func(Request, Response)
+		}{
+			{"no-store responses", func(_ Request, s Response) { s.SetHeader("Cache-Control", "no-store") }},
+			{"private responses", func(_ Request, s Response) { s.SetHeader("Cache-Control", "private, max-age=60") }},
+			{"responses setting cookies", func(_ Request, s Response) { s.SetCookie(&http.Cookie{Name: "a", Value: "b"}) }},
+			{"responses varying on anything", func(_ Request, s Response) { s.SetHeader("Vary", "*") }},
+			{"uncacheable statuses", func(_ Request, s Response) { s.SetCode(http.StatusAccepted) }},
+			{"error responses", func(_ Request, s Response) { s.SetError(errors.New("boom")) }},
+		} {
+			var calls int
+			h := cacheTestAdapter(NewCache(nil), &calls, test.edit)
+
+			cacheTestRequest(h, http.MethodGet, "http://foo.bar/a")
+			cacheTestRequest(h, http.MethodGet, "http://foo.bar/a")
+			c.So(calls, c.ShouldEqual, 2)
+		}
+	})
+
+	c.Convey("uses the response lifetime over the configured TTL", t, func() {
+		var calls int
+		store := NewMemoryCacheStore(0, 0)
+		h := cacheTestAdapter(NewCache(store).TTL(0), &calls, nil)
+
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a")
+		c.So(store.Len(), c.ShouldEqual, 0)
+
+		h = cacheTestAdapter(NewCache(store).TTL(0), &calls, func(_ Request, s Response) {
+			s.SetHeader("Cache-Control", "max-age=10, s-maxage=30")
+		})
+		cacheTestRequest(h, http.MethodGet, "http://foo.bar/a")
+		c.So(store.Len(), c.ShouldEqual, 1)
+
+		for _, el := range store.entries {
+			entry := el.Value.(*memoryCacheItem).entry
+			c.So(entry.Expires.Sub(entry.Stored), c.ShouldEqual, 30*time.Second)
+		}
+	})
+
+	c.Convey("answers conditional requests from cached responses", t, func() {
+		var calls int
+		h := cacheTestAdapter(NewCache(nil), &calls, nil).Conditional(ConditionalStrongETag)
+
+		first := cacheTestRequest(h, http.MethodGet, "http://foo.bar/a")
+		w := cacheTestRequest(h, http.MethodGet, "http://foo.bar/a", "If-None-Match", first.Header().Get("ETag"))
+
+		c.So(calls, c.ShouldEqual, 1)
+		c.So(w.Code, c.ShouldEqual, http.StatusNotModified)
+		c.So(w.Body.Len(), c.ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,543 @@
+package midl
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultCompressionMinSize is the minimum response body
+// size in bytes compressed by a Compression created with
+// NewCompression.
+const DefaultCompressionMinSize = 1024
+
+// DefaultUncompressedTypes lists the content types which a
+// Compression created with NewCompression does not compress,
+// as they are already compressed.
+var DefaultUncompressedTypes = []string{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"image/avif",
+	"video/*",
+	"audio/*",
+	"font/woff",
+	"font/woff2",
+	"application/gzip",
+	"application/x-gzip",
+	"application/zip",
+	"application/zstd",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/pdf",
+}
+
+// EncodingWriter compresses the data written to it.
+//
+// Flush writes any buffered data to the underlying writer,
+// and Close writes any remaining data and the stream footer.
+type EncodingWriter interface {
+	io.WriteCloser
+
+	Flush() error
+}
+
+// Encoder defines a service which creates writers for a
+// single content coding, such as gzip.
+type Encoder interface {
+
+	// NewWriter returns a writer compressing into the given
+	// writer.  The returned writer is closed once the response
+	// has been written.
+	NewWriter(w io.Writer) EncodingWriter
+}
+
+// EncoderFunc provides a function wrapper for simple
+// Encoders.
+type EncoderFunc func(w io.Writer) EncodingWriter
+
+// NewWriter calls the wrapped encoder function.
+func (e EncoderFunc) NewWriter(w io.Writer) EncodingWriter {
+	return e(w)
+}
+
+// GzipEncoder returns an Encoder for the gzip content coding
+// using the given compression level, as defined by the
+// compress/gzip package.  Writers are pooled and reused.
+//
+// Panics if the compression level is invalid.
+func GzipEncoder(level int) Encoder {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		panic(fmt.Sprintf("midl: invalid gzip level %d", level))
+	}
+
+	out := &pooledEncoder{}
+	out.pool.New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, level)
+		return w
+	}
+	return out
+}
+
+// DeflateEncoder returns an Encoder for the deflate content
+// coding, the zlib format, using the given compression
+// level as defined by the compress/zlib package.  Writers
+// are pooled and reused.
+//
+// Panics if the compression level is invalid.
+func DeflateEncoder(level int) Encoder {
+	if _, err := zlib.NewWriterLevel(io.Discard, level); err != nil {
+		panic(fmt.Sprintf("midl: invalid deflate level %d", level))
+	}
+
+	out := &pooledEncoder{}
+	out.pool.New = func() interface{} {
+		w, _ := zlib.NewWriterLevel(io.Discard, level)
+		return w
+	}
+	return out
+}
+
+// resettable is implemented by the gzip and zlib writers.
+type resettable interface {
+	EncodingWriter
+
+	Reset(w io.Writer)
+}
+
+type pooledEncoder struct {
+	pool sync.Pool
+}
+
+func (p *pooledEncoder) NewWriter(w io.Writer) EncodingWriter {
+	enc := p.pool.Get().(resettable)
+	enc.Reset(w)
+	return &pooledWriter{resettable: enc, pool: &p.pool}
+}
+
+// pooledWriter returns its writer to the pool once closed.
+type pooledWriter struct {
+	resettable
+	pool *sync.Pool
+}
+
+func (p *pooledWriter) Close() error {
+	err := p.resettable.Close()
+	p.resettable.Reset(io.Discard)
+	p.pool.Put(p.resettable)
+	return err
+}
+
+// Compression configures the compression of response
+// bodies, negotiated from the request's Accept-Encoding
+// header.
+//
+//   handler := JSONAdapter(NewController()).Compression(NewCompression())
+//
+// Bodies are compressed as they are written, so streamed
+// responses remain streamed; flushing a response flushes the
+// compressor.  Responses are left uncompressed if:
+//
+// * The client accepts none of the registered encodings.
+// * The body is smaller than the minimum size.  For bodies
+// without a Content-Length header this is decided once the
+// minimum size has been written, the response is flushed,
+// or the response ends.
+// * The content type is one of the skipped types.
+// * A Content-Encoding header is already set.
+// * The status code does not allow a body.
+//
+// Compressed responses have their Content-Length header
+// removed.  A "Vary: Accept-Encoding" header is added to
+// every response which could have been compressed.  Strong
+// ETags of compressed responses get the coding appended,
+// such as `"v1-gzip"` for `"v1"`.  Conditional requests
+// match such a tag only if they negotiate the same coding,
+// and 304 (Not Modified) responses carry it as the full
+// response would.
+//
+// A Compression must not be modified once in use.
+type Compression struct {
+	encodings []namedEncoder
+	minSize   int
+	skip      []mediaRange
+}
+
+type namedEncoder struct {
+	name    string
+	encoder Encoder
+}
+
+// NewCompression creates a new Compression which offers
+// gzip and deflate at their default compression levels,
+// compressing bodies of at least DefaultCompressionMinSize
+// bytes except for the DefaultUncompressedTypes.
+func NewCompression() *Compression {
+	return (&Compression{minSize: DefaultCompressionMinSize}).
+		Register("gzip", GzipEncoder(gzip.DefaultCompression)).
+		Register("deflate", DeflateEncoder(zlib.DefaultCompression)).
+		Skip(DefaultUncompressedTypes...)
+}
+
+// Register adds an encoder for the given content coding,
+// replacing any encoder registered for the same coding.
+//
+// When two codings are equally acceptable to a client, the
+// one registered first is preferred.
+func (c *Compression) Register(encoding string, enc Encoder) *Compression {
+	encoding = strings.ToLower(encoding)
+
+	for i := range c.encodings {
+		if c.encodings[i].name == encoding {
+			c.encodings[i].encoder = enc
+			return c
+		}
+	}
+
+	c.encodings = append(c.encodings, namedEncoder{name: encoding, encoder: enc})
+	return c
+}
+
+// MinSize sets the minimum body size in bytes to compress.
+func (c *Compression) MinSize(size int) *Compression {
+	c.minSize = size
+	return c
+}
+
+// Skip adds content types which should not be compressed.
+// Types may use a wildcard subtype such as "video/*".
+func (c *Compression) Skip(types ...string) *Compression {
+	for _, typ := range types {
+		if rng, ok := parseMediaRange(typ); ok {
+			c.skip = append(c.skip, rng)
+		}
+	}
+	return c
+}
+
+// negotiate picks the registered encoder with the highest
+// quality value from the given Accept-Encoding headers.
+// Returns a nil Encoder if none is acceptable.
+func (c *Compression) negotiate(values []string) (string, Encoder) {
+	codings := parseAcceptEncoding(values)
+
+	var best namedEncoder
+	var bestQ float64
+	for _, enc := range c.encodings {
+		if q := codingQuality(codings, enc.name); q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+
+	return best.name, best.encoder
+}
+
+// coding returns the coding the response to the given
+// request is compressed with, given its headers, content
+// type and body size, or an empty string if it is sent
+// uncompressed.  A negative size stands for a body of
+// unknown size, which is compressed once large enough.
+func (c *Compression) coding(r *http.Request, head http.Header, contentType string, size int) string {
+	if c == nil || r == nil {
+		return ""
+	}
+
+	name, enc := c.negotiate(r.Header.Values("Accept-Encoding"))
+	switch {
+	case enc == nil, head.Get("Content-Encoding") != "", c.skips(contentType):
+		return ""
+	case size >= 0 && size < c.minSize:
+		return ""
+	}
+
+	if length := head.Get("Content-Length"); length != "" {
+		if n, err := strconv.Atoi(length); err == nil && n < c.minSize {
+			return ""
+		}
+	}
+
+	return name
+}
+
+// codingETag returns the entity tag of the representation
+// with the given tag compressed with the named coding.  Weak
+// tags are returned unchanged.
+func codingETag(etag, coding string) string {
+	if strings.HasPrefix(etag, "W/") || len(etag) < 2 || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + coding + `"`
+}
+
+// skips returns whether the given content type is excluded
+// from compression.
+func (c *Compression) skips(contentType string) bool {
+	kind, subKind, ok := splitMediaType(contentType)
+	if !ok {
+		return false
+	}
+
+	for _, rng := range c.skip {
+		if rng.matches(kind, subKind) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptCoding is a single parsed entry from an
+// Accept-Encoding header.
+type acceptCoding struct {
+	name    string
+	quality float64
+}
+
+// parseAcceptEncoding parses the values of one or more
+// Accept-Encoding headers, skipping malformed entries.
+func parseAcceptEncoding(values []string) []acceptCoding {
+	var out []acceptCoding
+
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			params := strings.Split(part, ";")
+			name := strings.ToLower(strings.TrimSpace(params[0]))
+			if name == "" {
+				continue
+			}
+
+			if q, ok := parseQuality(params[1:]); ok {
+				out = append(out, acceptCoding{name: name, quality: q})
+			}
+		}
+	}
+
+	return out
+}
+
+// codingQuality returns the quality value the given codings
+// assign to the named coding, falling back to the "*" entry.
+// Returns 0 if neither is present.
+func codingQuality(codings []acceptCoding, name string) float64 {
+	wild := 0.0
+	for _, cod := range codings {
+		switch cod.name {
+		case name:
+			return cod.quality
+		case "*":
+			wild = cod.quality
+		}
+	}
+	return wild
+}
+
+// startCompression wraps the given writer to compress the
+// response to the given request, if a Compression is set.
+// The returned function must be called once the response
+// has been written.
+func startCompression(c *Compression, w writer, r *http.Request) (writer, func()) {
+	if c == nil || r == nil {
+		return w, func() {}
+	}
+
+	name, enc := c.negotiate(r.Header.Values("Accept-Encoding"))
+	out := &compressWriter{ResponseWriter: w, comp: c, name: name, enc: enc}
+	return out, out.close
+}
+
+// compressWriter defers writing the response header until
+// enough of the body has been written to decide whether to
+// compress it.
+type compressWriter struct {
+	http.ResponseWriter
+
+	comp    *Compression
+	name    string
+	enc     Encoder
+	code    int
+	decided bool
+	buf     []byte
+	zw      EncodingWriter
+}
+
+func (c *compressWriter) WriteHeader(code int) {
+	switch {
+	case c.decided, code < http.StatusOK:
+		c.ResponseWriter.WriteHeader(code)
+	case c.code == 0:
+		c.code = code
+	}
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	if !c.decided {
+		if c.code == 0 {
+			c.code = http.StatusOK
+		}
+
+		if c.compressible() {
+			c.buf = append(c.buf, p...)
+			if len(c.buf) < c.comp.minSize {
+				return len(p), nil
+			}
+			return len(p), c.start(true)
+		}
+
+		if err := c.start(false); err != nil {
+			return 0, err
+		}
+	}
+
+	if c.zw != nil {
+		return c.zw.Write(p)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Flush sends any buffered data to the client, compressing
+// the rest of the response if it may be compressed at all.
+func (c *compressWriter) Flush() {
+	_ = c.FlushError()
+}
+
+// FlushError is the Flush variant used by
+// http.ResponseController.
+func (c *compressWriter) FlushError() error {
+	if !c.decided {
+		if c.code == 0 {
+			c.code = http.StatusOK
+		}
+		if err := c.start(c.compressible()); err != nil {
+			return err
+		}
+	}
+
+	if c.zw != nil {
+		if err := c.zw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// Unwrap returns the wrapped writer for use by
+// http.ResponseController.
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// varies returns whether the response could be compressed,
+// depending on the client's Accept-Encoding header.
+func (c *compressWriter) varies() bool {
+	head := c.Header()
+
+	switch {
+	case c.code == http.StatusNoContent, c.code == http.StatusNotModified:
+		return false
+	case head.Get("Content-Encoding") != "":
+		return false
+	case c.comp.skips(head.Get("Content-Type")):
+		return false
+	}
+
+	return true
+}
+
+// compressible returns whether the response should be
+// compressed, given enough body.
+func (c *compressWriter) compressible() bool {
+	if c.enc == nil || !c.varies() {
+		return false
+	}
+
+	if length := c.Header().Get("Content-Length"); length != "" {
+		if n, err := strconv.Atoi(length); err == nil && n < c.comp.minSize {
+			return false
+		}
+	}
+
+	return true
+}
+
+// start writes the response header and any buffered body,
+// compressing the rest of the response if requested.
+func (c *compressWriter) start(compress bool) error {
+	c.decided = true
+	head := c.Header()
+
+	if c.varies() && !headerHasToken(head, "Vary", "Accept-Encoding") {
+		head.Add("Vary", "Accept-Encoding")
+	}
+
+	if compress {
+		// Sniff the uncompressed body, as the server would
+		// otherwise sniff the compressed one.
+		if head.Get("Content-Type") == "" {
+			head.Set("Content-Type", http.DetectContentType(c.buf))
+		}
+		head.Del("Content-Length")
+		head.Set("Content-Encoding", c.name)
+
+		// The compressed bytes differ from those a strong ETag
+		// was computed for, so each coding gets its own tag.
+		if etag := head.Get("ETag"); etag != "" {
+			head.Set("ETag", codingETag(etag, c.name))
+		}
+	}
+
+	c.ResponseWriter.WriteHeader(c.code)
+	if compress {
+		c.zw = c.enc.NewWriter(c.ResponseWriter)
+	}
+
+	buf := c.buf
+	c.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	if c.zw != nil {
+		_, err := c.zw.Write(buf)
+		return err
+	}
+
+	_, err := c.ResponseWriter.Write(buf)
+	return err
+}
+
+// close writes out any body too small to be compressed, or
+// finishes the compressed stream.
+func (c *compressWriter) close() {
+	if !c.decided && (c.code != 0 || len(c.buf) > 0) {
+		if c.code == 0 {
+			c.code = http.StatusOK
+		}
+		_ = c.start(false)
+	}
+
+	if c.zw != nil {
+		_ = c.zw.Close()
+		c.zw = nil
+	}
+}
+
+// headerHasToken returns whether any of the comma separated
+// values of the given header contains the given token.
+func headerHasToken(head http.Header, key, token string) bool {
+	for _, value := range head.Values(key) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package midl
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func gunzip(body []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return "invalid gzip: " + err.Error()
+	}
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func compressTestAdapter(body string, head ...string) Adapter {
+	return JSONAdapter(MiddlewareFunc(func(Request) Response {
+		res := MakeResponse(http.StatusOK, body)
+		for i := 0; i < len(head); i += 2 {
+			res.SetHeader(head[i], head[i+1])
+		}
+		return res
+	})).Serializer(SerializerFunc(func(v interface{}) ([]byte, error) {
+		return []byte(v.(string)), nil
+	})).Compression(NewCompression())
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat("compress me ", 200)
+
+	c.Convey("compresses large bodies with the preferred encoding", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept-Encoding", "deflate;q=0.5, gzip")
+
+		compressTestAdapter(large).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Header().Get("Content-Encoding"), c.ShouldEqual, "gzip")
+		c.So(w.Header().Get("Vary"), c.ShouldEqual, "Accept-Encoding")
+		c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "application/json")
+		c.So(w.Body.Len(), c.ShouldBeLessThan, len(large))
+		c.So(gunzip(w.Body.Bytes()), c.ShouldEqual, large)
+	})
+
+	c.Convey("supports deflate", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept-Encoding", "deflate")
+
+		compressTestAdapter(large).ServeHTTP(w, r)
+
+		c.So(w.Header().Get("Content-Encoding"), c.ShouldEqual, "deflate")
+		zr, err := zlib.NewReader(w.Body)
+		c.So(err, c.ShouldBeNil)
+		out, _ := io.ReadAll(zr)
+		c.So(string(out), c.ShouldEqual, large)
+	})
+
+	c.Convey("leaves responses uncompressed", t, func() {
+		cases := []struct {
+			name   string
+			accept string
+			body   string
+			head   []string
+			vary   string
+		}{
+			{"without Accept-Encoding", "", large, nil, "Accept-Encoding"},
+			{"for unsupported encodings", "br, gzip;q=0", large, nil, "Accept-Encoding"},
+			{"for small bodies", "gzip", "small", nil, "Accept-Encoding"},
+			{"for compressed types", "gzip", large, []string{"Content-Type", "image/png"}, ""},
+			{"for wildcard compressed types", "gzip", large, []string{"Content-Type", "video/mp4"}, ""},
+			{"when already encoded", "gzip", large, []string{"Content-Encoding", "br"}, ""},
+		}
+
+		for _, test := range cases {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+			if test.accept != "" {
+				r.Header.Set("Accept-Encoding", test.accept)
+			}
+
+			compressTestAdapter(test.body, test.head...).ServeHTTP(w, r)
+
+			c.So(w.Body.String(), c.ShouldEqual, test.body)
+			c.So(w.Header().Get("Vary"), c.ShouldEqual, test.vary)
+			if len(test.head) == 0 {
+				c.So(w.Header().Get("Content-Encoding"), c.ShouldEqual, "")
+			}
+		}
+	})
+
+	c.Convey("accepts any registered encoding through a wildcard", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept-Encoding", "*")
+
+		compressTestAdapter(large).ServeHTTP(w, r)
+
+		c.So(w.Header().Get("Content-Encoding"), c.ShouldEqual, "gzip")
+	})
+
+	c.Convey("skips bodies with a small Content-Length", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+
+		compressTestAdapter(large, "Content-Length", "10").ServeHTTP(w, r)
+
+		c.So(w.Header().Get("Content-Encoding"), c.ShouldEqual, "")
+	})
+
+	c.Convey("uses registered encoders", t, func() {
+		var used bool
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept-Encoding", "test")
+
+		comp := NewCompression().MinSize(0).Register("test", EncoderFunc(func(w io.Writer) EncodingWriter {
+			used = true
+			return gzip.NewWriter(w)
+		}))
+		compressTestAdapter("tiny").Compression(comp).ServeHTTP(w, r)
+
+		c.So(used, c.ShouldBeTrue)
+		c.So(w.Header().Get("Content-Encoding"), c.ShouldEqual, "test")
+		c.So(gunzip(w.Body.Bytes()), c.ShouldEqual, "tiny")
+	})
+
+	c.Convey("compresses streamed responses as they are flushed", t, func() {
+		read, write := io.Pipe()
+		done := make(chan struct{})
+
+		w := &syncRecorder{ResponseRecorder: httptest.NewRecorder()}
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+
+		go func() {
+			defer close(done)
+			StreamAdapter("text/plain", DefaultJSONErrorSerializer(),
+				MiddlewareFunc(func(Request) Response {
+					return MakeResponse(http.StatusOK, read)
+				}),
+			).FlushPolicy(FlushPolicy{EachWrite: true}).
+				Compression(NewCompression()).
+				ServeHTTP(w, r)
+		}()
+
+		_, _ = write.Write([]byte("first"))
+		for deadline := time.Now().Add(time.Second); w.flushCount() == 0 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+
+		zr, err := gzip.NewReader(strings.NewReader(w.flushed()))
+		c.So(err, c.ShouldBeNil)
+		buf := make([]byte, 5)
+		_, err = io.ReadFull(zr, buf)
+		c.So(err, c.ShouldBeNil)
+		c.So(string(buf), c.ShouldEqual, "first")
+
+		_ = write.Close()
+		<-done
+
+		c.So(w.Header().Get("Content-Encoding"), c.ShouldEqual, "gzip")
+		c.So(gunzip(w.Body.Bytes()), c.ShouldEqual, "first")
+	})
+
+	c.Convey("does not compress responses without a body", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+
+		JSONAdapter(MiddlewareFunc(func(Request) Response {
+			return NewResponse().SetCode(http.StatusNoContent)
+		})).Compression(NewCompression()).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusNoContent)
+		c.So(w.Header().Get("Content-Encoding"), c.ShouldEqual, "")
+		c.So(w.Header().Get("Vary"), c.ShouldEqual, "")
+	})
+}
+
+func TestGzipEncoder(t *testing.T) {
+	c.Convey("panics on invalid levels", t, func() {
+		c.So(func() { GzipEncoder(42) }, c.ShouldPanic)
+		c.So(func() { DeflateEncoder(42) }, c.ShouldPanic)
+	})
+}
@@ -0,0 +1,262 @@
+package midl
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ConditionalMode selects how an Adapter evaluates
+// conditional requests.
+type ConditionalMode int
+
+const (
+	// ConditionalOff disables the evaluation of conditional
+	// request headers.
+	ConditionalOff ConditionalMode = iota
+
+	// ConditionalSupplied evaluates conditional request
+	// headers against the ETag and Last-Modified headers set
+	// on the Response by a handler.
+	ConditionalSupplied
+
+	// ConditionalWeakETag behaves as ConditionalSupplied,
+	// generating a weak ETag from the serialized body for
+	// responses without one.
+	ConditionalWeakETag
+
+	// ConditionalStrongETag behaves as ConditionalSupplied,
+	// generating a strong ETag from the serialized body for
+	// responses without one.
+	ConditionalStrongETag
+)
+
+// CheckPreconditions evaluates the conditional headers of
+// the given request against the current validators of the
+// target resource, returning an HTTPError with a 412
+// (Precondition Failed) status if they do not hold.
+//
+// Handlers of methods other than GET and HEAD must call this
+// before modifying the resource, as the Adapter only
+// evaluates the conditions of those two methods once the
+// handler has returned.
+//
+//   if err := midl.CheckPreconditions(req, doc.ETag, doc.Updated); err != nil {
+//       return midl.MakeErrorResponse(http.StatusPreconditionFailed, err)
+//   }
+//
+// The etag is given as it would appear in an ETag header,
+// such as `"v1"` or `W/"v1"`, and may be empty if unknown,
+// as may the modification time.  If the resource does not
+// exist both must be empty.  A 304 (Not Modified) outcome
+// for safe methods is not an error.
+func CheckPreconditions(req Request, etag string, modified time.Time) error {
+	var coding string
+	if r, ok := req.(*request); ok {
+		coding = r.compress.coding(r.raw, nil, "", -1)
+	}
+
+	exists := etag != "" || !modified.IsZero()
+	if evalConditions(req.RawRequest(), coding, etag, modified, exists) == http.StatusPreconditionFailed {
+		return preconditionFailed()
+	}
+	return nil
+}
+
+func preconditionFailed() *HTTPError {
+	return NewHTTPError(http.StatusPreconditionFailed, ErrPreconditionFailed.Error()).
+		WithCause(ErrPreconditionFailed)
+}
+
+// bodyETag returns an ETag for the given serialized body.
+func bodyETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	tag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// formatETag quotes the given opaque tag for use in an ETag
+// header.
+func formatETag(tag string, weak bool) string {
+	tag = `"` + strings.ReplaceAll(tag, `"`, "") + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// evalConditions evaluates the conditional headers of the
+// given request, in the order defined by RFC 9110, against
+// the given validators of the selected representation.
+// exists reports whether there is a current representation
+// and coding names the coding it is compressed with, if any.
+//
+// Returns 304 or 412 if the request should be answered with
+// that status instead, otherwise 0.
+func evalConditions(r *http.Request, coding, etag string, modified time.Time, exists bool) int {
+	modified = modified.Truncate(time.Second)
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if match := r.Header.Get("If-Match"); match != "" {
+		if !exists || !matchETag(match, etag, coding, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := headerTime(r, "If-Unmodified-Since"); ok && !modified.IsZero() {
+		if modified.After(since) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if exists && matchETag(match, etag, coding, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if since, ok := headerTime(r, "If-Modified-Since"); ok && safe && !modified.IsZero() {
+		if !modified.After(since) {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+func headerTime(r *http.Request, key string) (time.Time, bool) {
+	val := r.Header.Get(key)
+	if val == "" {
+		return time.Time{}, false
+	}
+
+	out, err := http.ParseTime(val)
+	return out, err == nil
+}
+
+// matchETag returns whether any entity tag in the given
+// If-Match or If-None-Match header value matches the given
+// ETag, using weak or strong comparison.  If the response is
+// compressed with the given coding, the strong tag codingETag
+// derives for that coding matches as well.
+func matchETag(header, etag, coding string, weakCompare bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	weak, opaque, ok := parseETag(etag)
+	if !ok || (weak && !weakCompare) {
+		return false
+	}
+
+	coded := opaque
+	if coding != "" && !weak {
+		coded = codingETag(opaque, coding)
+	}
+
+	for rest := header; ; {
+		var w bool
+		var o string
+		w, o, rest, ok = nextETag(rest)
+		if !ok {
+			return false
+		}
+
+		if (o == opaque || o == coded) && (weakCompare || !w) {
+			return true
+		}
+	}
+}
+
+// parseETag splits a single entity tag into its weakness
+// and opaque quoted value.
+func parseETag(in string) (weak bool, opaque string, ok bool) {
+	weak, opaque, rest, ok := nextETag(in)
+	if !ok || strings.TrimSpace(rest) != "" {
+		return false, "", false
+	}
+	return weak, opaque, true
+}
+
+// nextETag reads the first entity tag from a comma separated
+// list, returning the rest of the list.
+func nextETag(in string) (weak bool, opaque, rest string, ok bool) {
+	in = strings.TrimLeft(in, " \t,")
+	if strings.HasPrefix(in, "W/") {
+		weak = true
+		in = in[2:]
+	}
+
+	if len(in) < 2 || in[0] != '"' {
+		return false, "", "", false
+	}
+
+	end := strings.IndexByte(in[1:], '"')
+	if end < 0 {
+		return false, "", "", false
+	}
+
+	return weak, in[:end+2], in[end+2:], true
+}
+
+// writeNotModified writes a 304 response keeping only the
+// headers allowed on it.  The ETag is adjusted for the coding
+// the full response would have been compressed with, if any.
+func writeNotModified(w writer, head http.Header, coding string) {
+	for _, key := range []string{
+		"Cache-Control",
+		"Content-Location",
+		"Date",
+		"ETag",
+		"Expires",
+		"Last-Modified",
+		"Vary",
+		"Set-Cookie",
+	} {
+		for _, val := range head.Values(key) {
+			if key == "ETag" && coding != "" {
+				val = codingETag(val, coding)
+			}
+			w.Header().Add(key, val)
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// conditionalStatus evaluates the given request against the
+// validators of the given response, generating an ETag from
+// the serialized body if the mode requires it.  Returns 304,
+// 412 or 0 as with evalConditions, where coding names the
+// coding the response is compressed with.
+//
+// Only GET and HEAD requests are evaluated.  The response to
+// any other method describes the resource after the handler
+// changed it, so its validators say nothing about whether
+// the request's preconditions held; handlers of those
+// methods must use CheckPreconditions instead.
+func conditionalStatus(mode ConditionalMode, r *http.Request, coding string, s Response, body []byte) int {
+	if mode == ConditionalOff || s.Code() < 200 || s.Code() > 299 {
+		return 0
+	}
+
+	head := s.RawHeaders()
+	if head.Get("ETag") == "" && (mode == ConditionalWeakETag || mode == ConditionalStrongETag) {
+		s.SetHeader("ETag", bodyETag(body, mode == ConditionalWeakETag))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return 0
+	}
+
+	var modified time.Time
+	if val := head.Get("Last-Modified"); val != "" {
+		modified, _ = http.ParseTime(val)
+	}
+
+	return evalConditions(r, coding, head.Get("ETag"), modified, true)
+}
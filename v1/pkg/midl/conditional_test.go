@@ -0,0 +1,319 @@
+package midl
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func conditionalTestAdapter(mode ConditionalMode, edit func(Response)) Adapter {
+	return JSONAdapter(MiddlewareFunc(func(Request) Response {
+		res := MakeResponse(http.StatusOK, map[string]string{"a": "b"})
+		if edit != nil {
+			edit(res)
+		}
+		return res
+	})).Conditional(mode)
+}
+
+func TestConditional(t *testing.T) {
+	c.Convey("generates ETags from the serialized body", t, func() {
+		for mode, weak := range map[ConditionalMode]bool{ConditionalStrongETag: false, ConditionalWeakETag: true} {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+			conditionalTestAdapter(mode, nil).ServeHTTP(w, r)
+
+			etag := w.Header().Get("ETag")
+			c.So(w.Code, c.ShouldEqual, http.StatusOK)
+			c.So(w.Body.String(), c.ShouldEqual, `{"a":"b"}`)
+			c.So(etag, c.ShouldEqual, bodyETag([]byte(`{"a":"b"}`), weak))
+			c.So(strings.HasPrefix(etag, "W/"), c.ShouldEqual, weak)
+		}
+	})
+
+	c.Convey("replies 304 when If-None-Match matches", t, func() {
+		etag := bodyETag([]byte(`{"a":"b"}`), false)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("If-None-Match", `"other", W/`+etag)
+
+		conditionalTestAdapter(ConditionalStrongETag, func(s Response) {
+			s.SetHeader("Cache-Control", "max-age=60").SetHeader("X-Other", "1")
+		}).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusNotModified)
+		c.So(w.Body.Len(), c.ShouldEqual, 0)
+		c.So(w.Header().Get("ETag"), c.ShouldEqual, etag)
+		c.So(w.Header().Get("Cache-Control"), c.ShouldEqual, "max-age=60")
+		c.So(w.Header().Get("X-Other"), c.ShouldEqual, "")
+		c.So(w.Header().Get("Content-Type"), c.ShouldEqual, "")
+	})
+
+	c.Convey("replies 304 when not modified since", t, func() {
+		modified := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+
+		conditionalTestAdapter(ConditionalSupplied, func(s Response) {
+			s.SetLastModified(modified)
+		}).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusNotModified)
+		c.So(w.Header().Get("Last-Modified"), c.ShouldEqual, modified.Format(http.TimeFormat))
+
+		w = httptest.NewRecorder()
+		r.Header.Set("If-Modified-Since", modified.Add(-time.Hour).Format(http.TimeFormat))
+
+		conditionalTestAdapter(ConditionalSupplied, func(s Response) {
+			s.SetLastModified(modified)
+		}).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+	})
+
+	c.Convey("prefers If-None-Match over If-Modified-Since", t, func() {
+		modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("If-None-Match", `"stale"`)
+		r.Header.Set("If-Modified-Since", modified.Format(http.TimeFormat))
+
+		conditionalTestAdapter(ConditionalSupplied, func(s Response) {
+			s.SetETag("fresh", false).SetLastModified(modified)
+		}).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Header().Get("ETag"), c.ShouldEqual, `"fresh"`)
+	})
+
+	c.Convey("does not evaluate unsafe methods after the handler", t, func() {
+		c.Convey("for PUT requests matching the previous version", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPut, "http://foo.bar", nil)
+			r.Header.Set("If-Match", `"v1"`)
+
+			conditionalTestAdapter(ConditionalSupplied, func(s Response) {
+				s.SetETag("v2", false)
+			}).ServeHTTP(w, r)
+
+			c.So(w.Code, c.ShouldEqual, http.StatusOK)
+			c.So(w.Header().Get("ETag"), c.ShouldEqual, `"v2"`)
+		})
+
+		c.Convey("for POST requests creating the resource", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "http://foo.bar", nil)
+			r.Header.Set("If-None-Match", "*")
+
+			conditionalTestAdapter(ConditionalStrongETag, func(s Response) {
+				s.SetCode(http.StatusCreated)
+			}).ServeHTTP(w, r)
+
+			c.So(w.Code, c.ShouldEqual, http.StatusCreated)
+			c.So(w.Header().Get("ETag"), c.ShouldNotBeEmpty)
+		})
+
+		c.Convey("for DELETE requests", func() {
+			modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodDelete, "http://foo.bar", nil)
+			r.Header.Set("If-Unmodified-Since", modified.Add(-time.Minute).Format(http.TimeFormat))
+
+			conditionalTestAdapter(ConditionalSupplied, func(s Response) {
+				s.SetLastModified(modified)
+			}).ServeHTTP(w, r)
+
+			c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		})
+	})
+
+	c.Convey("ignores conditional headers", t, func() {
+		c.Convey("when disabled", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+			r.Header.Set("If-None-Match", "*")
+
+			conditionalTestAdapter(ConditionalOff, nil).ServeHTTP(w, r)
+
+			c.So(w.Code, c.ShouldEqual, http.StatusOK)
+			c.So(w.Header().Get("ETag"), c.ShouldEqual, "")
+		})
+
+		c.Convey("for unsuccessful responses", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+			r.Header.Set("If-None-Match", "*")
+
+			conditionalTestAdapter(ConditionalStrongETag, func(s Response) {
+				s.SetCode(http.StatusNotFound)
+			}).ServeHTTP(w, r)
+
+			c.So(w.Code, c.ShouldEqual, http.StatusNotFound)
+		})
+	})
+
+	c.Convey("evaluates supplied validators on streamed responses", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("If-None-Match", `"v1"`)
+
+		StreamAdapter("text/plain", DefaultJSONErrorSerializer(), MiddlewareFunc(func(Request) Response {
+			return MakeResponse(http.StatusOK, strings.NewReader("streamed")).SetETag("v1", false)
+		})).Conditional(ConditionalStrongETag).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusNotModified)
+		c.So(w.Body.Len(), c.ShouldEqual, 0)
+	})
+
+	c.Convey("evaluates supplied validators on record streams", t, func() {
+		stream := func(match string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+			r.Header.Set("If-None-Match", match)
+
+			StreamingJSONAdapter(MiddlewareFunc(func(Request) Response {
+				return MakeResponse(http.StatusOK, records(2)).SetETag("v1", false)
+			})).Conditional(ConditionalSupplied).ServeHTTP(w, r)
+			return w
+		}
+
+		w := stream(`"v1"`)
+		c.So(w.Code, c.ShouldEqual, http.StatusNotModified)
+		c.So(w.Body.Len(), c.ShouldEqual, 0)
+		c.So(w.Header().Get("ETag"), c.ShouldEqual, `"v1"`)
+
+		w = stream(`"v2"`)
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, "{\"id\":1}\n{\"id\":2}\n")
+	})
+
+	c.Convey("uses coding specific strong ETags on compressed responses", t, func() {
+		large := strings.Repeat("compress me ", 200)
+
+		c.Convey("suffixing the coding to the tag", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+
+			compressTestAdapter(large, "ETag", `"v1"`).ServeHTTP(w, r)
+
+			c.So(w.Header().Get("Content-Encoding"), c.ShouldEqual, "gzip")
+			c.So(w.Header().Get("ETag"), c.ShouldEqual, `"v1-gzip"`)
+		})
+
+		c.Convey("leaving weak tags unchanged", func() {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+
+			compressTestAdapter(large, "ETag", `W/"v1"`).ServeHTTP(w, r)
+
+			c.So(w.Header().Get("ETag"), c.ShouldEqual, `W/"v1"`)
+		})
+
+		conditional := func(encoding, match string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+			r.Header.Set("Accept-Encoding", encoding)
+			r.Header.Set("If-None-Match", match)
+
+			compressTestAdapter(large, "ETag", `"v1"`).
+				Conditional(ConditionalSupplied).
+				ServeHTTP(w, r)
+			return w
+		}
+
+		c.Convey("matching them in requests negotiating the same coding", func() {
+			w := conditional("gzip", `"v1-gzip"`)
+
+			c.So(w.Code, c.ShouldEqual, http.StatusNotModified)
+			c.So(w.Header().Get("ETag"), c.ShouldEqual, `"v1-gzip"`)
+		})
+
+		c.Convey("not matching them in requests negotiating another coding", func() {
+			c.So(conditional("", `"v1-gzip"`).Code, c.ShouldEqual, http.StatusOK)
+			c.So(conditional("deflate", `"v1-gzip"`).Code, c.ShouldEqual, http.StatusOK)
+			c.So(conditional("", `"v1"`).Code, c.ShouldEqual, http.StatusNotModified)
+		})
+
+		c.Convey("matching them with strong comparison in CheckPreconditions", func() {
+			check := func(encoding string) int {
+				w := httptest.NewRecorder()
+				r := httptest.NewRequest(http.MethodPut, "http://foo.bar", nil)
+				r.Header.Set("Accept-Encoding", encoding)
+				r.Header.Set("If-Match", `"v1-gzip"`)
+
+				JSONAdapter(MiddlewareFunc(func(req Request) Response {
+					if err := CheckPreconditions(req, `"v1"`, time.Time{}); err != nil {
+						return MakeErrorResponse(http.StatusPreconditionFailed, err)
+					}
+					return NewResponse().SetCode(http.StatusNoContent)
+				})).Compression(NewCompression()).ServeHTTP(w, r)
+				return w.Code
+			}
+
+			c.So(check("gzip"), c.ShouldEqual, http.StatusNoContent)
+			c.So(check(""), c.ShouldEqual, http.StatusPreconditionFailed)
+		})
+	})
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	check := func(method string, head map[string]string, etag string, mod time.Time) error {
+		r := httptest.NewRequest(method, "http://foo.bar", nil)
+		for k, v := range head {
+			r.Header.Set(k, v)
+		}
+		req, _ := NewRequest(r)
+		return CheckPreconditions(req, etag, mod)
+	}
+
+	c.Convey("passes matching preconditions", t, func() {
+		c.So(check(http.MethodPut, map[string]string{"If-Match": `"a", "b"`}, `"b"`, modified), c.ShouldBeNil)
+		c.So(check(http.MethodPut, map[string]string{"If-Match": "*"}, `"b"`, time.Time{}), c.ShouldBeNil)
+		c.So(check(http.MethodPut, map[string]string{
+			"If-Unmodified-Since": modified.Format(http.TimeFormat),
+		}, "", modified), c.ShouldBeNil)
+		c.So(check(http.MethodPut, nil, `"b"`, modified), c.ShouldBeNil)
+	})
+
+	c.Convey("does not report 304 outcomes", t, func() {
+		c.So(check(http.MethodGet, map[string]string{"If-None-Match": `"b"`}, `"b"`, modified), c.ShouldBeNil)
+	})
+
+	c.Convey("fails preconditions which do not hold", t, func() {
+		err := check(http.MethodPut, map[string]string{"If-Match": `"a"`}, `"b"`, modified)
+
+		var httpErr *HTTPError
+		c.So(errors.As(err, &httpErr), c.ShouldBeTrue)
+		c.So(httpErr.Status, c.ShouldEqual, http.StatusPreconditionFailed)
+		c.So(errors.Is(err, ErrPreconditionFailed), c.ShouldBeTrue)
+
+		c.So(check(http.MethodPut, map[string]string{
+			"If-Unmodified-Since": modified.Add(-time.Second).Format(http.TimeFormat),
+		}, "", modified), c.ShouldNotBeNil)
+		c.So(check(http.MethodPost, map[string]string{"If-None-Match": "*"}, `"b"`, modified), c.ShouldNotBeNil)
+	})
+
+	c.Convey("uses strong comparison for If-Match", t, func() {
+		c.So(check(http.MethodPut, map[string]string{"If-Match": `W/"b"`}, `W/"b"`, modified), c.ShouldNotBeNil)
+		c.So(check(http.MethodPut, map[string]string{"If-Match": `W/"b"`}, `"b"`, modified), c.ShouldNotBeNil)
+	})
+
+	c.Convey("treats resources without validators as missing", t, func() {
+		c.So(check(http.MethodPut, map[string]string{"If-None-Match": "*"}, "", time.Time{}), c.ShouldBeNil)
+		c.So(check(http.MethodPut, map[string]string{"If-Match": "*"}, "", time.Time{}), c.ShouldNotBeNil)
+	})
+}
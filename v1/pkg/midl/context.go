@@ -0,0 +1,78 @@
+package midl
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// ContextKey is a typed key for storing request scoped
+// values in a Request's context.
+//
+//   var UserKey = midl.NewContextKey[*User]("user")
+//
+//   func (a Auth) Handle(req midl.Request) midl.Response {
+//       UserKey.Set(req, user)
+//       return nil
+//   }
+//
+//   func (c Controller) Handle(req midl.Request) midl.Response {
+//       user, ok := UserKey.Get(req)
+//       ...
+//   }
+//
+// Keys are compared by identity, so two keys created with
+// the same name will not collide.
+type ContextKey[T any] struct {
+	name string
+}
+
+// NewContextKey creates a new ContextKey instance.  The
+// name is used only for debugging output.
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+// Get retrieves the value stored at this key in the given
+// Request's context.
+//
+// Returns false if no value of type T is present.
+func (k *ContextKey[T]) Get(r Request) (T, bool) {
+	return k.From(r.Context())
+}
+
+// From retrieves the value stored at this key in the given
+// context.
+//
+// Returns false if no value of type T is present.
+func (k *ContextKey[T]) From(ctx context.Context) (T, bool) {
+	val, ok := ctx.Value(k).(T)
+	return val, ok
+}
+
+// Set stores the given value at this key in the given
+// Request's context.
+func (k *ContextKey[T]) Set(r Request, val T) {
+	r.WithContext(context.WithValue(r.RawRequest().Context(), k, val))
+}
+
+// String returns a description of the key for debugging.
+func (k *ContextKey[T]) String() string {
+	return fmt.Sprintf("midl.ContextKey[%s](%s)", reflect.TypeOf((*T)(nil)).Elem(), k.name)
+}
+
+// valuesContext overlays a Request's AdditionalContext map
+// on top of the wrapped request's context.
+type valuesContext struct {
+	context.Context
+
+	values map[interface{}]interface{}
+}
+
+func (v *valuesContext) Value(key interface{}) interface{} {
+	if val, ok := v.values[key]; ok {
+		return val
+	}
+
+	return v.Context.Value(key)
+}
@@ -0,0 +1,120 @@
+package midl
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+type testContextKey struct{}
+
+func TestContextKey(t *testing.T) {
+	c.Convey("stores and retrieves typed values", t, func() {
+		key := NewContextKey[int]("count")
+		req, _ := NewRequest(httptest.NewRequest(http.MethodGet, "http://foo.bar", nil))
+
+		_, ok := key.Get(req)
+		c.So(ok, c.ShouldBeFalse)
+
+		key.Set(req, 3)
+		val, ok := key.Get(req)
+		c.So(val, c.ShouldEqual, 3)
+		c.So(ok, c.ShouldBeTrue)
+
+		val, ok = key.From(req.RawRequest().Context())
+		c.So(val, c.ShouldEqual, 3)
+		c.So(ok, c.ShouldBeTrue)
+	})
+
+	c.Convey("keys with the same name do not collide", t, func() {
+		key1 := NewContextKey[string]("name")
+		key2 := NewContextKey[string]("name")
+		req, _ := NewRequest(httptest.NewRequest(http.MethodGet, "http://foo.bar", nil))
+
+		key1.Set(req, "one")
+		_, ok := key2.Get(req)
+		c.So(ok, c.ShouldBeFalse)
+	})
+
+	c.Convey("describes itself", t, func() {
+		c.So(NewContextKey[*http.Request]("raw").String(), c.ShouldEqual,
+			"midl.ContextKey[*http.Request](raw)")
+	})
+}
+
+func TestRequest_Context(t *testing.T) {
+	c.Convey("exposes values from the wrapped request", t, func() {
+		raw := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		raw = raw.WithContext(context.WithValue(raw.Context(), testContextKey{}, "raw"))
+		req, _ := NewRequest(raw)
+
+		c.So(req.Context().Value(testContextKey{}), c.ShouldEqual, "raw")
+	})
+
+	c.Convey("exposes additional context values", t, func() {
+		req, _ := NewRequest(httptest.NewRequest(http.MethodGet, "http://foo.bar", nil))
+		req.AdditionalContext()[testContextKey{}] = "additional"
+
+		c.So(req.Context().Value(testContextKey{}), c.ShouldEqual, "additional")
+	})
+
+	c.Convey("carries cancellation from the wrapped request", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		raw := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil).WithContext(ctx)
+		req, _ := NewRequest(raw)
+
+		cancel()
+		c.So(req.Context().Err(), c.ShouldEqual, context.Canceled)
+	})
+
+	c.Convey("works on zero value requests", t, func() {
+		test := request{raw: httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)}
+		c.So(test.Context().Value(testContextKey{}), c.ShouldBeNil)
+	})
+}
+
+func TestRequest_WithContext(t *testing.T) {
+	c.Convey("replaces the wrapped request context", t, func() {
+		req, _ := NewRequest(httptest.NewRequest(http.MethodGet, "http://foo.bar", nil))
+		ctx := context.WithValue(context.Background(), testContextKey{}, "new")
+
+		c.So(req.WithContext(ctx), c.ShouldEqual, req)
+		c.So(req.RawRequest().Context(), c.ShouldEqual, ctx)
+		c.So(req.Context().Value(testContextKey{}), c.ShouldEqual, "new")
+	})
+
+	c.Convey("does not stack the additional context overlay", t, func() {
+		ctx := context.WithValue(context.Background(), testContextKey{}, "raw")
+		raw := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil).WithContext(ctx)
+		req, _ := NewRequest(raw)
+
+		req.WithContext(req.Context())
+		c.So(req.RawRequest().Context(), c.ShouldEqual, ctx)
+	})
+}
+
+func TestAdapter_ServeHTTP_context(t *testing.T) {
+	c.Convey("skips remaining handlers once the context is done", t, func() {
+		var count int
+		ctx, cancel := context.WithCancel(context.Background())
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil).WithContext(ctx)
+
+		adapter{
+			errSerializer: ErrorSerializerFunc(func(err error, _ Request, _ Response) []byte {
+				return []byte(err.Error())
+			}),
+			handlers: []Middleware{
+				MiddlewareFunc(func(Request) Response { count++; cancel(); return nil }),
+				MiddlewareFunc(func(Request) Response { count++; return NewResponse() }),
+			},
+		}.ServeHTTP(w, r)
+
+		c.So(count, c.ShouldEqual, 1)
+		c.So(w.Code, c.ShouldEqual, http.StatusServiceUnavailable)
+		c.So(w.Body.String(), c.ShouldEqual, context.Canceled.Error())
+	})
+}
@@ -0,0 +1,178 @@
+package midl
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	textType     = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setValues converts the given string values into the type
+// of the given settable value and stores the result.
+//
+// Pointer values are allocated, slices (other than []byte)
+// receive one element per input value, and all other types
+// are set from the first input value.  Time values are
+// parsed with the given layout.
+func setValues(dst reflect.Value, values []string, layout string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	switch {
+	case dst.Kind() == reflect.Ptr:
+		val := reflect.New(dst.Type().Elem())
+		if err := setValues(val.Elem(), values, layout); err != nil {
+			return err
+		}
+		dst.Set(val)
+		return nil
+	case dst.Kind() == reflect.Slice && dst.Type().Elem().Kind() != reflect.Uint8:
+		out := reflect.MakeSlice(dst.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(out.Index(i), value, layout); err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+		return nil
+	}
+
+	return setValue(dst, values[0], layout)
+}
+
+// bindable returns whether setValues can store values into
+// a value of the given type.
+func bindable(typ reflect.Type) bool {
+	switch {
+	case typ.Kind() == reflect.Ptr:
+		return bindable(typ.Elem())
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8:
+		return bindableValue(typ.Elem())
+	}
+
+	return bindableValue(typ)
+}
+
+// bindableValue returns whether setValue can store a value
+// into a value of the given type.
+func bindableValue(typ reflect.Type) bool {
+	if typ == timeType || typ == durationType || reflect.PointerTo(typ).Implements(textType) {
+		return true
+	}
+
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return typ.Elem().Kind() == reflect.Uint8
+	}
+
+	return false
+}
+
+// setValue converts a single string into the type of the
+// given settable value and stores the result.
+func setValue(dst reflect.Value, value, layout string) error {
+	switch dst.Type() {
+	case timeType:
+		val, err := parseTime(value, layout)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(val))
+		return nil
+	case durationType:
+		val, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(int64(val))
+		return nil
+	}
+
+	if dst.CanAddr() && dst.Addr().Type().Implements(textType) {
+		return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(value)
+	case reflect.Slice:
+		dst.SetBytes([]byte(value))
+	case reflect.Bool:
+		val, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(val)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		val, err := strconv.ParseInt(value, 10, dst.Type().Bits())
+		if err != nil {
+			return numError(err, "an integer")
+		}
+		dst.SetInt(val)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		val, err := strconv.ParseUint(value, 10, dst.Type().Bits())
+		if err != nil {
+			return numError(err, "a non-negative integer")
+		}
+		dst.SetUint(val)
+	case reflect.Float32, reflect.Float64:
+		val, err := strconv.ParseFloat(value, dst.Type().Bits())
+		if err != nil {
+			return numError(err, "a number")
+		}
+		dst.SetFloat(val)
+	default:
+		return fmt.Errorf("unsupported type %s", dst.Type())
+	}
+
+	return nil
+}
+
+func parseBool(value string) (bool, error) {
+	val, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("must be a boolean")
+	}
+	return val, nil
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	val, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.New("must be a duration such as 1h30m")
+	}
+	return val, nil
+}
+
+func parseTime(value, layout string) (time.Time, error) {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+
+	val, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be a time in the format %s", layout)
+	}
+	return val, nil
+}
+
+func numError(err error, kind string) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return errors.New("is out of range")
+	}
+	return errors.New("must be " + kind)
+}
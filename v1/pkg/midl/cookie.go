@@ -0,0 +1,175 @@
+package midl
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// CookieCodec defines a service which protects cookie
+// values kept on the client.
+//
+// Values are bound to the cookie name they were encoded for,
+// so a value cannot be replayed under a different name.
+type CookieCodec interface {
+
+	// Encode protects the given value of the named cookie.
+	Encode(name, value string) (string, error)
+
+	// Decode verifies and returns the original value of the
+	// named cookie.  Returns ErrInvalidCookie if the value was
+	// not produced by this codec for the given name.
+	Decode(name, value string) (string, error)
+}
+
+// EncodeCookie returns a copy of the given cookie with its
+// value encoded by the given codec, ready to be passed to
+// Response.SetCookie.
+func EncodeCookie(codec CookieCodec, cookie *http.Cookie) (*http.Cookie, error) {
+	val, err := codec.Encode(cookie.Name, cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	out := *cookie
+	out.Value = val
+	return &out, nil
+}
+
+// DecodeCookie returns the value of the given cookie decoded
+// by the given codec, typically for a cookie returned by
+// Request.Cookie.
+func DecodeCookie(codec CookieCodec, cookie *http.Cookie) (string, error) {
+	return codec.Decode(cookie.Name, cookie.Value)
+}
+
+// SignedCookies returns a CookieCodec which signs values with
+// HMAC-SHA256.  Signed values remain readable by the client
+// but cannot be altered.
+//
+// Values are signed with the first key, and verified against
+// every key, allowing keys to be rotated by prepending the
+// new key and removing old keys once cookies signed with them
+// have expired.
+//
+// Panics if no keys are given.
+func SignedCookies(keys ...[]byte) CookieCodec {
+	if len(keys) == 0 {
+		panic("midl: SignedCookies requires at least one key")
+	}
+
+	return signedCookies{keys: keys}
+}
+
+type signedCookies struct {
+	keys [][]byte
+}
+
+func (s signedCookies) Encode(name, value string) (string, error) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.sign(s.keys[0], name, payload)), nil
+}
+
+func (s signedCookies) Decode(name, value string) (string, error) {
+	payload, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+
+	for _, key := range s.keys {
+		if hmac.Equal(mac, s.sign(key, name, payload)) {
+			out, err := base64.RawURLEncoding.DecodeString(payload)
+			if err != nil {
+				return "", ErrInvalidCookie
+			}
+			return string(out), nil
+		}
+	}
+
+	return "", ErrInvalidCookie
+}
+
+func (signedCookies) sign(key []byte, name, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// EncryptedCookies returns a CookieCodec which encrypts and
+// authenticates values with AES-GCM, hiding them from the
+// client.
+//
+// Each key must be 16, 24 or 32 bytes long, selecting
+// AES-128, AES-192 or AES-256.  Values are encrypted with
+// the first key and decrypted with whichever key succeeds,
+// allowing keys to be rotated as with SignedCookies.
+//
+// Returns an error if no keys are given or a key is invalid.
+func EncryptedCookies(keys ...[]byte) (CookieCodec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("midl: EncryptedCookies requires at least one key")
+	}
+
+	out := encryptedCookies{aeads: make([]cipher.AEAD, len(keys))}
+	for i, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("midl: invalid cookie key %d: %w", i, err)
+		}
+
+		if out.aeads[i], err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+type encryptedCookies struct {
+	aeads []cipher.AEAD
+}
+
+func (e encryptedCookies) Encode(name, value string) (string, error) {
+	aead := e.aeads[0]
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(value), []byte(name))), nil
+}
+
+func (e encryptedCookies) Decode(name, value string) (string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+
+	for _, aead := range e.aeads {
+		if len(raw) < aead.NonceSize() {
+			continue
+		}
+
+		nonce, sealed := raw[:aead.NonceSize()], raw[aead.NonceSize():]
+		if out, err := aead.Open(nil, nonce, sealed, []byte(name)); err == nil {
+			return string(out), nil
+		}
+	}
+
+	return "", ErrInvalidCookie
+}
@@ -0,0 +1,132 @@
+package midl
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequest_Cookie(t *testing.T) {
+	c.Convey("reads request cookies", t, func() {
+		raw := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		raw.AddCookie(&http.Cookie{Name: "a", Value: "1"})
+		raw.AddCookie(&http.Cookie{Name: "b", Value: "2"})
+		req, _ := NewRequest(raw)
+
+		cookie, ok := req.Cookie("b")
+		c.So(ok, c.ShouldBeTrue)
+		c.So(cookie.Value, c.ShouldEqual, "2")
+
+		_, ok = req.Cookie("c")
+		c.So(ok, c.ShouldBeFalse)
+		c.So(req.Cookies(), c.ShouldHaveLength, 2)
+	})
+}
+
+func TestResponse_SetCookie(t *testing.T) {
+	c.Convey("adds Set-Cookie headers", t, func() {
+		res := NewResponse().
+			SetCookie(&http.Cookie{Name: "a", Value: "1", Path: "/", HttpOnly: true}).
+			SetCookie(&http.Cookie{Name: "b", Value: "2"}).
+			SetCookie(&http.Cookie{Name: "bad name", Value: "3"})
+
+		c.So(res.Headers("Set-Cookie"), c.ShouldResemble, []string{"a=1; Path=/; HttpOnly", "b=2"})
+	})
+
+	c.Convey("clears cookies", t, func() {
+		res := NewResponse().ClearCookie(&http.Cookie{Name: "a", Value: "1", Path: "/x", Domain: "foo.bar"})
+
+		header := res.Header("Set-Cookie")
+		c.So(header, c.ShouldStartWith, "a=; Path=/x; Domain=foo.bar; Expires=Thu, 01 Jan 1970 00:00:00 GMT")
+		c.So(header, c.ShouldContainSubstring, "Max-Age=0")
+	})
+
+	c.Convey("writes cookies through the adapter", t, func() {
+		rec := httptest.NewRecorder()
+		JSONAdapter(MiddlewareFunc(func(Request) Response {
+			return MakeResponse(http.StatusOK, "ok").
+				SetCookie(&http.Cookie{Name: "a", Value: "1"}).
+				SetCookie(&http.Cookie{Name: "b", Value: "2"})
+		})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://foo.bar", nil))
+
+		c.So(rec.Result().Cookies(), c.ShouldHaveLength, 2)
+	})
+}
+
+func TestCookieCodecs(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 32)
+
+	encrypted, err := EncryptedCookies(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, _ := EncryptedCookies(newKey, oldKey)
+
+	codecs := map[string][2]CookieCodec{
+		"signed":    {SignedCookies(oldKey), SignedCookies(newKey, oldKey)},
+		"encrypted": {encrypted, rotated},
+	}
+
+	for name, pair := range codecs {
+		codec, next := pair[0], pair[1]
+
+		c.Convey(name+" cookies round trip", t, func() {
+			enc, err := EncodeCookie(codec, &http.Cookie{Name: "session", Value: "user=1; role=admin", Path: "/"})
+			c.So(err, c.ShouldBeNil)
+			c.So(enc.Path, c.ShouldEqual, "/")
+			c.So(enc.String(), c.ShouldStartWith, "session=")
+			c.So(enc.Value, c.ShouldNotContainSubstring, ";")
+
+			val, err := DecodeCookie(codec, enc)
+			c.So(err, c.ShouldBeNil)
+			c.So(val, c.ShouldEqual, "user=1; role=admin")
+		})
+
+		c.Convey(name+" cookies reject tampering", t, func() {
+			enc, _ := codec.Encode("session", "value")
+
+			_, err := codec.Decode("other", enc)
+			c.So(err, c.ShouldEqual, ErrInvalidCookie)
+
+			flipped := "A"
+			if enc[0] == 'A' {
+				flipped = "B"
+			}
+			_, err = codec.Decode("session", flipped+enc[1:])
+			c.So(err, c.ShouldEqual, ErrInvalidCookie)
+
+			_, err = codec.Decode("session", "garbage")
+			c.So(err, c.ShouldEqual, ErrInvalidCookie)
+		})
+
+		c.Convey(name+" cookies support key rotation", t, func() {
+			old, _ := codec.Encode("session", "value")
+			val, err := next.Decode("session", old)
+			c.So(err, c.ShouldBeNil)
+			c.So(val, c.ShouldEqual, "value")
+
+			fresh, _ := next.Encode("session", "value")
+			_, err = codec.Decode("session", fresh)
+			c.So(err, c.ShouldEqual, ErrInvalidCookie)
+		})
+	}
+
+	c.Convey("encrypted cookies hide values", t, func() {
+		enc, _ := encrypted.Encode("session", "secret")
+
+		c.So(strings.Contains(enc, "secret"), c.ShouldBeFalse)
+	})
+
+	c.Convey("encrypted cookies reject invalid keys", t, func() {
+		_, err := EncryptedCookies([]byte("short"))
+		c.So(err, c.ShouldBeError)
+
+		_, err = EncryptedCookies()
+		c.So(err, c.ShouldBeError)
+	})
+}
@@ -0,0 +1,225 @@
+package midl
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCORSMethods are the methods allowed by a CORS when
+// none are configured.
+var DefaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+}
+
+// CORS configures Cross-Origin Resource Sharing for an
+// Adapter.
+//
+//   handler := JSONAdapter(NewController()).
+//       CORS(NewCORS("https://app.example.com", "https://*.example.com").
+//           AllowMethods(http.MethodGet, http.MethodPut).
+//           AllowCredentials(true))
+//
+// Preflight requests, which are OPTIONS requests with an
+// Access-Control-Request-Method header, are answered with a
+// 204 (No Content) before any RequestWrapper or Middleware
+// is called.  All other requests are handled as usual, with
+// the Access-Control-* headers set on every response,
+// including error responses written by the ErrorSerializer.
+//
+// Requests from origins which are not allowed receive no
+// Access-Control-* headers, leaving the browser to block
+// them.
+//
+// A CORS must not be modified once in use.
+type CORS struct {
+	any         bool
+	origins     map[string]bool
+	wildcards   []corsWildcard
+	predicate   func(origin string) bool
+	methods     []string
+	headers     []string
+	expose      []string
+	credentials bool
+	maxAge      time.Duration
+}
+
+// corsWildcard matches origins on any subdomain of a
+// domain.
+type corsWildcard struct {
+	scheme string
+	domain string
+}
+
+func (w corsWildcard) matches(origin string) bool {
+	return len(origin) > len(w.scheme)+len(w.domain) &&
+		strings.HasPrefix(origin, w.scheme) &&
+		strings.HasSuffix(origin, w.domain)
+}
+
+// NewCORS creates a new CORS allowing the given origins.
+//
+// Origins are given as a scheme, host and optional port,
+// such as "https://example.com:8443".  The host may start
+// with a "*." wildcard, such as "https://*.example.com",
+// to allow any subdomain of the given domain, but not the
+// domain itself.  The origin "*" allows any origin.
+func NewCORS(origins ...string) *CORS {
+	out := &CORS{origins: map[string]bool{}}
+
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+
+		switch {
+		case origin == "*":
+			out.any = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://*.")
+			out.wildcards = append(out.wildcards, corsWildcard{scheme + "://", "." + host})
+		case origin != "":
+			out.origins[origin] = true
+		}
+	}
+
+	return out
+}
+
+// AllowOriginFunc sets a function called for origins which
+// are not allowed by the origins given to NewCORS, which
+// returns whether the origin is allowed.
+func (c *CORS) AllowOriginFunc(fn func(origin string) bool) *CORS {
+	c.predicate = fn
+	return c
+}
+
+// AllowMethods sets the methods cross-origin requests may
+// use, defaulting to DefaultCORSMethods.
+func (c *CORS) AllowMethods(methods ...string) *CORS {
+	c.methods = methods
+	return c
+}
+
+// AllowHeaders sets the request headers cross-origin
+// requests may send.  By default the headers requested by a
+// preflight request are allowed.
+func (c *CORS) AllowHeaders(headers ...string) *CORS {
+	c.headers = headers
+	return c
+}
+
+// ExposeHeaders sets the response headers which scripts
+// making cross-origin requests may read.
+func (c *CORS) ExposeHeaders(headers ...string) *CORS {
+	c.expose = headers
+	return c
+}
+
+// AllowCredentials sets whether cross-origin requests may
+// include credentials such as cookies.
+//
+// When enabled, allowed origins are echoed back instead of
+// "*" as browsers require.
+func (c *CORS) AllowCredentials(allow bool) *CORS {
+	c.credentials = allow
+	return c
+}
+
+// MaxAge sets how long browsers may cache preflight
+// responses.  A value of zero or less leaves it to the
+// browser, which is the default.
+func (c *CORS) MaxAge(age time.Duration) *CORS {
+	c.maxAge = age
+	return c
+}
+
+// allowed returns whether the given origin is allowed.
+func (c *CORS) allowed(origin string) bool {
+	if c.any {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+	if c.origins[lower] {
+		return true
+	}
+
+	for _, wild := range c.wildcards {
+		if wild.matches(lower) {
+			return true
+		}
+	}
+
+	return c.predicate != nil && c.predicate(origin)
+}
+
+// handle sets the CORS headers for the given request on the
+// given response writer, answering preflight requests.
+// Returns whether the request has been answered.
+//
+// A nil CORS does nothing.
+func (c *CORS) handle(w writer, r *http.Request) bool {
+	if c == nil || r == nil {
+		return false
+	}
+
+	head := w.Header()
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	wildcard := c.any && !c.credentials
+
+	if !wildcard {
+		head.Add("Vary", "Origin")
+	}
+
+	if preflight {
+		head.Add("Vary", "Access-Control-Request-Method")
+		head.Add("Vary", "Access-Control-Request-Headers")
+	}
+
+	if origin != "" && c.allowed(origin) {
+		if wildcard {
+			head.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			head.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if c.credentials {
+			head.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.preflightHeaders(head, r)
+		} else if len(c.expose) > 0 {
+			head.Set("Access-Control-Expose-Headers", strings.Join(c.expose, ", "))
+		}
+	}
+
+	if preflight {
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	return preflight
+}
+
+func (c *CORS) preflightHeaders(head http.Header, r *http.Request) {
+	methods := c.methods
+	if methods == nil {
+		methods = DefaultCORSMethods
+	}
+	head.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+	if c.headers != nil {
+		if len(c.headers) > 0 {
+			head.Set("Access-Control-Allow-Headers", strings.Join(c.headers, ", "))
+		}
+	} else if req := r.Header.Values("Access-Control-Request-Headers"); len(req) > 0 {
+		head.Set("Access-Control-Allow-Headers", strings.Join(req, ", "))
+	}
+
+	if c.maxAge > 0 {
+		head.Set("Access-Control-Max-Age", strconv.Itoa(int(c.maxAge/time.Second)))
+	}
+}
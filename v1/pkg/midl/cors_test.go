@@ -0,0 +1,162 @@
+package midl
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func corsTestRequest(h http.Handler, method, origin string, head ...string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, "http://api.example.com/a", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	for i := 0; i < len(head); i += 2 {
+		r.Header.Add(head[i], head[i+1])
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestCORS(t *testing.T) {
+	c.Convey("matches origins", t, func() {
+		cors := NewCORS("https://app.example.com", "https://*.example.org").
+			AllowOriginFunc(func(origin string) bool { return strings.HasSuffix(origin, ".test") })
+
+		c.So(cors.allowed("https://app.example.com"), c.ShouldBeTrue)
+		c.So(cors.allowed("HTTPS://APP.EXAMPLE.COM"), c.ShouldBeTrue)
+		c.So(cors.allowed("http://app.example.com"), c.ShouldBeFalse)
+		c.So(cors.allowed("https://a.b.example.org"), c.ShouldBeTrue)
+		c.So(cors.allowed("https://example.org"), c.ShouldBeFalse)
+		c.So(cors.allowed("https://evilexample.org"), c.ShouldBeFalse)
+		c.So(cors.allowed("http://a.example.org"), c.ShouldBeFalse)
+		c.So(cors.allowed("http://local.test"), c.ShouldBeTrue)
+		c.So(cors.allowed("https://other.com"), c.ShouldBeFalse)
+		c.So(NewCORS("*").allowed("https://other.com"), c.ShouldBeTrue)
+	})
+
+	c.Convey("answers preflight requests without calling middleware", t, func() {
+		var called bool
+		h := JSONAdapter(MiddlewareFunc(func(Request) Response {
+			called = true
+			return nil
+		})).CORS(NewCORS("https://app.example.com").
+			AllowMethods(http.MethodGet, http.MethodPut).
+			MaxAge(10 * time.Minute))
+
+		w := corsTestRequest(h, http.MethodOptions, "https://app.example.com",
+			"Access-Control-Request-Method", "PUT",
+			"Access-Control-Request-Headers", "X-Token, Content-Type")
+
+		c.So(called, c.ShouldBeFalse)
+		c.So(w.Code, c.ShouldEqual, http.StatusNoContent)
+		c.So(w.Body.Len(), c.ShouldEqual, 0)
+		c.So(w.Header().Get("Access-Control-Allow-Origin"), c.ShouldEqual, "https://app.example.com")
+		c.So(w.Header().Get("Access-Control-Allow-Methods"), c.ShouldEqual, "GET, PUT")
+		c.So(w.Header().Get("Access-Control-Allow-Headers"), c.ShouldEqual, "X-Token, Content-Type")
+		c.So(w.Header().Get("Access-Control-Max-Age"), c.ShouldEqual, "600")
+		c.So(w.Header().Values("Vary"), c.ShouldResemble, []string{
+			"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers",
+		})
+	})
+
+	c.Convey("uses configured preflight headers", t, func() {
+		h := JSONAdapter().CORS(NewCORS("https://app.example.com").AllowHeaders("Authorization"))
+
+		w := corsTestRequest(h, http.MethodOptions, "https://app.example.com",
+			"Access-Control-Request-Method", "POST",
+			"Access-Control-Request-Headers", "X-Other")
+
+		c.So(w.Header().Get("Access-Control-Allow-Headers"), c.ShouldEqual, "Authorization")
+		c.So(w.Header().Get("Access-Control-Allow-Methods"), c.ShouldEqual, "GET, HEAD, POST")
+	})
+
+	c.Convey("answers preflights from other origins without CORS headers", t, func() {
+		h := JSONAdapter().CORS(NewCORS("https://app.example.com"))
+
+		w := corsTestRequest(h, http.MethodOptions, "https://evil.com", "Access-Control-Request-Method", "GET")
+
+		c.So(w.Code, c.ShouldEqual, http.StatusNoContent)
+		c.So(w.Header().Get("Access-Control-Allow-Origin"), c.ShouldEqual, "")
+		c.So(w.Header().Get("Access-Control-Allow-Methods"), c.ShouldEqual, "")
+	})
+
+	c.Convey("passes plain OPTIONS requests to middleware", t, func() {
+		h := JSONAdapter(MiddlewareFunc(func(Request) Response {
+			return NewResponse().SetCode(http.StatusAccepted)
+		})).CORS(NewCORS("*"))
+
+		w := corsTestRequest(h, http.MethodOptions, "https://app.example.com")
+
+		c.So(w.Code, c.ShouldEqual, http.StatusAccepted)
+	})
+
+	c.Convey("sets headers on regular and error responses", t, func() {
+		cors := NewCORS("https://app.example.com").ExposeHeaders("X-Total").AllowCredentials(true)
+
+		ok := corsTestRequest(JSONAdapter(MiddlewareFunc(func(Request) Response {
+			return MakeResponse(http.StatusOK, "ok").AddHeader("Vary", "Accept-Language")
+		})).CORS(cors), http.MethodGet, "https://app.example.com")
+
+		c.So(ok.Code, c.ShouldEqual, http.StatusOK)
+		c.So(ok.Header().Get("Access-Control-Allow-Origin"), c.ShouldEqual, "https://app.example.com")
+		c.So(ok.Header().Get("Access-Control-Allow-Credentials"), c.ShouldEqual, "true")
+		c.So(ok.Header().Get("Access-Control-Expose-Headers"), c.ShouldEqual, "X-Total")
+		c.So(ok.Header().Values("Vary"), c.ShouldResemble, []string{"Origin", "Accept-Language"})
+
+		failed := corsTestRequest(JSONAdapter(MiddlewareFunc(func(Request) Response {
+			return MakeErrorResponse(http.StatusBadRequest, errors.New("bad"))
+		})).CORS(cors), http.MethodGet, "https://app.example.com")
+
+		c.So(failed.Code, c.ShouldEqual, http.StatusBadRequest)
+		c.So(failed.Header().Get("Access-Control-Allow-Origin"), c.ShouldEqual, "https://app.example.com")
+
+		tooLarge := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://api.example.com/a", strings.NewReader("too large"))
+		r.Header.Set("Origin", "https://app.example.com")
+		JSONAdapter().MaxBodySize(1).CORS(cors).ServeHTTP(tooLarge, r)
+
+		c.So(tooLarge.Code, c.ShouldEqual, http.StatusRequestEntityTooLarge)
+		c.So(tooLarge.Header().Get("Access-Control-Allow-Origin"), c.ShouldEqual, "https://app.example.com")
+	})
+
+	c.Convey("uses a wildcard origin for any origin without credentials", t, func() {
+		w := corsTestRequest(JSONAdapter().CORS(NewCORS("*")), http.MethodGet, "https://app.example.com")
+
+		c.So(w.Header().Get("Access-Control-Allow-Origin"), c.ShouldEqual, "*")
+		c.So(w.Header().Get("Vary"), c.ShouldEqual, "")
+
+		w = corsTestRequest(JSONAdapter().CORS(NewCORS("*").AllowCredentials(true)), http.MethodGet, "https://app.example.com")
+
+		c.So(w.Header().Get("Access-Control-Allow-Origin"), c.ShouldEqual, "https://app.example.com")
+		c.So(w.Header().Get("Vary"), c.ShouldEqual, "Origin")
+	})
+
+	c.Convey("applies once to negotiating adapters", t, func() {
+		h := JSONXMLAdapter(MiddlewareFunc(func(Request) Response {
+			return MakeResponse(http.StatusOK, "ok")
+		})).CORS(NewCORS("https://app.example.com"))
+
+		w := corsTestRequest(h, http.MethodGet, "https://app.example.com", "Accept", "application/xml")
+
+		c.So(w.Header().Values("Access-Control-Allow-Origin"), c.ShouldResemble, []string{"https://app.example.com"})
+		c.So(w.Header().Values("Vary"), c.ShouldResemble, []string{"Origin", "Accept"})
+	})
+
+	c.Convey("answers preflights for routes through the router's adapter", t, func() {
+		cors := NewCORS("https://app.example.com")
+		router := NewRouter(func(h ...Middleware) Adapter { return JSONAdapter(h...).CORS(cors) }).
+			Route(http.MethodPut, "/a", MiddlewareFunc(func(Request) Response { return nil }))
+
+		w := corsTestRequest(router, http.MethodOptions, "https://app.example.com", "Access-Control-Request-Method", "PUT")
+
+		c.So(w.Code, c.ShouldEqual, http.StatusNoContent)
+		c.So(w.Header().Get("Access-Control-Allow-Origin"), c.ShouldEqual, "https://app.example.com")
+	})
+}
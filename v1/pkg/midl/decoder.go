@@ -0,0 +1,66 @@
+package midl
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"strings"
+)
+
+// Decoder defines a service which can be used to
+// deserialize a request body into a target value.
+type Decoder interface {
+
+	// Decode is used to deserialize the given request body
+	// bytes into the value pointed to by the second
+	// parameter.
+	Decode([]byte, interface{}) error
+}
+
+// DecoderFunc defines a convenience wrapper for using a
+// function as a Decoder implementation.
+//
+//   dec := DecoderFunc(json.Unmarshal)
+type DecoderFunc func([]byte, interface{}) error
+
+// Decode is a simple passthrough to the wrapped function.
+func (d DecoderFunc) Decode(in []byte, out interface{}) error {
+	return d(in, out)
+}
+
+// DefaultDecoders returns a new map of media types to
+// Decoders containing decoders for JSON and XML request
+// bodies.
+func DefaultDecoders() map[string]Decoder {
+	return map[string]Decoder{
+		"application/json": DecoderFunc(json.Unmarshal),
+		"application/xml":  DecoderFunc(xml.Unmarshal),
+		"text/xml":         DecoderFunc(xml.Unmarshal),
+	}
+}
+
+// findDecoder looks up the Decoder for the given
+// Content-Type header value.
+//
+// Structured syntax suffixes are honored, so a type such as
+// "application/problem+json" will fall back to the decoder
+// registered for "application/json".
+func findDecoder(decoders map[string]Decoder, contentType string) (Decoder, bool) {
+	kind, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	if dec, ok := decoders[kind]; ok {
+		return dec, true
+	}
+
+	slash := strings.IndexByte(kind, '/')
+	plus := strings.LastIndexByte(kind, '+')
+	if slash < 0 || plus < slash {
+		return nil, false
+	}
+
+	dec, ok := decoders[kind[:slash+1]+kind[plus+1:]]
+	return dec, ok
+}
@@ -0,0 +1,247 @@
+package midl
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxDecompressedSize is the maximum number of bytes
+// a compressed request body may decompress to when no other
+// limit is configured.
+const DefaultMaxDecompressedSize int64 = 32 << 20
+
+// ContentDecoder defines a service which decompresses
+// request bodies of a single content coding, such as gzip.
+type ContentDecoder interface {
+
+	// NewReader returns a reader decompressing the given
+	// compressed body.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// ContentDecoderFunc provides a function wrapper for simple
+// ContentDecoders.
+type ContentDecoderFunc func(r io.Reader) (io.ReadCloser, error)
+
+// NewReader calls the wrapped decoder function.
+func (c ContentDecoderFunc) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return c(r)
+}
+
+// Decompression configures how compressed request bodies,
+// identified by their Content-Encoding header, are decoded
+// before being read through Request.Body, Request.BodyReader
+// or any method built on them.
+//
+//   handler := JSONAdapter(NewController()).
+//       Decompression(NewDecompression().MaxSize(8 << 20))
+//
+// Requests with a Content-Encoding which has no registered
+// ContentDecoder are rejected with a 415 (Unsupported Media
+// Type) through the ErrorSerializer before any Middleware
+// is called; the response lists the supported codings in an
+// Accept-Encoding header.
+//
+// Bodies which decompress past the maximum size fail with an
+// *http.MaxBytesError, which resolves to a 413, and corrupt
+// bodies fail with a 400 HTTPError.
+//
+// A Decompression must not be modified once in use.
+type Decompression struct {
+	decoders map[string]ContentDecoder
+	maxSize  int64
+}
+
+// NewDecompression creates a new Decompression which decodes
+// gzip and deflate bodies of up to
+// DefaultMaxDecompressedSize bytes.
+func NewDecompression() *Decompression {
+	gz := ContentDecoderFunc(func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+
+	return (&Decompression{maxSize: DefaultMaxDecompressedSize}).
+		Register("gzip", gz).
+		Register("x-gzip", gz).
+		Register("deflate", ContentDecoderFunc(newDeflateReader))
+}
+
+var defaultDecompression = NewDecompression()
+
+// Register adds a decoder for the given content coding,
+// replacing any decoder registered for the same coding.
+func (d *Decompression) Register(encoding string, dec ContentDecoder) *Decompression {
+	if d.decoders == nil {
+		d.decoders = map[string]ContentDecoder{}
+	}
+
+	d.decoders[strings.ToLower(encoding)] = dec
+	return d
+}
+
+// MaxSize sets the maximum number of bytes a body may
+// decompress to.  A value of zero or less disables the
+// limit.
+func (d *Decompression) MaxSize(max int64) *Decompression {
+	d.maxSize = max
+	return d
+}
+
+// encodings returns the supported content codings, sorted.
+func (d *Decompression) encodings() []string {
+	out := make([]string, 0, len(d.decoders))
+	for name := range d.decoders {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// newDeflateReader decodes the deflate content coding,
+// which is the zlib format, while tolerating the raw deflate
+// streams sent by some clients.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	buf := bufio.NewReader(r)
+
+	head, err := buf.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if len(head) == 2 && head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+		return zlib.NewReader(buf)
+	}
+
+	return flate.NewReader(buf), nil
+}
+
+// decodeBody replaces the body of the given request with one
+// decoding its Content-Encoding, removing the header.  A nil
+// Decompression uses the default settings.  Requests without
+// a body are left alone.
+//
+// Returns an error if an encoding is not supported.
+func decodeBody(r *http.Request, d *Decompression) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	if d == nil {
+		d = defaultDecompression
+	}
+
+	var codings []string
+	for _, value := range r.Header.Values("Content-Encoding") {
+		for _, part := range strings.Split(value, ",") {
+			name := strings.ToLower(strings.TrimSpace(part))
+			if name == "" || name == "identity" {
+				continue
+			}
+
+			if _, ok := d.decoders[name]; !ok {
+				return fmt.Errorf("%w: %q", ErrUnsupportedEncoding, name)
+			}
+			codings = append(codings, name)
+		}
+	}
+
+	r.Header.Del("Content-Encoding")
+	if len(codings) == 0 {
+		return nil
+	}
+
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	r.Body = &decodingReader{src: r.Body, codings: codings, dec: d}
+
+	return nil
+}
+
+// unsupportedEncoding returns the 415 error response for a
+// request with an unsupported Content-Encoding.
+func unsupportedEncoding(d *Decompression, err error) Response {
+	if d == nil {
+		d = defaultDecompression
+	}
+
+	return MakeErrorResponse(http.StatusUnsupportedMediaType, err).
+		SetHeader("Accept-Encoding", strings.Join(d.encodings(), ", "))
+}
+
+// decodingReader decompresses a request body, setting up the
+// decoders on first read so that malformed bodies fail when
+// read rather than before any Middleware is called.
+type decodingReader struct {
+	src     io.ReadCloser
+	codings []string
+	dec     *Decompression
+
+	read    io.Reader
+	closers []io.Closer
+	err     error
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	if d.read == nil && d.err == nil {
+		d.err = d.open()
+	}
+
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	n, err := d.read.Read(p)
+	if err != nil && err != io.EOF {
+		err = decodeError(err)
+		d.err = err
+	}
+	return n, err
+}
+
+// open applies the decoders in the reverse of the order the
+// codings were applied.
+func (d *decodingReader) open() error {
+	var read io.Reader = d.src
+
+	for i := len(d.codings) - 1; i >= 0; i-- {
+		next, err := d.dec.decoders[d.codings[i]].NewReader(read)
+		if err != nil {
+			return decodeError(err)
+		}
+		d.closers = append(d.closers, next)
+		read = next
+	}
+
+	if max := d.dec.maxSize; max > 0 {
+		read = &limitedReader{r: read, n: max, limit: max}
+	}
+
+	d.read = read
+	return nil
+}
+
+func (d *decodingReader) Close() error {
+	for _, c := range d.closers {
+		_ = c.Close()
+	}
+	return d.src.Close()
+}
+
+// decodeError reports corrupt compressed bodies as a 400,
+// leaving size limit errors to resolve to a 413.
+func decodeError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+
+	return NewHTTPError(http.StatusBadRequest, "invalid compressed request body").WithCause(err)
+}
@@ -0,0 +1,163 @@
+package midl
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func gzipBytes(in []byte) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, _ = w.Write(in)
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func decompressTestAdapter(called *bool) Adapter {
+	return JSONAdapter(MiddlewareFunc(func(r Request) Response {
+		*called = true
+		body := r.Body()
+		if r.Error() != nil {
+			return MakeErrorResponse(http.StatusInternalServerError, r.Error())
+		}
+		return MakeResponse(http.StatusOK, string(body))
+	})).Serializer(SerializerFunc(func(in interface{}) ([]byte, error) {
+		return []byte(in.(string)), nil
+	}))
+}
+
+func TestDecompression(t *testing.T) {
+	c.Convey("decodes gzip request bodies", t, func() {
+		var called bool
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar", bytes.NewReader(gzipBytes([]byte(`{"a":1}`))))
+		r.Header.Set("Content-Encoding", "gzip")
+
+		decompressTestAdapter(&called).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, `{"a":1}`)
+		c.So(r.Header.Get("Content-Encoding"), c.ShouldEqual, "")
+	})
+
+	c.Convey("decodes zlib and raw deflate request bodies", t, func() {
+		var zbuf, fbuf bytes.Buffer
+		zw := zlib.NewWriter(&zbuf)
+		_, _ = zw.Write([]byte("zlib body"))
+		_ = zw.Close()
+		fw, _ := flate.NewWriter(&fbuf, flate.DefaultCompression)
+		_, _ = fw.Write([]byte("raw body"))
+		_ = fw.Close()
+
+		for body, expect := range map[*bytes.Buffer]string{&zbuf: "zlib body", &fbuf: "raw body"} {
+			var called bool
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "http://foo.bar", body)
+			r.Header.Set("Content-Encoding", "deflate")
+
+			decompressTestAdapter(&called).ServeHTTP(w, r)
+
+			c.So(w.Body.String(), c.ShouldEqual, expect)
+		}
+	})
+
+	c.Convey("decodes stacked codings in reverse order", t, func() {
+		var zbuf bytes.Buffer
+		zw := zlib.NewWriter(&zbuf)
+		_, _ = zw.Write([]byte("twice"))
+		_ = zw.Close()
+
+		var called bool
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar", bytes.NewReader(gzipBytes(zbuf.Bytes())))
+		r.Header.Set("Content-Encoding", "deflate, gzip")
+
+		decompressTestAdapter(&called).ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "twice")
+	})
+
+	c.Convey("rejects unsupported codings before calling middleware", t, func() {
+		var called bool
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("data"))
+		r.Header.Set("Content-Encoding", "br")
+
+		decompressTestAdapter(&called).ServeHTTP(w, r)
+
+		c.So(called, c.ShouldBeFalse)
+		c.So(w.Code, c.ShouldEqual, http.StatusUnsupportedMediaType)
+		c.So(w.Header().Get("Accept-Encoding"), c.ShouldEqual, "deflate, gzip, x-gzip")
+	})
+
+	c.Convey("ignores the coding of requests without a body", t, func() {
+		var called bool
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		r.Header.Set("Content-Encoding", "br")
+
+		decompressTestAdapter(&called).ServeHTTP(w, r)
+
+		c.So(called, c.ShouldBeTrue)
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+	})
+
+	c.Convey("uses registered decoders", t, func() {
+		var called bool
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("DATA"))
+		r.Header.Set("Content-Encoding", "lower")
+
+		decompressTestAdapter(&called).
+			Decompression(NewDecompression().Register("lower", ContentDecoderFunc(func(r io.Reader) (io.ReadCloser, error) {
+				body, _ := io.ReadAll(r)
+				return io.NopCloser(strings.NewReader(strings.ToLower(string(body)))), nil
+			}))).
+			ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "data")
+	})
+
+	c.Convey("caps the decompressed size", t, func() {
+		var called bool
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar",
+			bytes.NewReader(gzipBytes(bytes.Repeat([]byte("a"), 1<<20))))
+		r.Header.Set("Content-Encoding", "gzip")
+
+		decompressTestAdapter(&called).Decompression(NewDecompression().MaxSize(1024)).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+
+	c.Convey("reports corrupt bodies as a 400", t, func() {
+		var called bool
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("not gzip"))
+		r.Header.Set("Content-Encoding", "gzip")
+
+		decompressTestAdapter(&called).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusBadRequest)
+	})
+
+	c.Convey("leaves identity bodies alone", t, func() {
+		var called bool
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("plain"))
+		r.Header.Set("Content-Encoding", "identity")
+
+		decompressTestAdapter(&called).ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "plain")
+	})
+}
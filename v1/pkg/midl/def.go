@@ -5,6 +5,14 @@ import "errors"
 // Listing of errors that can be returned by the midl
 // library specifically.
 var (
-	ErrWrappedNil = errors.New("cannot wrap a nil request")
-	ErrNoHandlers = errors.New("no handlers")
+	ErrWrappedNil           = errors.New("cannot wrap a nil request")
+	ErrNoHandlers           = errors.New("no handlers")
+	ErrNotAcceptable        = errors.New("no acceptable representation available")
+	ErrUnsupportedMediaType = errors.New("unsupported request content type")
+	ErrNotFound             = errors.New("not found")
+	ErrMethodNotAllowed     = errors.New("method not allowed")
+	ErrBodyStreamed         = errors.New("request body has already been streamed")
+	ErrInvalidCookie        = errors.New("invalid cookie value")
+	ErrUnsupportedEncoding  = errors.New("unsupported request content encoding")
+	ErrPreconditionFailed   = errors.New("precondition failed")
 )
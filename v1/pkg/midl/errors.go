@@ -0,0 +1,186 @@
+package midl
+
+import (
+	"errors"
+	"net/http"
+)
+
+// StatusCoder defines an error which carries the HTTP
+// status code that should be sent to the client when it is
+// returned from a request.
+type StatusCoder interface {
+
+	// StatusCode returns the HTTP status code for this error.
+	StatusCode() int
+}
+
+// HTTPError is an error type which carries an HTTP status
+// code and client facing information through to an
+// Adapter's ErrorSerializer.
+//
+//   return midl.MakeErrorResponse(http.StatusInternalServerError,
+//       midl.NewHTTPError(http.StatusConflict, "user already exists").
+//           WithCode("user_exists").
+//           WithCause(err))
+//
+// The Cause is for internal use such as logging, and is
+// never rendered by the serializers provided by this
+// package.
+type HTTPError struct {
+
+	// Status is the HTTP status code for this error.
+	Status int
+
+	// Message is the client facing error message.  If empty,
+	// the standard text for Status is used.
+	Message string
+
+	// Code is an optional machine readable error code.
+	Code string
+
+	// Details is an optional value with additional client
+	// facing information about the error.
+	Details interface{}
+
+	// Cause is the optional internal error which caused this
+	// error.
+	Cause error
+}
+
+// NewHTTPError creates a new HTTPError instance with the
+// given status code and client facing message.
+func NewHTTPError(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+// WithCode sets the machine readable error code.
+//
+// Returns the current HTTPError instance.
+func (e *HTTPError) WithCode(code string) *HTTPError {
+	e.Code = code
+	return e
+}
+
+// WithDetails sets the additional client facing details.
+//
+// Returns the current HTTPError instance.
+func (e *HTTPError) WithDetails(details interface{}) *HTTPError {
+	e.Details = details
+	return e
+}
+
+// WithCause sets the internal error which caused this error.
+//
+// Returns the current HTTPError instance.
+func (e *HTTPError) WithCause(cause error) *HTTPError {
+	e.Cause = cause
+	return e
+}
+
+// Error returns the client facing message followed by the
+// message of the wrapped cause, if any.
+func (e *HTTPError) Error() string {
+	if e.Cause == nil {
+		return e.PublicMessage()
+	}
+
+	return e.PublicMessage() + ": " + e.Cause.Error()
+}
+
+// PublicMessage returns the client facing message for this
+// error, never including the wrapped cause.
+func (e *HTTPError) PublicMessage() string {
+	if e.Message != "" {
+		return e.Message
+	}
+
+	return http.StatusText(e.Status)
+}
+
+// StatusCode returns the HTTP status code for this error.
+func (e *HTTPError) StatusCode() int {
+	return e.Status
+}
+
+// Unwrap returns the wrapped cause.
+func (e *HTTPError) Unwrap() error {
+	return e.Cause
+}
+
+// ResolveStatus determines the HTTP status code that should
+// be sent for the given error.
+//
+// If any error in the given error's chain implements
+// StatusCoder with an error status, that status is used.
+// If the chain contains an *http.MaxBytesError, 413 is
+// used.  Otherwise the given current status is used if it
+// is an error status (400 or above), falling back to 500.
+func ResolveStatus(err error, current int) int {
+	var coder StatusCoder
+	if errors.As(err, &coder) && coder.StatusCode() >= 400 {
+		return coder.StatusCode()
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	if current >= 400 {
+		return current
+	}
+
+	return http.StatusInternalServerError
+}
+
+// publicError returns the HTTPError in the given error's
+// chain, or the HTTPError equivalent of a ValidationError in
+// the chain, or nil if there is neither.
+//
+// Errors recovered from panics which carry neither are
+// replaced with a generic 500 HTTPError, so that panic values
+// and runtime error text are never sent to clients.
+func publicError(err error) *HTTPError {
+	var out *HTTPError
+	if errors.As(err, &out) {
+		return out
+	}
+
+	var val *ValidationError
+	if errors.As(err, &val) {
+		return val.httpError()
+	}
+
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		return NewHTTPError(http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError)).WithCause(pe)
+	}
+
+	return nil
+}
+
+// errorStatus maps errors matching a target via errors.Is
+// to an HTTP status code.
+type errorStatus struct {
+	target error
+	code   int
+}
+
+// resolveStatus determines the HTTP status for the given
+// error, consulting the given list of registered error
+// statuses after any StatusCoder in the error's chain.
+func resolveStatus(err error, current int, statuses []errorStatus) int {
+	var coder StatusCoder
+	if errors.As(err, &coder) && coder.StatusCode() >= 400 {
+		return coder.StatusCode()
+	}
+
+	for _, status := range statuses {
+		if errors.Is(err, status.target) {
+			return status.code
+		}
+	}
+
+	return ResolveStatus(err, current)
+}
@@ -0,0 +1,119 @@
+package midl
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+type teapotError struct{}
+
+func (teapotError) Error() string   { return "teapot" }
+func (teapotError) StatusCode() int { return http.StatusTeapot }
+
+func TestHTTPError(t *testing.T) {
+	c.Convey("builds errors with client facing information", t, func() {
+		cause := errors.New("db down")
+		test := NewHTTPError(http.StatusConflict, "already exists").
+			WithCode("exists").
+			WithDetails([]string{"name"}).
+			WithCause(cause)
+
+		c.So(test.StatusCode(), c.ShouldEqual, http.StatusConflict)
+		c.So(test.Code, c.ShouldEqual, "exists")
+		c.So(test.Details, c.ShouldResemble, []string{"name"})
+		c.So(test.PublicMessage(), c.ShouldEqual, "already exists")
+		c.So(test.Error(), c.ShouldEqual, "already exists: db down")
+		c.So(errors.Is(test, cause), c.ShouldBeTrue)
+	})
+
+	c.Convey("falls back to the status text", t, func() {
+		test := NewHTTPError(http.StatusNotFound, "")
+
+		c.So(test.PublicMessage(), c.ShouldEqual, "Not Found")
+		c.So(test.Error(), c.ShouldEqual, "Not Found")
+	})
+}
+
+func TestResolveStatus(t *testing.T) {
+	c.Convey("uses status coders in the error chain", t, func() {
+		err := fmt.Errorf("wrapped: %w", teapotError{})
+		c.So(ResolveStatus(err, http.StatusBadRequest), c.ShouldEqual, http.StatusTeapot)
+	})
+
+	c.Convey("keeps the current error status", t, func() {
+		c.So(ResolveStatus(errors.New(""), http.StatusBadRequest), c.ShouldEqual, http.StatusBadRequest)
+	})
+
+	c.Convey("falls back to 500", t, func() {
+		c.So(ResolveStatus(errors.New(""), http.StatusOK), c.ShouldEqual, http.StatusInternalServerError)
+	})
+
+	c.Convey("ignores non error statuses from status coders", t, func() {
+		err := NewHTTPError(http.StatusOK, "")
+		c.So(ResolveStatus(err, http.StatusBadRequest), c.ShouldEqual, http.StatusBadRequest)
+	})
+}
+
+func TestAdapter_ErrorStatus(t *testing.T) {
+	sentinel := errors.New("missing")
+
+	serve := func(err error, code int, register func(Adapter)) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+		test := JSONAdapter(MiddlewareFunc(func(Request) Response {
+			return MakeErrorResponse(code, err)
+		}))
+		register(test)
+		test.ServeHTTP(w, r)
+		return w
+	}
+
+	c.Convey("maps registered errors to status codes", t, func() {
+		w := serve(fmt.Errorf("lookup: %w", sentinel), http.StatusInternalServerError, func(a Adapter) {
+			a.ErrorStatus(sentinel, http.StatusNotFound)
+		})
+
+		c.So(w.Code, c.ShouldEqual, http.StatusNotFound)
+	})
+
+	c.Convey("prefers status coders over registered errors", t, func() {
+		w := serve(NewHTTPError(http.StatusGone, "").WithCause(sentinel), http.StatusInternalServerError,
+			func(a Adapter) { a.ErrorStatus(sentinel, http.StatusNotFound) })
+
+		c.So(w.Code, c.ShouldEqual, http.StatusGone)
+	})
+
+	c.Convey("keeps the response status for unmatched errors", t, func() {
+		w := serve(errors.New("other"), http.StatusBadRequest, func(a Adapter) {
+			a.ErrorStatus(sentinel, http.StatusNotFound)
+		})
+
+		c.So(w.Code, c.ShouldEqual, http.StatusBadRequest)
+	})
+
+	c.Convey("never writes the wrapped cause", t, func() {
+		w := serve(NewHTTPError(http.StatusForbidden, "denied").WithCause(errors.New("secret")),
+			http.StatusInternalServerError, func(Adapter) {})
+
+		c.So(w.Code, c.ShouldEqual, http.StatusForbidden)
+		c.So(w.Body.String(), c.ShouldEqual, `{"error":"denied"}`)
+	})
+
+	c.Convey("applies to the stream adapter", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		StreamAdapter("text/plain", DefaultJSONErrorSerializer(),
+			MiddlewareFunc(func(Request) Response {
+				return MakeErrorResponse(http.StatusInternalServerError, sentinel)
+			}),
+		).ErrorStatus(sentinel, http.StatusNotFound).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusNotFound)
+	})
+}
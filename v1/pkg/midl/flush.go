@@ -0,0 +1,149 @@
+package midl
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FlushPolicy configures when a streaming Adapter flushes
+// written data to the client.
+//
+// Policies combine; data is flushed as soon as any of the
+// configured conditions is met.  The zero value never
+// flushes explicitly, leaving data in the server's buffers
+// until they fill or the response ends.
+type FlushPolicy struct {
+
+	// Bytes, if positive, flushes once at least this many
+	// bytes have been written since the last flush.
+	Bytes int64
+
+	// Interval, if positive, flushes data which has been
+	// waiting in the buffers for this long.
+	Interval time.Duration
+
+	// EachWrite flushes after every write.  For StreamAdapter
+	// this is after each read from the response body.
+	EachWrite bool
+}
+
+// flushWriter applies a FlushPolicy to a ResponseWriter,
+// keeping track of the number of bytes written and the first
+// write error encountered.
+//
+// flushWriter is safe for use by the writing goroutine and
+// its own interval flush loop; close must be called before
+// the handler returns.
+type flushWriter struct {
+	mu      sync.Mutex
+	w       writer
+	ctl     *http.ResponseController
+	policy  FlushPolicy
+	written int64
+	pending int64
+	fail    error
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+func newFlushWriter(w writer, policy FlushPolicy) *flushWriter {
+	out := &flushWriter{
+		w:      w,
+		ctl:    http.NewResponseController(w),
+		policy: policy,
+		stop:   make(chan struct{}),
+	}
+
+	if policy.Interval > 0 {
+		out.done.Add(1)
+		go out.run()
+	}
+
+	return out
+}
+
+func (f *flushWriter) run() {
+	defer f.done.Done()
+
+	ticker := time.NewTicker(f.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+			f.mu.Lock()
+			if f.pending > 0 {
+				f.flush()
+			}
+			f.mu.Unlock()
+		}
+	}
+}
+
+// Write writes the given bytes to the response, flushing
+// them if required by the policy.  Once a write has failed
+// every following write fails with the same error.
+func (f *flushWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fail != nil {
+		return 0, f.fail
+	}
+
+	n, err := f.w.Write(p)
+	f.written += int64(n)
+	f.pending += int64(n)
+	if err != nil {
+		f.fail = err
+		return n, err
+	}
+
+	if f.policy.EachWrite || (f.policy.Bytes > 0 && f.pending >= f.policy.Bytes) {
+		f.flush()
+	}
+
+	return n, f.fail
+}
+
+// flush must be called with the lock held.
+func (f *flushWriter) flush() {
+	if f.fail != nil {
+		return
+	}
+
+	f.pending = 0
+	if err := f.ctl.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		f.fail = err
+	}
+}
+
+func (f *flushWriter) err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fail
+}
+
+func (f *flushWriter) count() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.written
+}
+
+// close stops the interval flush loop and flushes any
+// remaining data.
+func (f *flushWriter) close() {
+	close(f.stop)
+	f.done.Wait()
+
+	f.mu.Lock()
+	if f.pending > 0 {
+		f.flush()
+	}
+	f.mu.Unlock()
+}
@@ -0,0 +1,329 @@
+package midl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+)
+
+// DefaultFormMemory is the number of bytes of a multipart
+// form held in memory before file contents spill to
+// temporary files, when no FormOptions are configured.
+const DefaultFormMemory int64 = 32 << 20
+
+// FormOptions configures how request forms are parsed.
+type FormOptions struct {
+
+	// MaxMemory is the number of bytes of a multipart form,
+	// across all parts, held in memory.  File contents past
+	// this threshold are written to temporary files.  If zero
+	// or less, DefaultFormMemory is used.
+	MaxMemory int64
+
+	// MaxFileSize is the maximum size in bytes of a single
+	// uploaded file.  Larger files are rejected with a 413
+	// (Request Entity Too Large).  If zero or less, the size
+	// of individual files is not limited.
+	MaxFileSize int64
+
+	// MaxTotalSize is the maximum number of bytes read from a
+	// multipart body.  Larger bodies fail with an
+	// *http.MaxBytesError, which resolves to a 413.  If zero
+	// or less, only the Adapter's MaxBodySize applies.
+	MaxTotalSize int64
+}
+
+// FormFile is a file uploaded in a multipart/form-data
+// request body.
+type FormFile struct {
+
+	// Filename is the file name given by the client.  It
+	// should not be trusted as a file system path.
+	Filename string
+
+	// Header holds the MIME headers of the form part.
+	Header textproto.MIMEHeader
+
+	// Size is the file size in bytes.
+	Size int64
+
+	content []byte
+	tmpFile string
+}
+
+// Open returns a reader over the contents of this file.
+// The caller is responsible for closing it.
+func (f *FormFile) Open() (multipart.File, error) {
+	if f.tmpFile != "" {
+		return os.Open(f.tmpFile)
+	}
+
+	return memFile{io.NewSectionReader(bytes.NewReader(f.content), 0, int64(len(f.content)))}, nil
+}
+
+type memFile struct {
+	*io.SectionReader
+}
+
+func (memFile) Close() error {
+	return nil
+}
+
+// form holds the parsed form body of a request.
+type form struct {
+	values url.Values
+	files  map[string][]*FormFile
+}
+
+func (r *request) FormValue(key string) (string, bool) {
+	values, ok := r.parseForm().values[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+func (r *request) FormValues(key string) ([]string, bool) {
+	values, ok := r.parseForm().values[key]
+	return values, ok
+}
+
+func (r *request) File(key string) (*FormFile, bool) {
+	files, ok := r.parseForm().files[key]
+	if !ok || len(files) == 0 {
+		return nil, false
+	}
+	return files[0], true
+}
+
+func (r *request) Files(key string) ([]*FormFile, bool) {
+	files, ok := r.parseForm().files[key]
+	return files, ok
+}
+
+// isForm returns whether the request body is a URL encoded
+// or multipart form.
+func (r *request) isForm() bool {
+	contentType, _ := r.Header("Content-Type")
+	kind, sub, _ := splitMediaType(contentType)
+
+	return (kind == "application" && sub == "x-www-form-urlencoded") ||
+		(kind == "multipart" && sub == "form-data")
+}
+
+// parseForm parses and caches the request's form body,
+// recording any error encountered.  Requests without a form
+// body yield an empty form.
+func (r *request) parseForm() *form {
+	if r.form != nil {
+		return r.form
+	}
+
+	r.form = &form{values: url.Values{}, files: map[string][]*FormFile{}}
+	if r.error != nil {
+		return r.form
+	}
+
+	contentType, _ := r.Header("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return r.form
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		body := r.Body()
+		if r.error != nil {
+			return r.form
+		}
+
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			r.error = NewHTTPError(http.StatusBadRequest, "invalid form body").WithCause(err)
+			return r.form
+		}
+		r.form.values = values
+
+	case "multipart/form-data":
+		boundary := params["boundary"]
+		if boundary == "" {
+			r.error = NewHTTPError(http.StatusBadRequest, "invalid form body").
+				WithCause(errors.New("missing multipart boundary"))
+			return r.form
+		}
+
+		// Use the buffered body if it has already been read,
+		// otherwise stream the parts without buffering.
+		var body io.Reader
+		if r.hasBody {
+			body = bytes.NewReader(r.body)
+		} else {
+			body = r.BodyReader()
+		}
+
+		if r.formOpts.MaxTotalSize > 0 {
+			body = &limitedReader{r: body, n: r.formOpts.MaxTotalSize, limit: r.formOpts.MaxTotalSize}
+		}
+
+		if err := r.readMultipart(multipart.NewReader(body, boundary)); err != nil {
+			r.removeFormFiles()
+			r.form = &form{values: url.Values{}, files: map[string][]*FormFile{}}
+			r.error = err
+		}
+	}
+
+	return r.form
+}
+
+// readMultipart reads every part of a multipart form into
+// the request's form, holding up to the configured memory
+// threshold in memory and spilling file contents past it to
+// temporary files.
+func (r *request) readMultipart(reader *multipart.Reader) error {
+	memory := r.formOpts.MaxMemory
+	if memory <= 0 {
+		memory = DefaultFormMemory
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return multipartError(err)
+		}
+
+		name := part.FormName()
+		if name == "" {
+			continue
+		}
+
+		if part.FileName() == "" {
+			var buf bytes.Buffer
+			n, err := io.CopyN(&buf, part, memory+1)
+			if err != nil && err != io.EOF {
+				return multipartError(err)
+			}
+			if n > memory {
+				return NewHTTPError(http.StatusRequestEntityTooLarge, "form values are too large")
+			}
+
+			memory -= n
+			r.form.values[name] = append(r.form.values[name], buf.String())
+			continue
+		}
+
+		file, err := r.readFormFile(part, &memory)
+		if err != nil {
+			return err
+		}
+
+		r.form.files[name] = append(r.form.files[name], file)
+	}
+}
+
+// readFormFile reads a single file part, keeping it in memory
+// if it fits in the remaining memory threshold.
+func (r *request) readFormFile(part *multipart.Part, memory *int64) (*FormFile, error) {
+	file := &FormFile{Filename: part.FileName(), Header: part.Header}
+
+	var src io.Reader = part
+	if max := r.formOpts.MaxFileSize; max > 0 {
+		src = io.LimitReader(part, max+1)
+	}
+
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, src, *memory+1)
+	if err != nil && err != io.EOF {
+		return nil, multipartError(err)
+	}
+
+	if n <= *memory {
+		*memory -= n
+		file.content = buf.Bytes()
+		file.Size = n
+		return file, r.checkFileSize(file)
+	}
+
+	tmp, err := os.CreateTemp("", "midl-multipart-")
+	if err != nil {
+		return nil, err
+	}
+	file.tmpFile = tmp.Name()
+	r.formFiles = append(r.formFiles, tmp.Name())
+
+	size, err := io.Copy(tmp, io.MultiReader(&buf, src))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, multipartError(err)
+	}
+
+	file.Size = size
+	return file, r.checkFileSize(file)
+}
+
+func (r *request) checkFileSize(file *FormFile) error {
+	if max := r.formOpts.MaxFileSize; max > 0 && file.Size > max {
+		return NewHTTPError(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("file %q exceeds the maximum size of %d bytes", file.Filename, max))
+	}
+
+	return nil
+}
+
+// removeFormFiles deletes any temporary files created while
+// parsing a multipart form.
+func (r *request) removeFormFiles() {
+	for _, name := range r.formFiles {
+		_ = os.Remove(name)
+	}
+
+	r.formFiles = nil
+}
+
+// multipartError wraps malformed multipart errors as a 400,
+// leaving size limit errors to resolve to a 413.
+func multipartError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+
+	return NewHTTPError(http.StatusBadRequest, "invalid form body").WithCause(err)
+}
+
+// limitedReader fails with an *http.MaxBytesError once more
+// than n bytes have been read.
+type limitedReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, &http.MaxBytesError{Limit: l.limit}
+	}
+
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n + int(l.n), &http.MaxBytesError{Limit: l.limit}
+	}
+
+	return n, err
+}
@@ -0,0 +1,156 @@
+package midl
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func multipartTestRequest(values map[string]string, files map[string]string) *http.Request {
+	buf := new(bytes.Buffer)
+	mw := multipart.NewWriter(buf)
+	for key, val := range values {
+		_ = mw.WriteField(key, val)
+	}
+	for key, val := range files {
+		fw, _ := mw.CreateFormFile(key, key+".txt")
+		_, _ = fw.Write([]byte(val))
+	}
+	_ = mw.Close()
+
+	raw := httptest.NewRequest(http.MethodPost, "http://foo.bar", buf)
+	raw.Header.Set("Content-Type", mw.FormDataContentType())
+	return raw
+}
+
+func readFormFile(f *FormFile) string {
+	file, err := f.Open()
+	if err != nil {
+		return err.Error()
+	}
+	defer file.Close()
+	out, _ := io.ReadAll(file)
+	return string(out)
+}
+
+func TestRequest_Form(t *testing.T) {
+	c.Convey("reads URL encoded forms", t, func() {
+		raw := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("a=1&b=2&b=3"))
+		raw.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req, _ := NewRequest(raw)
+
+		val, ok := req.FormValue("a")
+		c.So(ok, c.ShouldBeTrue)
+		c.So(val, c.ShouldEqual, "1")
+		vals, _ := req.FormValues("b")
+		c.So(vals, c.ShouldResemble, []string{"2", "3"})
+		_, ok = req.File("a")
+		c.So(ok, c.ShouldBeFalse)
+		c.So(req.Body(), c.ShouldResemble, []byte("a=1&b=2&b=3"))
+	})
+
+	c.Convey("reads multipart forms", t, func() {
+		req, _ := NewRequest(multipartTestRequest(
+			map[string]string{"name": "foo"}, map[string]string{"upload": "hello"}))
+
+		val, _ := req.FormValue("name")
+		c.So(val, c.ShouldEqual, "foo")
+
+		file, ok := req.File("upload")
+		c.So(ok, c.ShouldBeTrue)
+		c.So(file.Filename, c.ShouldEqual, "upload.txt")
+		c.So(file.Size, c.ShouldEqual, 5)
+		c.So(readFormFile(file), c.ShouldEqual, "hello")
+		c.So(req.Error(), c.ShouldBeNil)
+	})
+
+	c.Convey("reads multipart forms from an already buffered body", t, func() {
+		req, _ := NewRequest(multipartTestRequest(map[string]string{"name": "foo"}, nil))
+		body := req.Body()
+
+		val, _ := req.FormValue("name")
+		c.So(val, c.ShouldEqual, "foo")
+		c.So(req.Body(), c.ShouldResemble, body)
+		c.So(req.Error(), c.ShouldBeNil)
+	})
+
+	c.Convey("ignores bodies which are not forms", t, func() {
+		raw := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("a=1"))
+		raw.Header.Set("Content-Type", "text/plain")
+		req, _ := NewRequest(raw)
+
+		_, ok := req.FormValue("a")
+		c.So(ok, c.ShouldBeFalse)
+		c.So(req.Error(), c.ShouldBeNil)
+	})
+
+	c.Convey("rejects malformed multipart bodies", t, func() {
+		raw := httptest.NewRequest(http.MethodPost, "http://foo.bar", strings.NewReader("nope"))
+		raw.Header.Set("Content-Type", "multipart/form-data; boundary=xyz")
+		req, _ := NewRequest(raw)
+
+		_, ok := req.FormValue("a")
+		c.So(ok, c.ShouldBeFalse)
+		c.So(ResolveStatus(req.Error(), 0), c.ShouldEqual, http.StatusBadRequest)
+	})
+
+	c.Convey("enforces the per file size limit", t, func() {
+		req, _ := NewRequest(multipartTestRequest(nil, map[string]string{"upload": "0123456789"}))
+		setFormOptions(req, FormOptions{MaxFileSize: 4})
+
+		_, ok := req.File("upload")
+		c.So(ok, c.ShouldBeFalse)
+		c.So(ResolveStatus(req.Error(), 0), c.ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+
+	c.Convey("enforces the total size limit", t, func() {
+		req, _ := NewRequest(multipartTestRequest(nil, map[string]string{"upload": "0123456789"}))
+		setFormOptions(req, FormOptions{MaxTotalSize: 16})
+
+		_, ok := req.File("upload")
+		c.So(ok, c.ShouldBeFalse)
+		c.So(ResolveStatus(req.Error(), 0), c.ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+
+	c.Convey("binds multipart form values", t, func() {
+		var out struct {
+			Name string `form:"name"`
+		}
+		req, _ := NewRequest(multipartTestRequest(map[string]string{"name": "foo"}, nil))
+
+		c.So(req.Bind(&out).Error(), c.ShouldBeNil)
+		c.So(out.Name, c.ShouldEqual, "foo")
+	})
+}
+
+func TestAdapter_FormOptions(t *testing.T) {
+	c.Convey("spills large files to temporary files removed after the response", t, func() {
+		var path string
+		var content string
+
+		adapter := JSONAdapter(MiddlewareFunc(func(req Request) Response {
+			file, _ := req.File("upload")
+			path = file.tmpFile
+			content = readFormFile(file)
+			_, err := os.Stat(path)
+			return MakeResponse(http.StatusOK, err == nil)
+		})).FormOptions(FormOptions{MaxMemory: 4})
+
+		rec := httptest.NewRecorder()
+		adapter.ServeHTTP(rec, multipartTestRequest(nil, map[string]string{"upload": "0123456789"}))
+
+		c.So(rec.Body.String(), c.ShouldEqual, "true")
+		c.So(path, c.ShouldNotBeEmpty)
+		c.So(content, c.ShouldEqual, "0123456789")
+
+		_, err := os.Stat(path)
+		c.So(os.IsNotExist(err), c.ShouldBeTrue)
+	})
+}
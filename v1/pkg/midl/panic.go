@@ -0,0 +1,110 @@
+package midl
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError wraps a value recovered from a panic raised
+// while an Adapter was handling a request.
+//
+// Recovered panics are passed to the Adapter's
+// ErrorSerializer as a PanicError with a 500 status code.
+// The default ErrorSerializers render them with a generic
+// "Internal Server Error" message, leaving the panic value
+// to the PanicHandler.
+type PanicError struct {
+
+	// Value is the value originally passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine at the time
+	// the panic was recovered.
+	Stack []byte
+}
+
+// Error returns a description of the recovered panic value.
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Unwrap returns the recovered panic value if it was an
+// error, otherwise nil.
+func (p *PanicError) Unwrap() error {
+	if err, ok := p.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// PanicHandler defines a service which will be notified of
+// panics recovered by an Adapter.
+//
+// Useful for alerting or logging; a PanicHandler cannot
+// alter the response sent to the client.
+type PanicHandler interface {
+
+	// Handle is called with each recovered panic and the
+	// request that was being handled when it occurred.
+	//
+	// The given Request may be nil if the panic occurred
+	// before the request could be wrapped.
+	Handle(*PanicError, Request)
+}
+
+// PanicHandlerFunc provides a function wrapper for simple
+// PanicHandlers.
+type PanicHandlerFunc func(*PanicError, Request)
+
+// Handle calls the wrapped handler function.
+func (p PanicHandlerFunc) Handle(err *PanicError, q Request) {
+	p(err, q)
+}
+
+// protect runs the given function, converting any panic it
+// raises into a PanicError.
+//
+// Panics with the value http.ErrAbortHandler are not
+// recovered, as they are a deliberate signal to the http
+// server to abort the response.
+func protect(fn func()) (out *PanicError) {
+	defer func() {
+		if val := recover(); val != nil {
+			if val == http.ErrAbortHandler {
+				panic(val)
+			}
+			out = &PanicError{Value: val, Stack: debug.Stack()}
+		}
+	}()
+
+	fn()
+	return nil
+}
+
+// notifyPanic passes the given error to the PanicHandler,
+// if one is set.
+//
+// Panics raised by the PanicHandler itself are discarded.
+func notifyPanic(ph PanicHandler, err *PanicError, q Request) {
+	if ph == nil {
+		return
+	}
+
+	_ = protect(func() { ph.Handle(err, q) })
+}
+
+// panicResponse notifies the PanicHandler of the given
+// panic and returns a 500 error response wrapping it.
+func panicResponse(ph PanicHandler, err *PanicError, q Request) Response {
+	notifyPanic(ph, err, q)
+	return MakeErrorResponse(http.StatusInternalServerError, err)
+}
+
+// writeFallbackError writes a bare plain text 500 response
+// for use when the configured ErrorSerializer is unusable.
+func writeFallbackError(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write([]byte(http.StatusText(http.StatusInternalServerError)))
+}
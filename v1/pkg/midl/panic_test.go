@@ -0,0 +1,231 @@
+package midl
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+type panicWrapper struct {
+	onRequest  bool
+	onResponse bool
+	responses  *int
+}
+
+func (p panicWrapper) Request(Request) {
+	if p.onRequest {
+		panic("request wrapper")
+	}
+}
+
+func (p panicWrapper) Response(_ Request, s Response) Response {
+	*p.responses++
+	if p.onResponse {
+		panic("response wrapper")
+	}
+	return s
+}
+
+func panicErrSerializer() ErrorSerializer {
+	return ErrorSerializerFunc(func(err error, _ Request, s Response) []byte {
+		return []byte(err.Error())
+	})
+}
+
+func TestPanicError(t *testing.T) {
+	c.Convey("unwraps error values", t, func() {
+		cause := errors.New("cause")
+		test := &PanicError{Value: cause}
+
+		c.So(test.Error(), c.ShouldEqual, "panic: cause")
+		c.So(errors.Is(test, cause), c.ShouldBeTrue)
+	})
+
+	c.Convey("does not unwrap non-error values", t, func() {
+		test := &PanicError{Value: 12}
+
+		c.So(test.Error(), c.ShouldEqual, "panic: 12")
+		c.So(test.Unwrap(), c.ShouldBeNil)
+	})
+}
+
+func TestProtect(t *testing.T) {
+	c.Convey("returns nil when no panic occurs", t, func() {
+		c.So(protect(func() {}), c.ShouldBeNil)
+	})
+
+	c.Convey("captures the panic value and stack", t, func() {
+		err := protect(func() { panic("oops") })
+
+		c.So(err.Value, c.ShouldEqual, "oops")
+		c.So(string(err.Stack), c.ShouldContainSubstring, "TestProtect")
+	})
+
+	c.Convey("does not recover http.ErrAbortHandler", t, func() {
+		c.So(func() { protect(func() { panic(http.ErrAbortHandler) }) },
+			c.ShouldPanicWith, http.ErrAbortHandler)
+	})
+}
+
+func TestAdapter_ServeHTTP_panics(t *testing.T) {
+	c.Convey("recovers panics in middleware", t, func() {
+		var seen *PanicError
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		(&adapter{errSerializer: panicErrSerializer()}).
+			AddHandlers(MiddlewareFunc(func(Request) Response { panic("handler") })).
+			PanicHandler(PanicHandlerFunc(func(e *PanicError, _ Request) { seen = e })).
+			ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(w.Body.String(), c.ShouldEqual, "panic: handler")
+		c.So(seen.Value, c.ShouldEqual, "handler")
+	})
+
+	c.Convey("recovers panics in request wrappers", t, func() {
+		count := 0
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		adapter{
+			errSerializer: panicErrSerializer(),
+			wrappers: []RequestWrapper{
+				panicWrapper{responses: &count},
+				panicWrapper{onRequest: true, responses: &count},
+			},
+			handlers: []Middleware{MiddlewareFunc(func(Request) Response {
+				panic("should not be called")
+			})},
+		}.ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(w.Body.String(), c.ShouldEqual, "panic: request wrapper")
+		c.So(count, c.ShouldEqual, 1)
+	})
+
+	c.Convey("recovers panics in response wrappers", t, func() {
+		count := 0
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		adapter{
+			errSerializer: panicErrSerializer(),
+			wrappers: []RequestWrapper{
+				panicWrapper{responses: &count},
+				panicWrapper{onResponse: true, responses: &count},
+			},
+			handlers: []Middleware{MiddlewareFunc(func(Request) Response {
+				return NewResponse()
+			})},
+		}.ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(w.Body.String(), c.ShouldEqual, "panic: response wrapper")
+		c.So(count, c.ShouldEqual, 2)
+	})
+
+	c.Convey("recovers panics in serializers", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		adapter{
+			errSerializer: panicErrSerializer(),
+			serializer: SerializerFunc(func(interface{}) ([]byte, error) {
+				panic("serializer")
+			}),
+			handlers: []Middleware{MiddlewareFunc(func(Request) Response {
+				return MakeResponse(http.StatusOK, "body")
+			})},
+		}.ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(w.Body.String(), c.ShouldEqual, "panic: serializer")
+	})
+
+	c.Convey("recovers panics in empty handlers", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		adapter{
+			errSerializer: panicErrSerializer(),
+			emptyHandler: EmptyHandlerFunc(func(Request, Response) []byte {
+				panic("empty")
+			}),
+			handlers: []Middleware{MiddlewareFunc(func(Request) Response {
+				return NewResponse()
+			})},
+		}.ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(w.Body.String(), c.ShouldEqual, "panic: empty")
+	})
+
+	c.Convey("falls back to plain text when the error serializer panics", t, func() {
+		count := 0
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		adapter{
+			errSerializer: ErrorSerializerFunc(func(error, Request, Response) []byte {
+				panic("error serializer")
+			}),
+			panicHandler: PanicHandlerFunc(func(*PanicError, Request) { count++ }),
+		}.ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(w.Body.String(), c.ShouldEqual, http.StatusText(http.StatusInternalServerError))
+		c.So(count, c.ShouldEqual, 1)
+	})
+
+	c.Convey("ignores panics raised by the panic handler", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		adapter{
+			errSerializer: panicErrSerializer(),
+			panicHandler:  PanicHandlerFunc(func(*PanicError, Request) { panic("hook") }),
+			handlers: []Middleware{MiddlewareFunc(func(Request) Response {
+				panic("handler")
+			})},
+		}.ServeHTTP(w, r)
+
+		c.So(w.Body.String(), c.ShouldEqual, "panic: handler")
+	})
+}
+
+type panicReader struct{}
+
+func (panicReader) Read([]byte) (int, error) { panic("reader") }
+
+func TestStreamAdapter_ServeHTTP_panics(t *testing.T) {
+	c.Convey("recovers panics in middleware", t, func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		StreamAdapter("text/plain", panicErrSerializer(),
+			MiddlewareFunc(func(Request) Response { panic("handler") }),
+		).ServeHTTP(w, r)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(w.Body.String(), c.ShouldEqual, "panic: handler")
+	})
+
+	c.Convey("aborts the response when the body reader panics", t, func() {
+		var seen *PanicError
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)
+
+		test := StreamAdapter("text/plain", panicErrSerializer(),
+			MiddlewareFunc(func(Request) Response {
+				return MakeResponse(http.StatusOK, panicReader{})
+			}),
+		).PanicHandler(PanicHandlerFunc(func(e *PanicError, _ Request) { seen = e }))
+
+		c.So(func() { test.ServeHTTP(w, r) }, c.ShouldPanicWith, http.ErrAbortHandler)
+		c.So(seen.Value, c.ShouldEqual, "reader")
+	})
+}
@@ -0,0 +1,109 @@
+package midl
+
+import "net/http"
+
+// runPipeline passes the given request through the request
+// wrappers and handlers and returns the response produced
+// once every wrapper has seen it.
+//
+// A panic in any wrapper or handler is recovered and
+// replaced with a 500 error response wrapping a PanicError.
+// Wrappers which already saw the request will still be
+// given the resulting response.
+func runPipeline(
+	req Request,
+	wrappers []RequestWrapper,
+	handlers []Middleware,
+	ph PanicHandler,
+) Response {
+	var res Response
+	var ran int
+
+	for ; ran < len(wrappers); ran++ {
+		wrap := wrappers[ran]
+		if err := protect(func() { wrap.Request(req) }); err != nil {
+			res = panicResponse(ph, err, req)
+			break
+		}
+	}
+
+	if res == nil {
+		res = runHandlers(req, handlers, ph)
+	}
+
+	res = ensureResponse(res)
+
+	for i := ran - 1; i > -1; i-- {
+		var next Response
+		wrap := wrappers[i]
+
+		if err := protect(func() { next = wrap.Response(req, res) }); err != nil {
+			res = panicResponse(ph, err, req)
+		} else {
+			res = next
+		}
+	}
+
+	return ensureResponse(res)
+}
+
+// runHandlers calls each of the given handlers in order
+// until one returns a non-nil response.
+//
+// If the request's context is done before a handler is
+// called, the remaining handlers are skipped and a 503
+// error response wrapping the context's error is returned.
+func runHandlers(req Request, handlers []Middleware, ph PanicHandler) Response {
+	var res Response
+
+	for _, hand := range handlers {
+		if hand == nil {
+			continue
+		}
+
+		if err := req.Context().Err(); err != nil {
+			return MakeErrorResponse(http.StatusServiceUnavailable, err)
+		}
+
+		if err := protect(func() { res = hand.Handle(req) }); err != nil {
+			return panicResponse(ph, err, req)
+		}
+
+		if res != nil {
+			break
+		}
+	}
+
+	return res
+}
+
+// limitBody caps the given request's body at the given
+// number of bytes, returning an error if the request
+// declares a larger body up front.
+//
+// A max of zero or less disables the limit.
+func limitBody(w http.ResponseWriter, r *http.Request, max int64) error {
+	if max <= 0 {
+		return nil
+	}
+
+	if r.ContentLength > max {
+		return &http.MaxBytesError{Limit: max}
+	}
+
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+	}
+
+	return nil
+}
+
+func ensureResponse(res Response) Response {
+	if res == nil {
+		return NewResponse().
+			SetCode(http.StatusInternalServerError).
+			SetError(ErrNoHandlers)
+	}
+
+	return res
+}
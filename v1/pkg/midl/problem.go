@@ -0,0 +1,347 @@
+package midl
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ProblemDetails holds the members of an RFC 9457 (formerly
+// RFC 7807) Problem Details document.
+type ProblemDetails struct {
+
+	// Type is a URI reference identifying the problem type.
+	// Defaults to "about:blank".
+	Type string
+
+	// Title is a short, human readable summary of the problem
+	// type.  Defaults to the standard text for Status.
+	Title string
+
+	// Status is the HTTP status code for this occurrence of
+	// the problem.  Defaults to the resolved response status.
+	Status int
+
+	// Detail is a human readable explanation specific to this
+	// occurrence of the problem.
+	Detail string
+
+	// Instance is a URI reference identifying this occurrence
+	// of the problem.  Defaults to the request path.
+	Instance string
+
+	// Extensions holds additional members to include in the
+	// document.  Extensions named after a standard member are
+	// ignored.
+	Extensions map[string]interface{}
+}
+
+// Problem defines an error which describes itself as an RFC
+// 9457 Problem Details document.
+//
+// Errors which do not implement Problem are rendered by the
+// problem serializers with default values; see
+// ProblemJSONErrorSerializer.
+type Problem interface {
+
+	// Problem returns the problem details for this error.
+	// Empty members will be filled with defaults.
+	Problem() ProblemDetails
+}
+
+// ProblemJSONErrorSerializer returns an ErrorSerializer
+// implementation which renders errors as RFC 9457
+// "application/problem+json" documents.
+//
+// If the error's chain contains a Problem, its details are
+// used.  Otherwise, if the chain contains an HTTPError, its
+// public message is used as the detail and its code and
+// details become the "code" and "details" extension members.
+// The message of any other error is used as the detail.
+//
+// The response status code is set using ResolveStatus,
+// unless the Problem provides its own status.
+func ProblemJSONErrorSerializer() ErrorSerializer {
+	return new(problemJSONSerializer)
+}
+
+// ProblemXMLErrorSerializer returns an ErrorSerializer
+// implementation which renders errors as RFC 9457
+// "application/problem+xml" documents in the
+// "urn:ietf:rfc:7807" namespace.
+//
+// Problem members are resolved the same way as with
+// ProblemJSONErrorSerializer.  Extension values are
+// rendered as child elements, with array items wrapped in
+// "i" elements as described in RFC 9457 Appendix B.  Member
+// names which are not valid XML names, such as "tags[2]",
+// are rendered as "entry" elements with the name in a
+// "name" attribute.
+func ProblemXMLErrorSerializer() ErrorSerializer {
+	return new(problemXMLSerializer)
+}
+
+type problemJSONSerializer struct{}
+
+func (problemJSONSerializer) Serialize(e error, q Request, s Response) []byte {
+	prob := resolveProblem(e, q, s)
+	s.SetHeader("Content-Type", "application/problem+json")
+
+	std, _ := marshalJSON(struct {
+		Type     string `json:"type"`
+		Title    string `json:"title"`
+		Status   int    `json:"status"`
+		Detail   string `json:"detail,omitempty"`
+		Instance string `json:"instance,omitempty"`
+	}{prob.Type, prob.Title, prob.Status, prob.Detail, prob.Instance})
+
+	buf := bytes.NewBuffer(std[:len(std)-1])
+	for _, key := range extensionKeys(prob.Extensions) {
+		val, err := marshalJSON(prob.Extensions[key])
+		if err != nil {
+			continue
+		}
+
+		name, _ := marshalJSON(key)
+		buf.WriteByte(',')
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes()
+}
+
+type problemXMLSerializer struct{}
+
+const problemNamespace = "urn:ietf:rfc:7807"
+
+func (problemXMLSerializer) Serialize(e error, q Request, s Response) []byte {
+	prob := resolveProblem(e, q, s)
+	s.SetHeader("Content-Type", "application/problem+xml")
+
+	out, err := encodeProblemXML(prob)
+	if err != nil {
+		// Only the extension members may fail to encode.
+		prob.Extensions = nil
+		out, _ = encodeProblemXML(prob)
+	}
+
+	return out
+}
+
+// encodeProblemXML renders the given problem details as an
+// XML document.
+func encodeProblemXML(prob ProblemDetails) ([]byte, error) {
+	buf := bytes.NewBufferString(xml.Header)
+	enc := xml.NewEncoder(buf)
+
+	root := xml.StartElement{Name: xml.Name{Local: "problem"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: problemNamespace}}}
+	if err := enc.EncodeToken(root); err != nil {
+		return nil, err
+	}
+
+	members := [][2]string{{"type", prob.Type}, {"title", prob.Title}, {"status", fmt.Sprint(prob.Status)}}
+	if prob.Detail != "" {
+		members = append(members, [2]string{"detail", prob.Detail})
+	}
+	if prob.Instance != "" {
+		members = append(members, [2]string{"instance", prob.Instance})
+	}
+	for _, m := range members {
+		if err := writeXMLText(enc, m[0], m[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range extensionKeys(prob.Extensions) {
+		raw, err := json.Marshal(prob.Extensions[key])
+		if err != nil {
+			continue
+		}
+
+		var val interface{}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&val); err != nil {
+			return nil, err
+		}
+		if err := writeXMLValue(enc, key, val); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.EncodeToken(root.End()); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// resolveProblem builds the problem details for the given
+// error, filling empty members with defaults and setting
+// the response status code.
+func resolveProblem(e error, q Request, s Response) ProblemDetails {
+	var out ProblemDetails
+	var prob Problem
+
+	switch {
+	case errors.As(e, &prob):
+		out = prob.Problem()
+	case publicError(e) != nil:
+		pub := publicError(e)
+		out.Detail = pub.PublicMessage()
+		out.Extensions = map[string]interface{}{}
+		if pub.Code != "" {
+			out.Extensions["code"] = pub.Code
+		}
+		if pub.Details != nil {
+			out.Extensions["details"] = pub.Details
+		}
+	default:
+		out.Detail = e.Error()
+	}
+
+	if out.Status == 0 {
+		out.Status = ResolveStatus(e, s.Code())
+	}
+	s.SetCode(out.Status)
+
+	if out.Type == "" {
+		out.Type = "about:blank"
+	}
+
+	if out.Title == "" {
+		out.Title = http.StatusText(out.Status)
+	}
+
+	if out.Instance == "" && q != nil && q.RawRequest() != nil {
+		out.Instance = q.RawRequest().URL.Path
+	}
+
+	return out
+}
+
+// extensionKeys returns the sorted extension member names,
+// excluding any which collide with standard members.
+func extensionKeys(ext map[string]interface{}) []string {
+	out := make([]string, 0, len(ext))
+
+	for key := range ext {
+		switch key {
+		case "type", "title", "status", "detail", "instance":
+		default:
+			out = append(out, key)
+		}
+	}
+
+	sort.Strings(out)
+	return out
+}
+
+func marshalJSON(in interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(in); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+func writeXMLText(enc *xml.Encoder, name, value string) error {
+	start := xmlElement(name)
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(xml.CharData(value)); err != nil {
+		return err
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// writeXMLValue writes a generic JSON decoded value as an
+// XML element.
+func writeXMLValue(enc *xml.Encoder, name string, value interface{}) error {
+	start := xmlElement(name)
+
+	switch v := value.(type) {
+	case nil:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+	case []interface{}:
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := writeXMLValue(enc, "i", item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := writeXMLValue(enc, key, v[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return writeXMLText(enc, name, fmt.Sprint(v))
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// xmlElement returns the start of an element for the given
+// member name, using an "entry" element with a "name"
+// attribute if the name is not a valid XML name.
+func xmlElement(name string) xml.StartElement {
+	if validXMLName(name) {
+		return xml.StartElement{Name: xml.Name{Local: name}}
+	}
+
+	return xml.StartElement{Name: xml.Name{Local: "entry"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: name}}}
+}
+
+// validXMLName returns whether the given name may be used as
+// an unprefixed element name.  Names starting with "xml" are
+// reserved.
+func validXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+
+	for i, r := range name {
+		switch {
+		case r == '_', unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,140 @@
+package midl
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+type outOfCredit struct{}
+
+func (outOfCredit) Error() string { return "out of credit" }
+
+func (outOfCredit) Problem() ProblemDetails {
+	return ProblemDetails{
+		Type:   "https://example.com/probs/out-of-credit",
+		Title:  "You do not have enough credit.",
+		Status: http.StatusForbidden,
+		Detail: "Your current balance is 30, but that costs 50.",
+		Extensions: map[string]interface{}{
+			"balance":  30,
+			"accounts": []string{"/account/12345", "/account/67890"},
+			"status":   "ignored",
+		},
+	}
+}
+
+func problemTestRequest() Request {
+	req, _ := NewRequest(httptest.NewRequest(http.MethodGet, "http://foo.bar/account/12345/msgs/abc", nil))
+	return req
+}
+
+func TestProblemJSONErrorSerializer(t *testing.T) {
+	c.Convey("renders Problem errors", t, func() {
+		res := NewResponse()
+		data := ProblemJSONErrorSerializer().Serialize(outOfCredit{}, problemTestRequest(), res)
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusForbidden)
+		c.So(res.Header("Content-Type"), c.ShouldEqual, "application/problem+json")
+		c.So(string(data), c.ShouldEqual, `{"type":"https://example.com/probs/out-of-credit",`+
+			`"title":"You do not have enough credit.","status":403,`+
+			`"detail":"Your current balance is 30, but that costs 50.",`+
+			`"instance":"/account/12345/msgs/abc",`+
+			`"accounts":["/account/12345","/account/67890"],"balance":30}`)
+	})
+
+	c.Convey("renders HTTPErrors without their cause", t, func() {
+		res := NewResponse()
+		err := NewHTTPError(http.StatusConflict, "taken").
+			WithCode("taken").
+			WithCause(errors.New("secret"))
+		data := ProblemJSONErrorSerializer().Serialize(err, nil, res)
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusConflict)
+		c.So(string(data), c.ShouldEqual,
+			`{"type":"about:blank","title":"Conflict","status":409,"detail":"taken","code":"taken"}`)
+	})
+
+	c.Convey("hides recovered panic values", t, func() {
+		res := MakeErrorResponse(http.StatusInternalServerError, nil)
+		data := ProblemJSONErrorSerializer().Serialize(&PanicError{Value: "secret"}, nil, res)
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusInternalServerError)
+		c.So(string(data), c.ShouldEqual, `{"type":"about:blank","title":"Internal Server Error",`+
+			`"status":500,"detail":"Internal Server Error"}`)
+	})
+
+	c.Convey("renders plain errors with defaults", t, func() {
+		res := MakeErrorResponse(http.StatusBadRequest, nil)
+		data := ProblemJSONErrorSerializer().Serialize(errors.New("bad"), problemTestRequest(), res)
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusBadRequest)
+		c.So(string(data), c.ShouldEqual, `{"type":"about:blank","title":"Bad Request",`+
+			`"status":400,"detail":"bad","instance":"/account/12345/msgs/abc"}`)
+	})
+}
+
+func TestProblemXMLErrorSerializer(t *testing.T) {
+	c.Convey("renders Problem errors", t, func() {
+		res := NewResponse()
+		data := ProblemXMLErrorSerializer().Serialize(outOfCredit{}, problemTestRequest(), res)
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusForbidden)
+		c.So(res.Header("Content-Type"), c.ShouldEqual, "application/problem+xml")
+		c.So(string(data), c.ShouldEqual, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+			`<problem xmlns="urn:ietf:rfc:7807">`+
+			`<type>https://example.com/probs/out-of-credit</type>`+
+			`<title>You do not have enough credit.</title>`+
+			`<status>403</status>`+
+			`<detail>Your current balance is 30, but that costs 50.</detail>`+
+			`<instance>/account/12345/msgs/abc</instance>`+
+			`<accounts><i>/account/12345</i><i>/account/67890</i></accounts>`+
+			`<balance>30</balance>`+
+			`</problem>`)
+	})
+
+	c.Convey("renders member names which are not XML names as entries", t, func() {
+		res := NewResponse()
+		err := NewHTTPError(http.StatusBadRequest, "bad").WithDetails(map[string]interface{}{
+			"tags[2]":   "too long",
+			"1st name":  "x",
+			"xmlns":     "y",
+			"last-name": "z",
+		})
+		data := ProblemXMLErrorSerializer().Serialize(err, nil, res)
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusBadRequest)
+		c.So(xml.Unmarshal(data, new(interface{})), c.ShouldBeNil)
+		c.So(string(data), c.ShouldEqual, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+			`<problem xmlns="urn:ietf:rfc:7807">`+
+			`<type>about:blank</type>`+
+			`<title>Bad Request</title>`+
+			`<status>400</status>`+
+			`<detail>bad</detail>`+
+			`<details>`+
+			`<entry name="1st name">x</entry>`+
+			`<last-name>z</last-name>`+
+			`<entry name="tags[2]">too long</entry>`+
+			`<entry name="xmlns">y</entry>`+
+			`</details>`+
+			`</problem>`)
+	})
+
+	c.Convey("renders plain errors with defaults", t, func() {
+		res := NewResponse()
+		data := ProblemXMLErrorSerializer().Serialize(errors.New("<bad>"), nil, res)
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusInternalServerError)
+		c.So(string(data), c.ShouldEqual, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+			`<problem xmlns="urn:ietf:rfc:7807">`+
+			`<type>about:blank</type>`+
+			`<title>Internal Server Error</title>`+
+			`<status>500</status>`+
+			`<detail>&lt;bad&gt;</detail>`+
+			`</problem>`)
+	})
+}
@@ -0,0 +1,185 @@
+package midl
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errRequired = errors.New("is required")
+
+// queryValues returns the parsed query string of the wrapped
+// request, parsing it on first use.
+func (r *request) queryValues() url.Values {
+	if r.query == nil {
+		r.query = r.raw.URL.Query()
+	}
+
+	return r.query
+}
+
+// queryParam returns the first value of the given query
+// parameter, recording an error if it is required but
+// absent.
+func (r *request) queryParam(key string, required bool) (string, bool) {
+	val, ok := r.Parameter(key)
+	if !ok && required {
+		r.queryError(key, errRequired)
+	}
+
+	return val, ok
+}
+
+// queryError records a query parameter conversion failure,
+// adding it to the ValidationError recorded by previous
+// failures.  Does nothing if another error has been
+// previously encountered.
+func (r *request) queryError(key string, err error) {
+	switch cur := r.error.(type) {
+	case nil:
+		r.error = new(ValidationError).Add("query", key, err.Error())
+	case *ValidationError:
+		cur.Add("query", key, err.Error())
+	}
+}
+
+func (r *request) ParameterInt(key string, def int) int {
+	return r.paramInt(key, def, false)
+}
+
+func (r *request) ParameterIntRequired(key string) int {
+	return r.paramInt(key, 0, true)
+}
+
+func (r *request) paramInt(key string, def int, required bool) int {
+	raw, ok := r.queryParam(key, required)
+	if !ok {
+		return def
+	}
+
+	val, err := strconv.ParseInt(raw, 10, 0)
+	if err != nil {
+		r.queryError(key, numError(err, "an integer"))
+		return def
+	}
+
+	return int(val)
+}
+
+func (r *request) ParameterBool(key string, def bool) bool {
+	return r.paramBool(key, def, false)
+}
+
+func (r *request) ParameterBoolRequired(key string) bool {
+	return r.paramBool(key, false, true)
+}
+
+func (r *request) paramBool(key string, def, required bool) bool {
+	raw, ok := r.queryParam(key, required)
+	if !ok {
+		return def
+	}
+
+	val, err := parseBool(raw)
+	if err != nil {
+		r.queryError(key, err)
+		return def
+	}
+
+	return val
+}
+
+func (r *request) ParameterFloat(key string, def float64) float64 {
+	return r.paramFloat(key, def, false)
+}
+
+func (r *request) ParameterFloatRequired(key string) float64 {
+	return r.paramFloat(key, 0, true)
+}
+
+func (r *request) paramFloat(key string, def float64, required bool) float64 {
+	raw, ok := r.queryParam(key, required)
+	if !ok {
+		return def
+	}
+
+	val, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		r.queryError(key, numError(err, "a number"))
+		return def
+	}
+
+	return val
+}
+
+func (r *request) ParameterTime(key, layout string, def time.Time) time.Time {
+	return r.paramTime(key, layout, def, false)
+}
+
+func (r *request) ParameterTimeRequired(key, layout string) time.Time {
+	return r.paramTime(key, layout, time.Time{}, true)
+}
+
+func (r *request) paramTime(key, layout string, def time.Time, required bool) time.Time {
+	raw, ok := r.queryParam(key, required)
+	if !ok {
+		return def
+	}
+
+	val, err := parseTime(raw, layout)
+	if err != nil {
+		r.queryError(key, err)
+		return def
+	}
+
+	return val
+}
+
+func (r *request) ParameterDuration(key string, def time.Duration) time.Duration {
+	return r.paramDuration(key, def, false)
+}
+
+func (r *request) ParameterDurationRequired(key string) time.Duration {
+	return r.paramDuration(key, 0, true)
+}
+
+func (r *request) paramDuration(key string, def time.Duration, required bool) time.Duration {
+	raw, ok := r.queryParam(key, required)
+	if !ok {
+		return def
+	}
+
+	val, err := parseDuration(raw)
+	if err != nil {
+		r.queryError(key, err)
+		return def
+	}
+
+	return val
+}
+
+func (r *request) ParameterEnum(key, def string, options ...string) string {
+	return r.paramEnum(key, def, false, options)
+}
+
+func (r *request) ParameterEnumRequired(key string, options ...string) string {
+	return r.paramEnum(key, "", true, options)
+}
+
+func (r *request) paramEnum(key, def string, required bool, options []string) string {
+	raw, ok := r.queryParam(key, required)
+	if !ok {
+		return def
+	}
+
+	for _, option := range options {
+		if raw == option {
+			return raw
+		}
+	}
+
+	r.queryError(key, errors.New("must be one of: "+strings.Join(options, ", ")))
+	return def
+}
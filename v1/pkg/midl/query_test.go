@@ -0,0 +1,91 @@
+package midl
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func queryTestRequest(query string) Request {
+	req, _ := NewRequest(httptest.NewRequest(http.MethodGet, "http://foo.bar/?"+query, nil))
+	return req
+}
+
+func TestRequest_TypedParameters(t *testing.T) {
+	c.Convey("converts present parameters", t, func() {
+		req := queryTestRequest("n=-4&b=true&f=1.5&t=2020-01-02&d=1m&e=asc")
+
+		c.So(req.ParameterInt("n", 1), c.ShouldEqual, -4)
+		c.So(req.ParameterBool("b", false), c.ShouldBeTrue)
+		c.So(req.ParameterFloat("f", 0), c.ShouldEqual, 1.5)
+		c.So(req.ParameterTime("t", "2006-01-02", time.Time{}), c.ShouldEqual,
+			time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC))
+		c.So(req.ParameterDuration("d", 0), c.ShouldEqual, time.Minute)
+		c.So(req.ParameterEnum("e", "desc", "asc", "desc"), c.ShouldEqual, "asc")
+		c.So(req.Error(), c.ShouldBeNil)
+	})
+
+	c.Convey("returns defaults for absent parameters", t, func() {
+		req := queryTestRequest("")
+		now := time.Now()
+
+		c.So(req.ParameterInt("n", 7), c.ShouldEqual, 7)
+		c.So(req.ParameterBool("b", true), c.ShouldBeTrue)
+		c.So(req.ParameterFloat("f", 2.5), c.ShouldEqual, 2.5)
+		c.So(req.ParameterTime("t", "", now), c.ShouldEqual, now)
+		c.So(req.ParameterDuration("d", time.Second), c.ShouldEqual, time.Second)
+		c.So(req.ParameterEnum("e", "desc", "asc", "desc"), c.ShouldEqual, "desc")
+		c.So(req.Error(), c.ShouldBeNil)
+	})
+
+	c.Convey("aggregates conversion failures", t, func() {
+		req := queryTestRequest("n=x&b=maybe&e=up&f=1")
+
+		c.So(req.ParameterInt("n", 7), c.ShouldEqual, 7)
+		c.So(req.ParameterBool("b", true), c.ShouldBeTrue)
+		c.So(req.ParameterFloat("f", 0), c.ShouldEqual, 1)
+		c.So(req.ParameterEnum("e", "desc", "asc", "desc"), c.ShouldEqual, "desc")
+		c.So(req.ParameterDurationRequired("d"), c.ShouldEqual, 0)
+
+		var val *ValidationError
+		c.So(errors.As(req.Error(), &val), c.ShouldBeTrue)
+		c.So(val.StatusCode(), c.ShouldEqual, http.StatusBadRequest)
+		c.So(val.Fields, c.ShouldResemble, []FieldError{
+			{In: "query", Field: "n", Message: "must be an integer"},
+			{In: "query", Field: "b", Message: "must be a boolean"},
+			{In: "query", Field: "e", Message: "must be one of: asc, desc"},
+			{In: "query", Field: "d", Message: "is required"},
+		})
+	})
+
+	c.Convey("reports missing required parameters", t, func() {
+		req := queryTestRequest("n=1")
+
+		c.So(req.ParameterIntRequired("n"), c.ShouldEqual, 1)
+		c.So(req.ParameterBoolRequired("b"), c.ShouldBeFalse)
+		c.So(req.ParameterFloatRequired("f"), c.ShouldEqual, 0)
+		c.So(req.ParameterTimeRequired("t", ""), c.ShouldEqual, time.Time{})
+		c.So(req.ParameterEnumRequired("e", "a"), c.ShouldEqual, "")
+		c.So(req.Error().(*ValidationError).Fields, c.ShouldHaveLength, 4)
+	})
+
+	c.Convey("does not replace other errors", t, func() {
+		req := queryTestRequest("n=x")
+		req.ProcessBody(BodyProcessorFunc(func([]byte) error { return ErrNoHandlers }))
+
+		c.So(req.ParameterInt("n", 3), c.ShouldEqual, 3)
+		c.So(req.Error(), c.ShouldEqual, ErrNoHandlers)
+	})
+
+	c.Convey("caches the parsed query", t, func() {
+		req := queryTestRequest("n=1")
+
+		c.So(req.ParameterInt("n", 0), c.ShouldEqual, 1)
+		req.RawRequest().URL.RawQuery = "n=2"
+		c.So(req.ParameterInt("n", 0), c.ShouldEqual, 1)
+	})
+}
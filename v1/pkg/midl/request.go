@@ -2,8 +2,12 @@ package midl
 
 import (
 	"bytes"
+	"context"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 // Request defines a wrapper/accessor for the default Golang
@@ -15,6 +19,13 @@ type Request interface {
 	// Headers gets all headers stored at the given key.
 	Headers(key string) (values []string, ok bool)
 
+	// Cookie gets the cookie with the given name sent with
+	// the request.
+	Cookie(name string) (cookie *http.Cookie, ok bool)
+
+	// Cookies gets all cookies sent with the request.
+	Cookies() []*http.Cookie
+
 	// Body retrieves the request body bytes.
 	//
 	// In the event of a read error, returns nil; the error
@@ -24,6 +35,17 @@ type Request interface {
 	// reader once.
 	Body() []byte
 
+	// BodyReader returns a reader over the request body for
+	// processing input incrementally without buffering it in
+	// memory.
+	//
+	// If Body has already been called the returned reader
+	// reads from the buffered bytes.  Otherwise the body is
+	// streamed from the wrapped http.Request; any later call
+	// to Body will return nil and record ErrBodyStreamed as
+	// this Request's error.
+	BodyReader() io.Reader
+
 	// Host retrieves the host string from the request.
 	Host() string
 
@@ -35,6 +57,110 @@ type Request interface {
 	// key.
 	Parameters(key string) (values []string, ok bool)
 
+	// ParameterInt gets the query parameter stored at the
+	// given key as an int, or the given default if the
+	// parameter is absent.
+	//
+	// The typed Parameter methods parse the query string once
+	// per Request.  Values which cannot be converted return
+	// the default and are recorded in a *ValidationError
+	// retrievable from the Error method, so that a handler may
+	// read several parameters before checking for errors once.
+	// Conversion failures are only recorded if no other error
+	// has been previously encountered.
+	ParameterInt(key string, def int) int
+
+	// ParameterIntRequired gets the query parameter stored at
+	// the given key as an int, recording an error if the
+	// parameter is absent.
+	ParameterIntRequired(key string) int
+
+	// ParameterBool gets the query parameter stored at the
+	// given key as a bool, or the given default if the
+	// parameter is absent.
+	ParameterBool(key string, def bool) bool
+
+	// ParameterBoolRequired gets the query parameter stored at
+	// the given key as a bool, recording an error if the
+	// parameter is absent.
+	ParameterBoolRequired(key string) bool
+
+	// ParameterFloat gets the query parameter stored at the
+	// given key as a float64, or the given default if the
+	// parameter is absent.
+	ParameterFloat(key string, def float64) float64
+
+	// ParameterFloatRequired gets the query parameter stored
+	// at the given key as a float64, recording an error if
+	// the parameter is absent.
+	ParameterFloatRequired(key string) float64
+
+	// ParameterTime gets the query parameter stored at the
+	// given key as a time.Time parsed with the given layout
+	// (time.RFC3339 if empty), or the given default if the
+	// parameter is absent.
+	ParameterTime(key, layout string, def time.Time) time.Time
+
+	// ParameterTimeRequired gets the query parameter stored at
+	// the given key as a time.Time parsed with the given
+	// layout, recording an error if the parameter is absent.
+	ParameterTimeRequired(key, layout string) time.Time
+
+	// ParameterDuration gets the query parameter stored at the
+	// given key as a time.Duration, or the given default if
+	// the parameter is absent.
+	ParameterDuration(key string, def time.Duration) time.Duration
+
+	// ParameterDurationRequired gets the query parameter
+	// stored at the given key as a time.Duration, recording an
+	// error if the parameter is absent.
+	ParameterDurationRequired(key string) time.Duration
+
+	// ParameterEnum gets the query parameter stored at the
+	// given key, which must be one of the given options, or
+	// the given default if the parameter is absent.
+	ParameterEnum(key, def string, options ...string) string
+
+	// ParameterEnumRequired gets the query parameter stored at
+	// the given key, which must be one of the given options,
+	// recording an error if the parameter is absent.
+	ParameterEnumRequired(key string, options ...string) string
+
+	// FormValue gets the first value stored at the given key
+	// in a URL encoded or multipart form request body.
+	//
+	// The form is parsed once per Request, using the buffered
+	// body if Body has already been called.  Multipart bodies
+	// which have not been buffered are streamed, after which
+	// Body will return nil and record ErrBodyStreamed.
+	//
+	// Parse failures are retrievable from the Error method.
+	// Requests without a form body have no form values.
+	FormValue(key string) (value string, ok bool)
+
+	// FormValues gets all values stored at the given key in a
+	// URL encoded or multipart form request body.
+	FormValues(key string) (values []string, ok bool)
+
+	// File gets the first file uploaded under the given key in
+	// a multipart form request body.
+	//
+	// Files past the Adapter's configured FormOptions memory
+	// threshold are stored in temporary files, which are
+	// removed once the response has been written.
+	File(key string) (file *FormFile, ok bool)
+
+	// Files gets all files uploaded under the given key in a
+	// multipart form request body.
+	Files(key string) (files []*FormFile, ok bool)
+
+	// PathParam gets the path parameter matched under the
+	// given name by a Router, or by an http.ServeMux pattern
+	// when mounted under the standard library mux.
+	//
+	// Returns the empty string if no such parameter exists.
+	PathParam(name string) string
+
 	// RawRequest retrieves the raw Go standard library
 	// request.
 	//
@@ -57,9 +183,56 @@ type Request interface {
 	// been previously encountered.
 	ProcessBody(BodyProcessor) Request
 
+	// Bind populates the fields of the struct pointed to by
+	// dst from the values of this request, using the field
+	// tags below.
+	//
+	//   type Search struct {
+	//       ID     int       `path:"id"`
+	//       Tags   []string  `query:"tag"`
+	//       Since  time.Time `query:"since" layout:"2006-01-02"`
+	//       Token  *string   `header:"X-Token"`
+	//       Name   string    `form:"name"`
+	//       Filter Filter    `body:""`
+	//   }
+	//
+	// Form values are read from URL encoded or multipart
+	// request bodies, and body fields are decoded with the
+	// default Decoder for the request's Content-Type.  Fields
+	// with no value in the request are left unchanged, and
+	// untagged embedded structs are bound recursively.
+	//
+	// Values which cannot be converted are recorded together
+	// as a *ValidationError retrievable from the Error method.
+	// Targets which cannot be bound, such as fields of an
+	// unsupported type, are reported as a *BindError which
+	// resolves to a 500.
+	//
+	// Calls to this method will do nothing if an error has
+	// been previously encountered.
+	Bind(dst interface{}) Request
+
 	// AdditionalContext returns a map for use in assigning
 	// additional arbitrary context data to a request.
+	//
+	// Values stored in this map are visible through the
+	// context returned by Context.  Prefer ContextKey for
+	// typed access to request scoped values.
 	AdditionalContext() map[interface{}]interface{}
+
+	// Context returns the request's context.
+	//
+	// The returned context carries the deadline, cancellation
+	// and values of the wrapped http.Request's context, with
+	// the values of AdditionalContext layered on top.
+	Context() context.Context
+
+	// WithContext replaces the context of the wrapped
+	// http.Request with the given context.  The change is
+	// visible to every Middleware called after this one.
+	//
+	// Returns the current Request instance.
+	WithContext(context.Context) Request
 }
 
 // NewRequest constructs a new instance of midl.Request
@@ -75,11 +248,43 @@ func NewRequest(r *http.Request) (Request, error) {
 }
 
 type request struct {
-	raw     *http.Request
-	error   error
-	body    []byte
-	hasBody bool
-	ctx     map[interface{}]interface{}
+	raw      *http.Request
+	error    error
+	body     []byte
+	hasBody  bool
+	streamed bool
+	query    url.Values
+	ctx      map[interface{}]interface{}
+
+	form      *form
+	formOpts  FormOptions
+	formFiles []string
+
+	compress *Compression
+}
+
+// closeRequest releases any resources held by the given
+// request, such as temporary form files.
+func closeRequest(req Request) {
+	if r, ok := req.(*request); ok {
+		r.removeFormFiles()
+	}
+}
+
+// setFormOptions applies the given form options to the
+// given request.
+func setFormOptions(req Request, opts FormOptions) {
+	if r, ok := req.(*request); ok {
+		r.formOpts = opts
+	}
+}
+
+// setCompression records the response compression applied
+// by the adapter serving the given request.
+func setCompression(req Request, c *Compression) {
+	if r, ok := req.(*request); ok {
+		r.compress = c
+	}
 }
 
 func (r *request) readBody() {
@@ -87,6 +292,11 @@ func (r *request) readBody() {
 		return
 	}
 
+	if r.streamed {
+		r.error = ErrBodyStreamed
+		return
+	}
+
 	body, err := ioutil.ReadAll(r.raw.Body)
 	if err != nil {
 		r.error = err
@@ -110,17 +320,35 @@ func (r *request) Headers(key string) ([]string, bool) {
 	return values, ok
 }
 
+func (r *request) Cookie(name string) (*http.Cookie, bool) {
+	cookie, err := r.raw.Cookie(name)
+	return cookie, err == nil
+}
+
+func (r *request) Cookies() []*http.Cookie {
+	return r.raw.Cookies()
+}
+
 func (r *request) Body() []byte {
 	r.readBody()
 	return r.body
 }
 
+func (r *request) BodyReader() io.Reader {
+	if r.hasBody {
+		return bytes.NewReader(r.body)
+	}
+
+	r.streamed = true
+	return r.raw.Body
+}
+
 func (r *request) Host() string {
 	return r.raw.Host
 }
 
 func (r *request) Parameter(key string) (string, bool) {
-	val, ok := r.raw.URL.Query()[key]
+	val, ok := r.queryValues()[key]
 	if !ok {
 		return "", false
 	}
@@ -128,10 +356,14 @@ func (r *request) Parameter(key string) (string, bool) {
 }
 
 func (r *request) Parameters(key string) ([]string, bool) {
-	val, ok := r.raw.URL.Query()[key]
+	val, ok := r.queryValues()[key]
 	return val, ok
 }
 
+func (r *request) PathParam(name string) string {
+	return r.raw.PathValue(name)
+}
+
 func (r *request) RawRequest() *http.Request {
 	return r.raw
 }
@@ -149,5 +381,28 @@ func (r *request) ProcessBody(processor BodyProcessor) Request {
 }
 
 func (r *request) AdditionalContext() map[interface{}]interface{} {
+	if r.ctx == nil {
+		r.ctx = map[interface{}]interface{}{}
+	}
+
 	return r.ctx
 }
+
+func (r *request) Context() context.Context {
+	return &valuesContext{Context: r.raw.Context(), values: r.AdditionalContext()}
+}
+
+func (r *request) WithContext(ctx context.Context) Request {
+	if ctx == nil {
+		panic("midl: nil context")
+	}
+
+	// Avoid stacking the AdditionalContext overlay when handed
+	// back the context returned by Context.
+	if vc, ok := ctx.(*valuesContext); ok {
+		ctx = vc.Context
+	}
+
+	r.raw = r.raw.WithContext(ctx)
+	return r
+}
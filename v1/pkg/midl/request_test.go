@@ -257,3 +257,34 @@ func TestNewRequest(t *testing.T) {
 		c.So(err, c.ShouldBeNil)
 	})
 }
+
+func TestRequest_BodyReader(t *testing.T) {
+	c.Convey("streams the raw body", t, func() {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar",
+			bytes.NewBufferString("streamed"))
+		test := request{raw: req}
+
+		val, _ := ioutil.ReadAll(test.BodyReader())
+		c.So(string(val), c.ShouldEqual, "streamed")
+	})
+
+	c.Convey("reads from the buffered body if already read", t, func() {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar",
+			bytes.NewBufferString("buffered"))
+		test := request{raw: req}
+		test.Body()
+
+		val, _ := ioutil.ReadAll(test.BodyReader())
+		c.So(string(val), c.ShouldEqual, "buffered")
+	})
+
+	c.Convey("records an error if Body is called after streaming", t, func() {
+		req := httptest.NewRequest(http.MethodPost, "http://foo.bar",
+			bytes.NewBufferString("streamed"))
+		test := request{raw: req}
+		test.BodyReader()
+
+		c.So(test.Body(), c.ShouldBeNil)
+		c.So(test.Error(), c.ShouldEqual, ErrBodyStreamed)
+	})
+}
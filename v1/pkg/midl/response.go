@@ -2,6 +2,7 @@ package midl
 
 import (
 	"net/http"
+	"time"
 )
 
 // Response defines a builder that can be used to build
@@ -55,6 +56,28 @@ type Response interface {
 	// this response.
 	SetHeaders(key string, values []string) Response
 
+	// SetCookie adds a Set-Cookie header for the given cookie
+	// to this response.  Invalid cookies are silently
+	// dropped, as with http.SetCookie.
+	SetCookie(cookie *http.Cookie) Response
+
+	// ClearCookie adds a Set-Cookie header instructing the
+	// client to delete the given cookie.  The cookie's Name,
+	// Path and Domain must match those it was set with; its
+	// other fields are ignored.
+	ClearCookie(cookie *http.Cookie) Response
+
+	// SetETag sets the ETag header of this response to the
+	// given opaque tag, quoting it and marking it as weak if
+	// requested.  Used by Adapters to answer conditional
+	// requests.
+	SetETag(tag string, weak bool) Response
+
+	// SetLastModified sets the Last-Modified header of this
+	// response.  Used by Adapters to answer conditional
+	// requests.
+	SetLastModified(modified time.Time) Response
+
 	// RawHeaders grants access to the internal http.Header
 	// map.
 	RawHeaders() http.Header
@@ -142,19 +165,19 @@ func (d *response) SetError(err error) Response {
 }
 
 func (d *response) AddHeader(key, value string) Response {
-	d.head.Add(key, value)
+	d.headers().Add(key, value)
 	return d
 }
 
 func (d *response) AddHeaders(key string, value []string) Response {
 	for _, v := range value {
-		d.head.Add(key, v)
+		d.headers().Add(key, v)
 	}
 	return d
 }
 
 func (d *response) SetHeader(key, value string) Response {
-	d.head.Set(key, value)
+	d.headers().Set(key, value)
 	return d
 }
 
@@ -163,10 +186,46 @@ func (d *response) SetHeaders(key string, values []string) Response {
 	return d.AddHeaders(key, values)
 }
 
+func (d *response) SetCookie(cookie *http.Cookie) Response {
+	if val := cookie.String(); val != "" {
+		d.headers().Add("Set-Cookie", val)
+	}
+	return d
+}
+
+func (d *response) ClearCookie(cookie *http.Cookie) Response {
+	return d.SetCookie(&http.Cookie{
+		Name:    cookie.Name,
+		Path:    cookie.Path,
+		Domain:  cookie.Domain,
+		Expires: time.Unix(0, 0),
+		MaxAge:  -1,
+	})
+}
+
+func (d *response) SetETag(tag string, weak bool) Response {
+	d.headers().Set("ETag", formatETag(tag, weak))
+	return d
+}
+
+func (d *response) SetLastModified(modified time.Time) Response {
+	d.headers().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	return d
+}
+
 func (d response) RawHeaders() http.Header {
 	return d.head
 }
 
+// headers returns the internal header map, creating it if
+// the response was not built by one of the constructors.
+func (d *response) headers() http.Header {
+	if d.head == nil {
+		d.head = make(http.Header)
+	}
+	return d.head
+}
+
 func (d *response) Callback(fn func()) Response {
 	d.cbs = append(d.cbs, fn)
 	return d
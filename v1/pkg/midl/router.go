@@ -0,0 +1,326 @@
+package midl
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Router is an http.Handler which dispatches requests to
+// other handlers based on the request method and path.
+//
+//   router := midl.NewRouter(midl.JSONAdapter).
+//       Route(http.MethodGet, "/users/{id}", NewUserController()).
+//       Route(http.MethodGet, "/files/{path...}", NewFileController())
+//   log.Fatal(http.ListenAndServe(":8080", router))
+//
+// Patterns are made up of "/" separated segments.  A
+// segment may be a literal, a named parameter such as
+// "{id}" which matches exactly one non-empty segment, or, as
+// the final segment only, a wildcard such as "{path...}" or
+// "*" which matches the remainder of the path.  Literal
+// segments take priority over parameters which take
+// priority over wildcards.
+//
+// Matched parameters are available from Request.PathParam.
+//
+// Requests which match no pattern receive a 404 (Not Found)
+// and requests which match a pattern registered for other
+// methods receive a 405 (Method Not Allowed) with an Allow
+// header.  OPTIONS requests are answered automatically with
+// an Allow header unless a handler is registered for them.
+// HEAD requests fall back to the handler registered for GET.
+//
+// Error and OPTIONS responses are written by an Adapter
+// built with the Router's adapter constructor, so they are
+// rendered by that Adapter's ErrorSerializer and CORS
+// preflight requests are answered by that Adapter's CORS
+// settings.
+type Router interface {
+	http.Handler
+
+	// Handle registers the given handler for requests with
+	// the given method and a path matching the given pattern.
+	//
+	// Panics if the pattern is invalid or has already been
+	// registered for the given method.
+	Handle(method, pattern string, handler http.Handler) Router
+
+	// Route registers the given list of Middleware, wrapped in
+	// an Adapter built with the Router's adapter constructor,
+	// for requests with the given method and a path matching
+	// the given pattern.
+	//
+	// Panics if the pattern is invalid or has already been
+	// registered for the given method.
+	Route(method, pattern string, handlers ...Middleware) Router
+}
+
+// NewRouter creates a new Router instance which uses the
+// given constructor to build Adapters for Middleware routes
+// and for its own error responses.
+//
+//   router := NewRouter(JSONAdapter)
+//
+// If the given constructor is nil, JSONAdapter is used.
+func NewRouter(adapter func(...Middleware) Adapter) Router {
+	if adapter == nil {
+		adapter = JSONAdapter
+	}
+
+	return &router{adapter: adapter}
+}
+
+type route struct {
+	handler http.Handler
+	names   []string
+}
+
+type routeNode struct {
+	static  map[string]*routeNode
+	param   *routeNode
+	rest    *routeNode
+	methods map[string]route
+}
+
+type router struct {
+	root    routeNode
+	adapter func(...Middleware) Adapter
+}
+
+func (r *router) Handle(method, pattern string, handler http.Handler) Router {
+	if method == "" {
+		panic("midl: empty method for pattern " + pattern)
+	}
+
+	if handler == nil {
+		panic("midl: nil handler for pattern " + pattern)
+	}
+
+	segs, names, err := parsePattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+
+	node := &r.root
+	for _, seg := range segs {
+		node = node.child(seg)
+	}
+
+	if node.methods == nil {
+		node.methods = make(map[string]route)
+	}
+
+	if _, ok := node.methods[method]; ok {
+		panic(fmt.Sprintf("midl: pattern %s already registered for %s", pattern, method))
+	}
+
+	node.methods[method] = route{handler: handler, names: names}
+	return r
+}
+
+func (r *router) Route(method, pattern string, handlers ...Middleware) Router {
+	return r.Handle(method, pattern, r.adapter(handlers...))
+}
+
+func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req == nil {
+		r.adapter().ServeHTTP(w, req)
+		return
+	}
+
+	var found route
+	var values []string
+	var matched bool
+	allowed := map[string]bool{}
+
+	r.root.walk(splitPath(req.URL.EscapedPath()), nil, func(n *routeNode, vals []string) bool {
+		if rt, ok := n.lookup(req.Method); ok {
+			found, values, matched = rt, vals, true
+			return true
+		}
+
+		for method := range n.methods {
+			allowed[method] = true
+		}
+		return false
+	})
+
+	switch {
+	case matched:
+		for i, name := range found.names {
+			if name != "" {
+				req.SetPathValue(name, values[i])
+			}
+		}
+		found.handler.ServeHTTP(w, req)
+	case len(allowed) == 0:
+		r.writeResponse(w, req, MakeErrorResponse(http.StatusNotFound, ErrNotFound))
+	case req.Method == http.MethodOptions:
+		r.writeResponse(w, req, NewResponse().
+			SetCode(http.StatusNoContent).
+			SetHeader("Allow", allowHeader(allowed)))
+	default:
+		r.writeResponse(w, req, MakeErrorResponse(http.StatusMethodNotAllowed, ErrMethodNotAllowed).
+			SetHeader("Allow", allowHeader(allowed)))
+	}
+}
+
+// writeResponse writes the given response using an Adapter
+// built with the router's adapter constructor.
+func (r *router) writeResponse(w http.ResponseWriter, req *http.Request, res Response) {
+	r.adapter(MiddlewareFunc(func(Request) Response { return res })).ServeHTTP(w, req)
+}
+
+// child returns the child node for the given pattern
+// segment, creating it if necessary.
+func (n *routeNode) child(seg string) *routeNode {
+	switch seg {
+	case "{}":
+		if n.param == nil {
+			n.param = new(routeNode)
+		}
+		return n.param
+	case "*":
+		if n.rest == nil {
+			n.rest = new(routeNode)
+		}
+		return n.rest
+	}
+
+	if n.static == nil {
+		n.static = make(map[string]*routeNode)
+	}
+
+	if _, ok := n.static[seg]; !ok {
+		n.static[seg] = new(routeNode)
+	}
+
+	return n.static[seg]
+}
+
+// lookup returns the route registered for the given method,
+// falling back to GET for HEAD requests.
+func (n *routeNode) lookup(method string) (route, bool) {
+	if rt, ok := n.methods[method]; ok {
+		return rt, true
+	}
+
+	if method == http.MethodHead {
+		rt, ok := n.methods[http.MethodGet]
+		return rt, ok
+	}
+
+	return route{}, false
+}
+
+// walk calls fn with each node with registered routes that
+// matches the given path segments, in priority order, until
+// fn returns true.
+func (n *routeNode) walk(segs, vals []string, fn func(*routeNode, []string) bool) bool {
+	if len(segs) == 0 {
+		return n.methods != nil && fn(n, vals)
+	}
+
+	if next, ok := n.static[segs[0]]; ok && next.walk(segs[1:], vals, fn) {
+		return true
+	}
+
+	if n.param != nil && segs[0] != "" && n.param.walk(segs[1:], appendValue(vals, segs[0]), fn) {
+		return true
+	}
+
+	return n.rest != nil && n.rest.methods != nil &&
+		fn(n.rest, appendValue(vals, strings.Join(segs, "/")))
+}
+
+// appendValue appends to a copy of the given slice so that
+// sibling branches of a walk do not share storage.
+func appendValue(vals []string, val string) []string {
+	return append(vals[:len(vals):len(vals)], val)
+}
+
+// parsePattern splits a route pattern into normalized
+// segments, where named parameters are replaced with "{}"
+// and wildcards with "*", and the list of parameter names.
+func parsePattern(pattern string) (segs, names []string, err error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, nil, fmt.Errorf("midl: pattern %q must begin with /", pattern)
+	}
+
+	raw := strings.Split(pattern[1:], "/")
+	seen := map[string]bool{}
+
+	for i, seg := range raw {
+		last := i == len(raw)-1
+
+		if seg == "*" {
+			if !last {
+				return nil, nil, fmt.Errorf("midl: wildcard must be the final segment in %q", pattern)
+			}
+			segs = append(segs, "*")
+			names = append(names, "")
+			continue
+		}
+
+		if !strings.HasPrefix(seg, "{") || !strings.HasSuffix(seg, "}") {
+			segs = append(segs, seg)
+			continue
+		}
+
+		name := seg[1 : len(seg)-1]
+		norm := "{}"
+		if strings.HasSuffix(name, "...") {
+			if !last {
+				return nil, nil, fmt.Errorf("midl: wildcard must be the final segment in %q", pattern)
+			}
+			name = strings.TrimSuffix(name, "...")
+			norm = "*"
+		}
+
+		if name == "" || strings.ContainsAny(name, "{}/") {
+			return nil, nil, fmt.Errorf("midl: invalid parameter %q in %q", seg, pattern)
+		}
+
+		if seen[name] {
+			return nil, nil, fmt.Errorf("midl: duplicate parameter %q in %q", name, pattern)
+		}
+
+		seen[name] = true
+		segs = append(segs, norm)
+		names = append(names, name)
+	}
+
+	return segs, names, nil
+}
+
+// splitPath splits an escaped URL path into unescaped
+// segments.
+func splitPath(path string) []string {
+	segs := strings.Split(strings.TrimPrefix(path, "/"), "/")
+
+	for i, seg := range segs {
+		if val, err := url.PathUnescape(seg); err == nil {
+			segs[i] = val
+		}
+	}
+
+	return segs
+}
+
+func allowHeader(methods map[string]bool) string {
+	if methods[http.MethodGet] {
+		methods[http.MethodHead] = true
+	}
+	methods[http.MethodOptions] = true
+
+	out := make([]string, 0, len(methods))
+	for method := range methods {
+		out = append(out, method)
+	}
+	sort.Strings(out)
+
+	return strings.Join(out, ", ")
+}
@@ -0,0 +1,191 @@
+package midl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func routerTestAdapter(next ...Middleware) Adapter {
+	return NewAdapter(
+		"text/plain",
+		SerializerFunc(func(in interface{}) ([]byte, error) {
+			return []byte(in.(string)), nil
+		}),
+		ErrorSerializerFunc(func(err error, _ Request, _ Response) []byte {
+			return []byte(err.Error())
+		}),
+		next...,
+	)
+}
+
+func echoParams(names ...string) Middleware {
+	return MiddlewareFunc(func(r Request) Response {
+		out := make([]string, len(names))
+		for i, name := range names {
+			out[i] = name + "=" + r.PathParam(name)
+		}
+		return MakeResponse(http.StatusOK, strings.Join(out, ","))
+	})
+}
+
+func serveRouter(r Router, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w
+}
+
+func TestRouter_ServeHTTP(t *testing.T) {
+	c.Convey("routes by method and path", t, func() {
+		test := NewRouter(routerTestAdapter).
+			Route(http.MethodGet, "/users", echoParams()).
+			Route(http.MethodPost, "/users", MiddlewareFunc(func(Request) Response {
+				return MakeResponse(http.StatusCreated, "created")
+			}))
+
+		w := serveRouter(test, http.MethodPost, "/users")
+		c.So(w.Code, c.ShouldEqual, http.StatusCreated)
+		c.So(w.Body.String(), c.ShouldEqual, "created")
+	})
+
+	c.Convey("exposes path parameters", t, func() {
+		test := NewRouter(routerTestAdapter).
+			Route(http.MethodGet, "/users/{id}/posts/{post}", echoParams("id", "post"))
+
+		w := serveRouter(test, http.MethodGet, "/users/a%2Fb/posts/12")
+		c.So(w.Body.String(), c.ShouldEqual, "id=a/b,post=12")
+	})
+
+	c.Convey("matches wildcards against the path remainder", t, func() {
+		test := NewRouter(routerTestAdapter).
+			Route(http.MethodGet, "/files/{path...}", echoParams("path")).
+			Route(http.MethodGet, "/static/*", echoParams())
+
+		c.So(serveRouter(test, http.MethodGet, "/files/a/b/c.txt").Body.String(),
+			c.ShouldEqual, "path=a/b/c.txt")
+		c.So(serveRouter(test, http.MethodGet, "/files/").Body.String(),
+			c.ShouldEqual, "path=")
+		c.So(serveRouter(test, http.MethodGet, "/static/x/y").Code,
+			c.ShouldEqual, http.StatusOK)
+		c.So(serveRouter(test, http.MethodGet, "/files").Code,
+			c.ShouldEqual, http.StatusNotFound)
+	})
+
+	c.Convey("prefers literals over parameters over wildcards", t, func() {
+		test := NewRouter(routerTestAdapter).
+			Route(http.MethodGet, "/a/b", MiddlewareFunc(func(Request) Response {
+				return MakeResponse(http.StatusOK, "literal")
+			})).
+			Route(http.MethodGet, "/a/{x}", MiddlewareFunc(func(Request) Response {
+				return MakeResponse(http.StatusOK, "param")
+			})).
+			Route(http.MethodGet, "/a/{x...}", MiddlewareFunc(func(Request) Response {
+				return MakeResponse(http.StatusOK, "wildcard")
+			}))
+
+		c.So(serveRouter(test, http.MethodGet, "/a/b").Body.String(), c.ShouldEqual, "literal")
+		c.So(serveRouter(test, http.MethodGet, "/a/c").Body.String(), c.ShouldEqual, "param")
+		c.So(serveRouter(test, http.MethodGet, "/a/c/d").Body.String(), c.ShouldEqual, "wildcard")
+	})
+
+	c.Convey("falls back to lower priority patterns for other methods", t, func() {
+		test := NewRouter(routerTestAdapter).
+			Route(http.MethodGet, "/a/b", echoParams()).
+			Route(http.MethodPost, "/a/{x}", echoParams("x"))
+
+		c.So(serveRouter(test, http.MethodPost, "/a/b").Body.String(), c.ShouldEqual, "x=b")
+	})
+
+	c.Convey("writes a 404 through the adapter", t, func() {
+		test := NewRouter(routerTestAdapter).Route(http.MethodGet, "/a", echoParams())
+
+		w := serveRouter(test, http.MethodGet, "/b")
+		c.So(w.Code, c.ShouldEqual, http.StatusNotFound)
+		c.So(w.Body.String(), c.ShouldEqual, ErrNotFound.Error())
+	})
+
+	c.Convey("writes a 405 with an Allow header", t, func() {
+		test := NewRouter(routerTestAdapter).
+			Route(http.MethodGet, "/a", echoParams()).
+			Route(http.MethodPut, "/a", echoParams())
+
+		w := serveRouter(test, http.MethodDelete, "/a")
+		c.So(w.Code, c.ShouldEqual, http.StatusMethodNotAllowed)
+		c.So(w.Body.String(), c.ShouldEqual, ErrMethodNotAllowed.Error())
+		c.So(w.Header().Get("Allow"), c.ShouldEqual, "GET, HEAD, OPTIONS, PUT")
+	})
+
+	c.Convey("answers OPTIONS automatically", t, func() {
+		test := NewRouter(routerTestAdapter).Route(http.MethodPost, "/a", echoParams())
+
+		w := serveRouter(test, http.MethodOptions, "/a")
+		c.So(w.Code, c.ShouldEqual, http.StatusNoContent)
+		c.So(w.Header().Get("Allow"), c.ShouldEqual, "OPTIONS, POST")
+	})
+
+	c.Convey("uses registered OPTIONS handlers", t, func() {
+		test := NewRouter(routerTestAdapter).
+			Route(http.MethodOptions, "/a", MiddlewareFunc(func(Request) Response {
+				return MakeResponse(http.StatusOK, "custom")
+			}))
+
+		c.So(serveRouter(test, http.MethodOptions, "/a").Body.String(), c.ShouldEqual, "custom")
+	})
+
+	c.Convey("serves HEAD requests with GET handlers", t, func() {
+		test := NewRouter(routerTestAdapter).Route(http.MethodGet, "/a", echoParams())
+
+		c.So(serveRouter(test, http.MethodHead, "/a").Code, c.ShouldEqual, http.StatusOK)
+	})
+
+	c.Convey("accepts plain http handlers", t, func() {
+		test := NewRouter(nil).Handle(http.MethodGet, "/a/{id}",
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(r.PathValue("id")))
+			}))
+
+		c.So(serveRouter(test, http.MethodGet, "/a/7").Body.String(), c.ShouldEqual, "7")
+	})
+}
+
+func TestRouter_Handle(t *testing.T) {
+	c.Convey("panics on invalid patterns", t, func() {
+		test := NewRouter(nil)
+		mid := echoParams()
+
+		c.So(func() { test.Route(http.MethodGet, "a", mid) }, c.ShouldPanic)
+		c.So(func() { test.Route(http.MethodGet, "/{}", mid) }, c.ShouldPanic)
+		c.So(func() { test.Route(http.MethodGet, "/{a...}/b", mid) }, c.ShouldPanic)
+		c.So(func() { test.Route(http.MethodGet, "/*/b", mid) }, c.ShouldPanic)
+		c.So(func() { test.Route(http.MethodGet, "/{a}/{a}", mid) }, c.ShouldPanic)
+		c.So(func() { test.Route("", "/a", mid) }, c.ShouldPanic)
+		c.So(func() { test.Handle(http.MethodGet, "/a", nil) }, c.ShouldPanic)
+	})
+
+	c.Convey("panics on duplicate registrations", t, func() {
+		test := NewRouter(nil).Route(http.MethodGet, "/a/{x}", echoParams())
+
+		c.So(func() { test.Route(http.MethodGet, "/a/{y}", echoParams()) }, c.ShouldPanic)
+		c.So(func() { test.Route(http.MethodPost, "/a/{y}", echoParams()) }, c.ShouldNotPanic)
+	})
+}
+
+func TestRequest_PathParam(t *testing.T) {
+	c.Convey("reads parameters matched by http.ServeMux", t, func() {
+		mux := http.NewServeMux()
+		mux.Handle("GET /things/{id}", routerTestAdapter(echoParams("id")))
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/things/42", nil))
+
+		c.So(w.Body.String(), c.ShouldEqual, "id=42")
+	})
+
+	c.Convey("returns the empty string for unknown parameters", t, func() {
+		test := request{raw: httptest.NewRequest(http.MethodGet, "http://foo.bar", nil)}
+		c.So(test.PathParam("id"), c.ShouldEqual, "")
+	})
+}
@@ -1,10 +1,6 @@
 package midl
 
-import (
-	"fmt"
-	"net/http"
-	"strconv"
-)
+import "encoding/xml"
 
 // Serializer defines a service which can be used to
 // serialize a response body into an array of bytes.
@@ -27,6 +23,25 @@ func (s SerializerFunc) Serialize(in interface{}) ([]byte, error) {
 	return s(in)
 }
 
+// SerializeError wraps an error returned by a Serializer
+// while writing a streamed response, after the status line
+// was sent.
+type SerializeError struct {
+
+	// Err is the error returned by the Serializer.
+	Err error
+}
+
+// Error describes the serialization failure.
+func (e *SerializeError) Error() string {
+	return "midl: serializing event: " + e.Err.Error()
+}
+
+// Unwrap returns the Serializer's error.
+func (e *SerializeError) Unwrap() error {
+	return e.Err
+}
+
 // ErrorSerializer defines a service which can be used to
 // serialize a given error into a byte array.
 type ErrorSerializer interface {
@@ -55,18 +70,41 @@ func (f ErrorSerializerFunc) Serialize(e error, q Request, s Response) []byte {
 // The given error will be printed in a JSON string matching
 // this pattern:
 //   `{"error":"%s"}`
-// The input error message is run through strconv.Quote for
-// escaping special characters.
+// If the error's chain contains an HTTPError, its public
+// message is used along with its code and details when set:
+//   `{"error":"%s","code":"%s","details":%v}`
+// The wrapped cause of an HTTPError is never included, and
+// recovered panics are rendered as "Internal Server Error".
+//
+// The response status code is set using ResolveStatus.
 func DefaultJSONErrorSerializer() ErrorSerializer {
 	return new(defJSONErrSerializer)
 }
 
 type defJSONErrSerializer struct{}
 
+type jsonError struct {
+	Error   string      `json:"error"`
+	Code    string      `json:"code,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+}
+
 func (d defJSONErrSerializer) Serialize(e error, _ Request, s Response) []byte {
-	s.SetCode(http.StatusInternalServerError)
+	s.SetCode(ResolveStatus(e, s.Code()))
 	s.SetHeader("Content-Type", "application/json")
-	return []byte(fmt.Sprintf(`{"error":%s}`, strconv.Quote(e.Error())))
+
+	out := jsonError{Error: e.Error()}
+	if pub := publicError(e); pub != nil {
+		out = jsonError{Error: pub.PublicMessage(), Code: pub.Code, Details: pub.Details}
+	}
+
+	body, err := marshalJSON(out)
+	if err != nil {
+		out.Details = nil
+		body, _ = marshalJSON(out)
+	}
+
+	return body
 }
 
 // DefaultXMLErrorSerializer returns an ErrorSerializer
@@ -76,15 +114,40 @@ func (d defJSONErrSerializer) Serialize(e error, _ Request, s Response) []byte {
 // The given error will be printed in an XML string matching
 // this pattern:
 //   `<error>%s</error>`
+// If the error's chain contains an HTTPError, its public
+// message is used along with its code and details when set:
+//   `<error code="%s">%s<details>%v</details></error>`
+// The wrapped cause of an HTTPError is never included, and
+// recovered panics are rendered as "Internal Server Error".
+//
+// The response status code is set using ResolveStatus.
 func DefaultXMLErrorSerializer() ErrorSerializer {
 	return new(defXMLErrSerializer)
 }
 
 type defXMLErrSerializer struct{}
 
+type xmlError struct {
+	XMLName xml.Name    `xml:"error"`
+	Code    string      `xml:"code,attr,omitempty"`
+	Message string      `xml:",chardata"`
+	Details interface{} `xml:"details,omitempty"`
+}
+
 func (d defXMLErrSerializer) Serialize(e error, _ Request, s Response) []byte {
-	s.SetCode(http.StatusInternalServerError)
+	s.SetCode(ResolveStatus(e, s.Code()))
 	s.SetHeader("Content-Type", "application/xml")
-	return []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<error>%s</error>`, e.Error()))
+
+	out := xmlError{Message: e.Error()}
+	if pub := publicError(e); pub != nil {
+		out = xmlError{Message: pub.PublicMessage(), Code: pub.Code, Details: pub.Details}
+	}
+
+	body, err := xml.Marshal(out)
+	if err != nil {
+		out.Details = nil
+		body, _ = xml.Marshal(out)
+	}
+
+	return append([]byte(xml.Header), body...)
 }
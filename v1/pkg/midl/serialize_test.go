@@ -76,3 +76,74 @@ func TestXMLErrorSerializer(t *testing.T) {
 			"<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<error>something</error>")
 	})
 }
+
+func TestJSONErrorSerializer_HTTPError(t *testing.T) {
+	c.Convey("keeps error statuses already set", t, func() {
+		res := response{code: http.StatusBadRequest}
+		DefaultJSONErrorSerializer().Serialize(errors.New("something"), nil, &res)
+		c.So(res.code, c.ShouldEqual, http.StatusBadRequest)
+	})
+
+	c.Convey("renders public error information", t, func() {
+		res := response{code: http.StatusOK}
+		err := NewHTTPError(http.StatusConflict, "<taken>").
+			WithCode("taken").
+			WithDetails(map[string]string{"field": "name"}).
+			WithCause(errors.New("internal"))
+		data := DefaultJSONErrorSerializer().Serialize(err, nil, &res)
+
+		c.So(res.code, c.ShouldEqual, http.StatusConflict)
+		c.So(string(data), c.ShouldEqual,
+			`{"error":"<taken>","code":"taken","details":{"field":"name"}}`)
+	})
+
+	c.Convey("drops details which cannot be encoded", t, func() {
+		res := response{}
+		err := NewHTTPError(http.StatusConflict, "taken").WithDetails(func() {})
+		data := DefaultJSONErrorSerializer().Serialize(err, nil, &res)
+
+		c.So(string(data), c.ShouldEqual, `{"error":"taken"}`)
+	})
+
+	c.Convey("hides recovered panic values", t, func() {
+		res := response{code: http.StatusInternalServerError}
+		err := &PanicError{Value: errors.New("runtime error: secret")}
+		data := DefaultJSONErrorSerializer().Serialize(err, nil, &res)
+
+		c.So(res.code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(string(data), c.ShouldEqual, `{"error":"Internal Server Error"}`)
+	})
+
+	c.Convey("renders HTTPErrors raised as panics", t, func() {
+		res := response{code: http.StatusInternalServerError}
+		err := &PanicError{Value: NewHTTPError(http.StatusForbidden, "denied")}
+		data := DefaultJSONErrorSerializer().Serialize(err, nil, &res)
+
+		c.So(res.code, c.ShouldEqual, http.StatusForbidden)
+		c.So(string(data), c.ShouldEqual, `{"error":"denied"}`)
+	})
+}
+
+func TestXMLErrorSerializer_HTTPError(t *testing.T) {
+	c.Convey("renders public error information", t, func() {
+		res := response{code: http.StatusOK}
+		err := NewHTTPError(http.StatusConflict, "<taken>").
+			WithCode("taken").
+			WithDetails("name").
+			WithCause(errors.New("internal"))
+		data := DefaultXMLErrorSerializer().Serialize(err, nil, &res)
+
+		c.So(res.code, c.ShouldEqual, http.StatusConflict)
+		c.So(string(data), c.ShouldEqual, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+			`<error code="taken">&lt;taken&gt;<details>name</details></error>`)
+	})
+
+	c.Convey("drops details which cannot be encoded", t, func() {
+		res := response{}
+		err := NewHTTPError(http.StatusConflict, "taken").WithDetails(map[string]string{})
+		data := DefaultXMLErrorSerializer().Serialize(err, nil, &res)
+
+		c.So(string(data), c.ShouldEqual, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+			`<error>taken</error>`)
+	})
+}
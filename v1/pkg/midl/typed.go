@@ -0,0 +1,112 @@
+package midl
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// TypedHandler is a Middleware implementation which decodes
+// the request body into a value of type In, passes it to a
+// wrapped function, and returns the function's output as
+// the response body.
+//
+//   type Input struct { Name string `json:"name"` }
+//   type Output struct { Greeting string `json:"greeting"` }
+//
+//   handler := midl.Handler(func(_ midl.Request, in Input) (Output, error) {
+//       return Output{"hello " + in.Name}, nil
+//   })
+//
+// The request body is decoded with the Decoder registered
+// for the request's Content-Type.  An unregistered
+// Content-Type results in a 415 (Unsupported Media Type)
+// error response and a body which fails to decode results
+// in a 400 (Bad Request) error response, both of which are
+// rendered by the Adapter's ErrorSerializer.
+//
+// Requests without a Content-Type header are treated as
+// "application/octet-stream" as described in RFC 9110.
+// Requests with an empty body are not decoded and the
+// wrapped function is given the zero value of In.
+type TypedHandler[In, Out any] struct {
+	fn       func(Request, In) (Out, error)
+	decoders map[string]Decoder
+	code     int
+}
+
+// Handler creates a new TypedHandler wrapping the given
+// function, using the decoders returned by DefaultDecoders
+// and a 200 (OK) success status.
+func Handler[In, Out any](fn func(Request, In) (Out, error)) *TypedHandler[In, Out] {
+	return &TypedHandler[In, Out]{
+		fn:       fn,
+		decoders: DefaultDecoders(),
+		code:     http.StatusOK,
+	}
+}
+
+// Decoder registers a Decoder for the given media type,
+// replacing any Decoder previously registered for it.
+//
+// Returns the current TypedHandler instance.
+func (t *TypedHandler[In, Out]) Decoder(mediaType string, dec Decoder) *TypedHandler[In, Out] {
+	t.decoders[mediaType] = dec
+	return t
+}
+
+// Code sets the HTTP status code used for successful
+// responses.
+//
+// Returns the current TypedHandler instance.
+func (t *TypedHandler[In, Out]) Code(code int) *TypedHandler[In, Out] {
+	t.code = code
+	return t
+}
+
+// Handle decodes the request body and calls the wrapped
+// function.
+//
+// If the wrapped function returns an error, the response
+// will be a 500 error response wrapping that error.
+func (t *TypedHandler[In, Out]) Handle(req Request) Response {
+	var in In
+
+	if res := t.decode(req, &in); res != nil {
+		return res
+	}
+
+	out, err := t.fn(req, in)
+	if err != nil {
+		return MakeErrorResponse(http.StatusInternalServerError, err)
+	}
+
+	return MakeResponse(t.code, out)
+}
+
+func (t *TypedHandler[In, Out]) decode(req Request, in *In) Response {
+	body := req.Body()
+	if req.Error() != nil {
+		return MakeErrorResponse(http.StatusBadRequest, req.Error())
+	}
+
+	if len(body) == 0 {
+		return nil
+	}
+
+	contentType, ok := req.Header("Content-Type")
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+
+	dec, ok := findDecoder(t.decoders, contentType)
+	if !ok {
+		return MakeErrorResponse(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType)
+	}
+
+	if err := dec.Decode(body, in); err != nil {
+		return MakeErrorResponse(http.StatusBadRequest,
+			fmt.Errorf("invalid request body: %w", err))
+	}
+
+	return nil
+}
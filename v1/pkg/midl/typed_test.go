@@ -0,0 +1,121 @@
+package midl
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+type typedInput struct {
+	Name string `json:"name" xml:"name"`
+}
+
+type typedOutput struct {
+	Greeting string
+}
+
+func typedTestRequest(contentType, body string) Request {
+	raw := httptest.NewRequest(http.MethodPost, "http://foo.bar", bytes.NewBufferString(body))
+	if contentType != "" {
+		raw.Header.Set("Content-Type", contentType)
+	}
+	req, _ := NewRequest(raw)
+	return req
+}
+
+func greet(_ Request, in typedInput) (typedOutput, error) {
+	return typedOutput{Greeting: "hello " + in.Name}, nil
+}
+
+func TestTypedHandler_Handle(t *testing.T) {
+	c.Convey("decodes JSON bodies", t, func() {
+		res := Handler(greet).Handle(typedTestRequest("application/json", `{"name":"foo"}`))
+
+		c.So(res.Error(), c.ShouldBeNil)
+		c.So(res.Code(), c.ShouldEqual, http.StatusOK)
+		c.So(res.Body(), c.ShouldResemble, typedOutput{Greeting: "hello foo"})
+	})
+
+	c.Convey("decodes XML bodies", t, func() {
+		res := Handler(greet).
+			Handle(typedTestRequest("application/xml; charset=utf-8", `<in><name>bar</name></in>`))
+
+		c.So(res.Body(), c.ShouldResemble, typedOutput{Greeting: "hello bar"})
+	})
+
+	c.Convey("decodes structured syntax suffix types", t, func() {
+		res := Handler(greet).Handle(typedTestRequest("application/vnd.foo+json", `{"name":"baz"}`))
+
+		c.So(res.Body(), c.ShouldResemble, typedOutput{Greeting: "hello baz"})
+	})
+
+	c.Convey("skips decoding empty bodies", t, func() {
+		res := Handler(greet).Handle(typedTestRequest("", ""))
+
+		c.So(res.Body(), c.ShouldResemble, typedOutput{Greeting: "hello "})
+	})
+
+	c.Convey("uses registered decoders", t, func() {
+		res := Handler(greet).
+			Decoder("text/plain", DecoderFunc(func(in []byte, out interface{}) error {
+				out.(*typedInput).Name = string(in)
+				return nil
+			})).
+			Handle(typedTestRequest("text/plain", "qux"))
+
+		c.So(res.Body(), c.ShouldResemble, typedOutput{Greeting: "hello qux"})
+	})
+
+	c.Convey("uses the configured status code", t, func() {
+		res := Handler(greet).Code(http.StatusCreated).
+			Handle(typedTestRequest("application/json", `{}`))
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusCreated)
+	})
+
+	c.Convey("rejects unknown content types", t, func() {
+		res := Handler(greet).Handle(typedTestRequest("text/csv", "a,b"))
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusUnsupportedMediaType)
+		c.So(res.Error(), c.ShouldEqual, ErrUnsupportedMediaType)
+	})
+
+	c.Convey("rejects bodies without a content type", t, func() {
+		res := Handler(greet).Handle(typedTestRequest("", `{"name":"foo"}`))
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusUnsupportedMediaType)
+	})
+
+	c.Convey("rejects malformed bodies", t, func() {
+		res := Handler(greet).Handle(typedTestRequest("application/json", `{"name":`))
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusBadRequest)
+		c.So(res.Error().Error(), c.ShouldStartWith, "invalid request body: ")
+	})
+
+	c.Convey("returns handler errors", t, func() {
+		err := errors.New("handler error")
+		res := Handler(func(Request, typedInput) (*typedOutput, error) {
+			return nil, err
+		}).Handle(typedTestRequest("application/json", `{}`))
+
+		c.So(res.Code(), c.ShouldEqual, http.StatusInternalServerError)
+		c.So(res.Error(), c.ShouldEqual, err)
+	})
+}
+
+func TestFindDecoder(t *testing.T) {
+	c.Convey("does not match malformed content types", t, func() {
+		_, ok := findDecoder(DefaultDecoders(), "application/")
+		c.So(ok, c.ShouldBeFalse)
+	})
+
+	c.Convey("does not match unregistered suffixes", t, func() {
+		_, ok := findDecoder(DefaultDecoders(), "application/vnd.foo+yaml")
+		c.So(ok, c.ShouldBeFalse)
+	})
+}
@@ -0,0 +1,95 @@
+package midl
+
+import (
+	"net/http"
+	"strings"
+)
+
+// FieldError describes a single invalid input value.
+type FieldError struct {
+
+	// In names the part of the request the value came from,
+	// such as "query", "header", "path", "form" or "body".
+	In string `json:"in,omitempty" xml:"in,attr,omitempty"`
+
+	// Field identifies the invalid value within its source,
+	// such as a parameter name or a JSON pointer.
+	Field string `json:"field" xml:"field,attr"`
+
+	// Message describes why the value is invalid.
+	Message string `json:"message" xml:",chardata"`
+}
+
+// ValidationError is an error aggregating one or more
+// invalid input values.
+//
+// The serializers provided by this package render the
+// individual FieldErrors as the error details (or the
+// "errors" extension member for problem documents).
+type ValidationError struct {
+
+	// Status is the HTTP status code for this error.  If
+	// zero, 400 (Bad Request) is used.
+	Status int
+
+	// Fields is the list of invalid input values.
+	Fields []FieldError
+}
+
+// Add appends a FieldError to this ValidationError.
+//
+// Returns the current ValidationError instance.
+func (v *ValidationError) Add(in, field, message string) *ValidationError {
+	v.Fields = append(v.Fields, FieldError{In: in, Field: field, Message: message})
+	return v
+}
+
+// Empty returns whether this ValidationError contains no
+// FieldErrors.
+func (v *ValidationError) Empty() bool {
+	return len(v.Fields) == 0
+}
+
+// Error returns a description of every invalid value.
+func (v *ValidationError) Error() string {
+	parts := make([]string, len(v.Fields))
+
+	for i, field := range v.Fields {
+		name := field.Field
+		if field.In != "" {
+			name = field.In + " " + name
+		}
+		parts[i] = name + ": " + field.Message
+	}
+
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// StatusCode returns the HTTP status code for this error.
+func (v *ValidationError) StatusCode() int {
+	if v.Status == 0 {
+		return http.StatusBadRequest
+	}
+	return v.Status
+}
+
+// Problem returns problem details describing this error with
+// the FieldErrors in the "errors" extension member.
+func (v *ValidationError) Problem() ProblemDetails {
+	return ProblemDetails{
+		Status:     v.StatusCode(),
+		Detail:     "the request contains invalid values",
+		Extensions: map[string]interface{}{"errors": v.Fields},
+	}
+}
+
+// httpError converts this ValidationError into the public
+// HTTPError rendered by the default serializers.
+func (v *ValidationError) httpError() *HTTPError {
+	return &HTTPError{
+		Status:  v.StatusCode(),
+		Message: "the request contains invalid values",
+		Code:    "invalid_request",
+		Details: v.Fields,
+	}
+}
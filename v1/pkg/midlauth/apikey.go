@@ -0,0 +1,111 @@
+package midlauth
+
+import (
+	"net/http"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+// DefaultAPIKeyHeader is the header API keys are read from
+// when no other source is configured.
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// APIKeyAuth is a midl.Middleware authenticating requests
+// with API keys sent in a header or query parameter.
+type APIKeyAuth struct {
+	authenticator
+
+	verifier TokenVerifier
+	headers  []string
+	queries  []string
+}
+
+// NewAPIKey creates a new APIKeyAuth for the given realm,
+// checking keys with the given verifier.
+//
+// Keys are read from the DefaultAPIKeyHeader header unless
+// other sources are set with Header or Query.  Challenges
+// use the "APIKey" scheme, naming the header keys are read
+// from.
+func NewAPIKey(realm string, verifier TokenVerifier) *APIKeyAuth {
+	return &APIKeyAuth{
+		authenticator: authenticator{scheme: "APIKey", realm: realm},
+		verifier:      verifier,
+	}
+}
+
+// Header adds a request header API keys are read from.
+// Headers are read in the order they were added, before any
+// query parameter.
+func (a *APIKeyAuth) Header(name string) *APIKeyAuth {
+	a.headers = append(a.headers, name)
+	return a
+}
+
+// Query adds a query parameter API keys are read from.
+//
+// Keys sent in URLs are likely to be recorded in logs, so
+// headers should be preferred where clients allow.
+func (a *APIKeyAuth) Query(name string) *APIKeyAuth {
+	a.queries = append(a.queries, name)
+	return a
+}
+
+// Optional sets whether requests without an API key are
+// passed on unauthenticated instead of being rejected.
+func (a *APIKeyAuth) Optional(optional bool) *APIKeyAuth {
+	a.optional = optional
+	return a
+}
+
+// Handle authenticates the given request, unless an earlier
+// authenticator already has.
+func (a *APIKeyAuth) Handle(req midl.Request) midl.Response {
+	if _, ok := FromRequest(req); ok {
+		return nil
+	}
+
+	key, ok := a.key(req)
+	if !ok {
+		return a.missing("header", a.header())
+	}
+
+	p, err := a.verifier.Verify(req.Context(), key)
+	if err != nil || p == nil {
+		return a.reject(err, "header", a.header())
+	}
+
+	return a.accept(req, p)
+}
+
+func (a *APIKeyAuth) key(req midl.Request) (string, bool) {
+	headers := a.headers
+	if len(headers) == 0 && len(a.queries) == 0 {
+		headers = []string{DefaultAPIKeyHeader}
+	}
+
+	for _, name := range headers {
+		if val, _ := req.Header(http.CanonicalHeaderKey(name)); val != "" {
+			return val, true
+		}
+	}
+
+	for _, name := range a.queries {
+		if val, _ := req.Parameter(name); val != "" {
+			return val, true
+		}
+	}
+
+	return "", false
+}
+
+// header returns the header named in challenges.
+func (a *APIKeyAuth) header() string {
+	if len(a.headers) > 0 {
+		return a.headers[0]
+	}
+	if len(a.queries) > 0 {
+		return ""
+	}
+	return DefaultAPIKeyHeader
+}
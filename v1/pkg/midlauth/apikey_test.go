@@ -0,0 +1,63 @@
+package midlauth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+func TestAPIKey(t *testing.T) {
+	keys := TokenVerifierFunc(func(_ context.Context, key string) (*Principal, error) {
+		if key != "k3y" {
+			return nil, nil
+		}
+		return &Principal{ID: "client", Roles: []string{"read"}}, nil
+	})
+
+	c.Convey("reads keys from the default header", t, func() {
+		w := serve(midl.JSONAdapter(NewAPIKey("api", keys), whoami), func(r *http.Request) {
+			r.Header.Set("X-API-Key", "k3y")
+		})
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, `"APIKey:client"`)
+	})
+
+	c.Convey("reads keys from configured headers and query parameters", t, func() {
+		h := midl.JSONAdapter(NewAPIKey("api", keys).Header("X-Token").Query("key"), whoami)
+
+		c.So(serve(h, func(r *http.Request) { r.Header.Set("X-Token", "k3y") }).Code, c.ShouldEqual, http.StatusOK)
+		c.So(serve(h, func(r *http.Request) { r.URL.RawQuery = "key=k3y" }).Code, c.ShouldEqual, http.StatusOK)
+		c.So(serve(h, func(r *http.Request) { r.Header.Set("X-API-Key", "k3y") }).Code, c.ShouldEqual, http.StatusUnauthorized)
+	})
+
+	c.Convey("challenges requests without a valid key", t, func() {
+		h := midl.JSONAdapter(NewAPIKey("api", keys), whoami)
+
+		missing := serve(h, nil)
+		c.So(missing.Code, c.ShouldEqual, http.StatusUnauthorized)
+		c.So(missing.Header().Get("WWW-Authenticate"), c.ShouldEqual, `APIKey realm="api", header="X-API-Key"`)
+
+		invalid := serve(h, func(r *http.Request) { r.Header.Set("X-API-Key", "nope") })
+		c.So(invalid.Code, c.ShouldEqual, http.StatusUnauthorized)
+		c.So(invalid.Body.String(), c.ShouldEqual, `{"error":"invalid credentials"}`)
+
+		queryOnly := serve(midl.JSONAdapter(NewAPIKey("api", keys).Query("key"), whoami), nil)
+		c.So(queryOnly.Header().Get("WWW-Authenticate"), c.ShouldEqual, `APIKey realm="api"`)
+	})
+
+	c.Convey("stores a copy of the verified principal", t, func() {
+		shared := &Principal{ID: "shared"}
+		h := midl.JSONAdapter(NewAPIKey("api", TokenVerifierFunc(func(context.Context, string) (*Principal, error) {
+			return shared, nil
+		})), whoami)
+
+		w := serve(h, func(r *http.Request) { r.Header.Set("X-API-Key", "any") })
+		c.So(w.Body.String(), c.ShouldEqual, `"APIKey:shared"`)
+		c.So(shared.Scheme, c.ShouldEqual, "")
+	})
+}
@@ -0,0 +1,58 @@
+package midlauth
+
+import "github.com/vulpine-io/midl/v1/pkg/midl"
+
+// CredentialFunc looks up the password expected for the
+// given user name.
+//
+// Returns false if the user is unknown.
+type CredentialFunc func(username string) (password string, ok bool)
+
+// BasicAuth is a midl.Middleware authenticating requests
+// with HTTP Basic credentials.
+type BasicAuth struct {
+	authenticator
+
+	lookup CredentialFunc
+}
+
+// Basic creates a new BasicAuth for the given realm, checking
+// credentials against the passwords returned by the given
+// lookup function.
+//
+// Passwords are compared in constant time, and unknown users
+// take as long to reject as wrong passwords.  On success the
+// Principal's ID is the user name.
+func Basic(realm string, lookup CredentialFunc) *BasicAuth {
+	return &BasicAuth{
+		authenticator: authenticator{scheme: "Basic", realm: realm},
+		lookup:        lookup,
+	}
+}
+
+// Optional sets whether requests without Basic credentials
+// are passed on unauthenticated instead of being rejected.
+func (b *BasicAuth) Optional(optional bool) *BasicAuth {
+	b.optional = optional
+	return b
+}
+
+// Handle authenticates the given request, unless an earlier
+// authenticator already has.
+func (b *BasicAuth) Handle(req midl.Request) midl.Response {
+	if _, ok := FromRequest(req); ok {
+		return nil
+	}
+
+	user, pass, ok := req.RawRequest().BasicAuth()
+	if !ok {
+		return b.missing("charset", "UTF-8")
+	}
+
+	expect, known := b.lookup(user)
+	if !secretsEqual(pass, expect) || !known {
+		return b.reject(nil, "charset", "UTF-8")
+	}
+
+	return b.accept(req, &Principal{ID: user})
+}
@@ -0,0 +1,92 @@
+package midlauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+// whoami responds with the ID and scheme of the request's
+// Principal, or "anonymous".
+var whoami = midl.MiddlewareFunc(func(req midl.Request) midl.Response {
+	if p, ok := FromRequest(req); ok {
+		return midl.MakeResponse(http.StatusOK, p.Scheme+":"+p.ID)
+	}
+	return midl.MakeResponse(http.StatusOK, "anonymous")
+})
+
+func serve(h http.Handler, edit func(*http.Request)) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://foo.bar/", nil)
+	if edit != nil {
+		edit(r)
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestBasic(t *testing.T) {
+	lookup := func(user string) (string, bool) {
+		if user == "alice" {
+			return "secret", true
+		}
+		return "", false
+	}
+
+	c.Convey("accepts valid credentials", t, func() {
+		w := serve(midl.JSONAdapter(Basic("admin", lookup), whoami), func(r *http.Request) {
+			r.SetBasicAuth("alice", "secret")
+		})
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, `"Basic:alice"`)
+	})
+
+	c.Convey("challenges requests without credentials", t, func() {
+		w := serve(midl.JSONAdapter(Basic("admin", lookup), whoami), nil)
+
+		c.So(w.Code, c.ShouldEqual, http.StatusUnauthorized)
+		c.So(w.Body.String(), c.ShouldEqual, `{"error":"authentication required"}`)
+		c.So(w.Header().Get("WWW-Authenticate"), c.ShouldEqual, `Basic realm="admin", charset="UTF-8"`)
+	})
+
+	c.Convey("rejects invalid credentials", t, func() {
+		for _, creds := range [][2]string{{"alice", "wrong"}, {"bob", ""}, {"alice", ""}} {
+			w := serve(midl.JSONAdapter(Basic(`the "admin" realm`, lookup), whoami), func(r *http.Request) {
+				r.SetBasicAuth(creds[0], creds[1])
+			})
+
+			c.So(w.Code, c.ShouldEqual, http.StatusUnauthorized)
+			c.So(w.Body.String(), c.ShouldEqual, `{"error":"invalid credentials"}`)
+			c.So(w.Header().Get("WWW-Authenticate"), c.ShouldEqual, `Basic realm="the \"admin\" realm", charset="UTF-8"`)
+		}
+	})
+
+	c.Convey("passes requests without credentials on when optional", t, func() {
+		h := midl.JSONAdapter(Basic("admin", lookup).Optional(true), whoami)
+
+		c.So(serve(h, nil).Body.String(), c.ShouldEqual, `"anonymous"`)
+		c.So(serve(h, func(r *http.Request) { r.SetBasicAuth("alice", "wrong") }).Code,
+			c.ShouldEqual, http.StatusUnauthorized)
+	})
+}
+
+func TestPrincipal(t *testing.T) {
+	c.Convey("reports roles", t, func() {
+		p := &Principal{Roles: []string{"admin", "user"}}
+
+		c.So(p.HasRole("admin"), c.ShouldBeTrue)
+		c.So(p.HasRole("owner"), c.ShouldBeFalse)
+	})
+
+	c.Convey("is absent from unauthenticated requests", t, func() {
+		req, _ := midl.NewRequest(httptest.NewRequest(http.MethodGet, "http://foo.bar/", nil))
+
+		_, ok := FromRequest(req)
+		c.So(ok, c.ShouldBeFalse)
+	})
+}
@@ -0,0 +1,70 @@
+package midlauth
+
+import (
+	"strings"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+// BearerAuth is a midl.Middleware authenticating requests
+// with Bearer tokens sent in the Authorization header.
+type BearerAuth struct {
+	authenticator
+
+	verifier TokenVerifier
+}
+
+// Bearer creates a new BearerAuth for the given realm,
+// checking tokens with the given verifier.
+//
+// Rejected tokens are challenged with an "invalid_token"
+// error as described by RFC 6750.
+func Bearer(realm string, verifier TokenVerifier) *BearerAuth {
+	return &BearerAuth{
+		authenticator: authenticator{scheme: "Bearer", realm: realm},
+		verifier:      verifier,
+	}
+}
+
+// Optional sets whether requests without a Bearer token are
+// passed on unauthenticated instead of being rejected.
+func (b *BearerAuth) Optional(optional bool) *BearerAuth {
+	b.optional = optional
+	return b
+}
+
+// Handle authenticates the given request, unless an earlier
+// authenticator already has.
+func (b *BearerAuth) Handle(req midl.Request) midl.Response {
+	if _, ok := FromRequest(req); ok {
+		return nil
+	}
+
+	token, ok := BearerToken(req)
+	if !ok {
+		return b.missing()
+	}
+
+	p, err := b.verifier.Verify(req.Context(), token)
+	if err != nil || p == nil {
+		return b.reject(err, "error", "invalid_token")
+	}
+
+	return b.accept(req, p)
+}
+
+// BearerToken returns the Bearer token sent in the
+// Authorization header of the given request.
+//
+// Returns false if the request has no Bearer token.
+func BearerToken(req midl.Request) (string, bool) {
+	auth, _ := req.Header("Authorization")
+
+	scheme, token, ok := strings.Cut(strings.TrimSpace(auth), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
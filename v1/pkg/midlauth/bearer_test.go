@@ -0,0 +1,96 @@
+package midlauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+func TestBearer(t *testing.T) {
+	tokens := StaticTokens(map[string]string{"t0k3n": "svc"})
+
+	c.Convey("accepts valid tokens", t, func() {
+		w := serve(midl.JSONAdapter(Bearer("api", tokens), whoami), func(r *http.Request) {
+			r.Header.Set("Authorization", "bearer  t0k3n ")
+		})
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, `"Bearer:svc"`)
+	})
+
+	c.Convey("challenges requests without a token", t, func() {
+		for _, auth := range []string{"", "Basic abc", "Bearer ", "Bearer"} {
+			w := serve(midl.JSONAdapter(Bearer("api", tokens), whoami), func(r *http.Request) {
+				r.Header.Set("Authorization", auth)
+			})
+
+			c.So(w.Code, c.ShouldEqual, http.StatusUnauthorized)
+			c.So(w.Header().Get("WWW-Authenticate"), c.ShouldEqual, `Bearer realm="api"`)
+		}
+	})
+
+	c.Convey("rejects invalid tokens", t, func() {
+		w := serve(midl.JSONAdapter(Bearer("api", tokens), whoami), func(r *http.Request) {
+			r.Header.Set("Authorization", "Bearer nope")
+		})
+
+		c.So(w.Code, c.ShouldEqual, http.StatusUnauthorized)
+		c.So(w.Body.String(), c.ShouldEqual, `{"error":"invalid credentials"}`)
+		c.So(w.Header().Get("WWW-Authenticate"), c.ShouldEqual, `Bearer realm="api", error="invalid_token"`)
+	})
+
+	c.Convey("reports verifier errors", t, func() {
+		var cause error
+		h := func(err error) http.Handler {
+			return midl.JSONAdapter(Bearer("api", TokenVerifierFunc(func(context.Context, string) (*Principal, error) {
+				return nil, err
+			})), whoami).ErrorSerializer(midl.ErrorSerializerFunc(func(e error, _ midl.Request, _ midl.Response) []byte {
+				cause = e
+				return nil
+			}))
+		}
+
+		expired := errors.New("token expired")
+		w := serve(h(expired), func(r *http.Request) { r.Header.Set("Authorization", "Bearer x") })
+		c.So(w.Code, c.ShouldEqual, http.StatusUnauthorized)
+		c.So(errors.Is(cause, expired), c.ShouldBeTrue)
+		c.So(errors.Is(cause, ErrInvalidCredentials), c.ShouldBeTrue)
+
+		down := midl.NewHTTPError(http.StatusServiceUnavailable, "unavailable")
+		w = serve(h(down), func(r *http.Request) { r.Header.Set("Authorization", "Bearer x") })
+		c.So(w.Code, c.ShouldEqual, http.StatusServiceUnavailable)
+		c.So(w.Header().Get("WWW-Authenticate"), c.ShouldEqual, "")
+	})
+
+	c.Convey("chains optional authenticators", t, func() {
+		h := midl.JSONAdapter(
+			Bearer("api", tokens).Optional(true),
+			NewAPIKey("api", StaticTokens(map[string]string{"k3y": "client"})),
+			whoami,
+		)
+
+		c.So(serve(h, func(r *http.Request) { r.Header.Set("Authorization", "Bearer t0k3n") }).Body.String(),
+			c.ShouldEqual, `"Bearer:svc"`)
+		c.So(serve(h, func(r *http.Request) { r.Header.Set("X-Api-Key", "k3y") }).Body.String(),
+			c.ShouldEqual, `"APIKey:client"`)
+		c.So(serve(h, nil).Code, c.ShouldEqual, http.StatusUnauthorized)
+	})
+}
+
+func TestStaticTokens(t *testing.T) {
+	c.Convey("maps tokens to principals", t, func() {
+		verifier := StaticTokens(map[string]string{"a": "first", "b": "second"})
+
+		p, err := verifier.Verify(context.Background(), "b")
+		c.So(err, c.ShouldBeNil)
+		c.So(p.ID, c.ShouldEqual, "second")
+
+		_, err = verifier.Verify(context.Background(), "c")
+		c.So(err, c.ShouldEqual, ErrInvalidCredentials)
+	})
+}
@@ -0,0 +1,51 @@
+/*
+Package midlauth provides midl.Middleware implementations
+which authenticate requests using HTTP Basic credentials,
+Bearer tokens or API keys.
+
+Usage
+
+Each authenticator reads the credentials of its scheme from
+the request, verifies them and, on success, stores a
+*Principal in the request context before passing the
+request on to the next Middleware.
+
+  users := midlauth.Basic("admin", func(user string) (string, bool) {
+      pass, ok := passwords[user]
+      return pass, ok
+  })
+
+  router.Route(http.MethodGet, "/admin", users, NewAdminController())
+
+Downstream Middleware read the Principal with FromRequest:
+
+  func (c AdminController) Handle(req midl.Request) midl.Response {
+      user, _ := midlauth.FromRequest(req)
+      ...
+  }
+
+Requests without valid credentials are answered with a 401
+(Unauthorized) rendered by the Adapter's ErrorSerializer,
+with a WWW-Authenticate header challenging the client to
+authenticate with the authenticator's scheme.
+
+Authenticators pass on requests already authenticated by an
+earlier one, and optional authenticators pass on requests
+without credentials unauthenticated, so that several
+schemes may be chained:
+
+  midl.JSONAdapter(
+      midlauth.Bearer("api", tokens).Optional(true),
+      midlauth.NewAPIKey("api", keys),
+      NewController(),
+  )
+
+Bearer tokens and API keys are checked by a TokenVerifier.
+StaticTokens verifies a fixed set of tokens, and
+midljwt.Verifier validates signed JSON Web Tokens.  Other
+verifiers may be provided by implementing the interface.
+
+The Principal's ID may be used to rate limit authenticated
+clients with midlrate.ByValue and PrincipalKey.
+*/
+package midlauth
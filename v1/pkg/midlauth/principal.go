@@ -0,0 +1,153 @@
+package midlauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+var (
+	// ErrNoCredentials is the cause of the errors returned
+	// for requests without credentials.
+	ErrNoCredentials = errors.New("authentication required")
+
+	// ErrInvalidCredentials is the cause of the errors
+	// returned for requests with credentials which could not
+	// be verified.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal describes an authenticated client.
+type Principal struct {
+
+	// ID identifies the user, client or key authenticated.
+	ID string
+
+	// Scheme is the authentication scheme used, such as
+	// "Basic", "Bearer" or "APIKey".
+	Scheme string
+
+	// Roles lists the roles granted to the client.
+	Roles []string
+
+	// Attributes holds additional details provided when
+	// verifying the credentials.
+	Attributes map[string]interface{}
+}
+
+// HasRole returns whether the Principal was granted the
+// given role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// PrincipalKey holds the Principal of authenticated
+// requests.
+var PrincipalKey = midl.NewContextKey[*Principal]("midlauth.principal")
+
+// FromRequest returns the Principal stored on the given
+// request by an authenticator.
+//
+// Returns false if the request was not authenticated.
+func FromRequest(req midl.Request) (*Principal, bool) {
+	p, ok := PrincipalKey.Get(req)
+	return p, ok && p != nil
+}
+
+// authenticator holds the settings shared by every scheme.
+type authenticator struct {
+	scheme   string
+	realm    string
+	optional bool
+}
+
+// accept stores a copy of the given principal on the
+// request, filling in the scheme if unset.
+func (a *authenticator) accept(req midl.Request, p *Principal) midl.Response {
+	out := *p
+	if out.Scheme == "" {
+		out.Scheme = a.scheme
+	}
+
+	PrincipalKey.Set(req, &out)
+	return nil
+}
+
+// missing answers requests without credentials, unless the
+// authenticator is optional, with the given challenge
+// parameters.
+func (a *authenticator) missing(params ...string) midl.Response {
+	if a.optional {
+		return nil
+	}
+
+	return a.challenge(ErrNoCredentials, params...)
+}
+
+// reject answers requests whose credentials failed
+// verification with the given error and challenge
+// parameters.
+//
+// Errors carrying a status other than 401 through
+// midl.StatusCoder, such as an unavailable backend, are
+// passed on as they are.
+func (a *authenticator) reject(err error, params ...string) midl.Response {
+	if err == nil {
+		err = ErrInvalidCredentials
+	} else if !errors.Is(err, ErrInvalidCredentials) {
+		if status := midl.ResolveStatus(err, http.StatusUnauthorized); status != http.StatusUnauthorized {
+			return midl.MakeErrorResponse(status, err)
+		}
+		err = fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	return a.challenge(err, params...)
+}
+
+// challenge returns a 401 error response for the given error
+// with a WWW-Authenticate header carrying the given
+// additional name and value parameters.
+func (a *authenticator) challenge(err error, params ...string) midl.Response {
+	msg := ErrInvalidCredentials.Error()
+	if errors.Is(err, ErrNoCredentials) {
+		msg = ErrNoCredentials.Error()
+	}
+
+	head := a.scheme + ` realm="` + quote(a.realm) + `"`
+	for i := 0; i+1 < len(params); i += 2 {
+		if params[i+1] != "" {
+			head += ", " + params[i] + `="` + quote(params[i+1]) + `"`
+		}
+	}
+
+	return midl.MakeErrorResponse(http.StatusUnauthorized,
+		midl.NewHTTPError(http.StatusUnauthorized, msg).WithCause(err)).
+		SetHeader("WWW-Authenticate", head)
+}
+
+// quote escapes the given value for use in a quoted string.
+func quote(val string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(val)
+}
+
+// digest returns the SHA-256 digest of the given secret,
+// used to compare secrets of any length in constant time.
+func digest(secret string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(secret))
+}
+
+// secretsEqual compares the given secrets in constant time.
+func secretsEqual(a, b string) bool {
+	da, db := digest(a), digest(b)
+	return subtle.ConstantTimeCompare(da[:], db[:]) == 1
+}
@@ -0,0 +1,45 @@
+/*
+Package midljwt provides a midl.Middleware authenticating
+requests with JSON Web Tokens sent as Bearer tokens.
+
+Usage
+
+A Verifier checks the signature of tokens, signed with
+HS256, HS384, HS512, RS256 or ES256, against the keys of a
+KeySource, then checks their "exp", "nbf" and "iat" claims,
+allowing for clock skew, and optionally their issuer and
+audience.
+
+  keys := midljwt.NewJWKSURL("http://auth.internal/.well-known/jwks.json")
+
+  verifier := midljwt.NewVerifier(keys).
+      Issuer("https://auth.example.com").
+      Audience("orders")
+
+  router.Route(http.MethodGet, "/orders", midljwt.New("orders", verifier), NewOrderController())
+
+StaticKeys provides fixed keys, such as an HMAC secret, and
+a JWKS reads a JSON Web Key Set from a file or an HTTP
+endpoint, caching it and reloading it when a token names an
+unknown key ID.
+
+Downstream Middleware read the verified claims with
+FromRequest, decoding application claims with Decode:
+
+  func (c OrderController) Handle(req midl.Request) midl.Response {
+      claims, _ := midljwt.FromRequest(req)
+
+      var scope struct {
+          Roles []string `json:"roles"`
+      }
+      if err := claims.Decode(&scope); err != nil {
+          ...
+      }
+  }
+
+The Middleware also stores a midlauth.Principal identified
+by the "sub" claim, and rejects requests as midlauth.Bearer
+does.  The Verifier may also be passed to midlauth.Bearer
+directly when the claims are not needed.
+*/
+package midljwt
@@ -0,0 +1,314 @@
+package midljwt
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+const (
+	// DefaultJWKSRefresh is the default interval after which a
+	// JWKS reloads its key set.
+	DefaultJWKSRefresh = time.Hour
+
+	// DefaultJWKSMinRefresh is the default minimum interval
+	// between two loads of a JWKS, limiting the reloads
+	// triggered by unknown key IDs or failures.
+	DefaultJWKSMinRefresh = time.Minute
+
+	// maxJWKSSize is the largest key set document read.
+	maxJWKSSize = 1 << 20
+)
+
+// JWKS is a KeySource providing the keys of a JSON Web Key
+// Set read from a file or an HTTP endpoint.
+//
+// The key set is cached and reloaded periodically, and
+// immediately when a token names an unknown key ID, so that
+// rotated keys are picked up.  If a reload fails, the keys
+// loaded last remain in use.
+type JWKS struct {
+	path   string
+	url    string
+	client *http.Client
+
+	refresh    time.Duration
+	minRefresh time.Duration
+
+	mut     sync.Mutex
+	keys    []Key
+	err     error
+	loaded  time.Time
+	tried   time.Time
+	loading chan struct{}
+
+	now func() time.Time
+}
+
+// NewJWKSFile creates a new JWKS reading the key set from
+// the file at the given path.
+func NewJWKSFile(path string) *JWKS {
+	return newJWKS(&JWKS{path: path})
+}
+
+// NewJWKSURL creates a new JWKS fetching the key set from the
+// given URL.
+//
+//   keys := midljwt.NewJWKSURL("http://auth.internal/.well-known/jwks.json")
+func NewJWKSURL(url string) *JWKS {
+	return newJWKS(&JWKS{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	})
+}
+
+func newJWKS(j *JWKS) *JWKS {
+	j.refresh = DefaultJWKSRefresh
+	j.minRefresh = DefaultJWKSMinRefresh
+	j.now = time.Now
+	return j
+}
+
+// Client sets the http.Client used to fetch the key set.
+func (j *JWKS) Client(client *http.Client) *JWKS {
+	j.client = client
+	return j
+}
+
+// Refresh sets the interval after which the key set is
+// reloaded.  Defaults to DefaultJWKSRefresh.
+func (j *JWKS) Refresh(refresh time.Duration) *JWKS {
+	j.refresh = refresh
+	return j
+}
+
+// MinRefresh sets the minimum interval between two loads of
+// the key set.  Defaults to DefaultJWKSMinRefresh.
+func (j *JWKS) MinRefresh(min time.Duration) *JWKS {
+	j.minRefresh = min
+	return j
+}
+
+// Keys returns the keys of the key set which may have signed
+// a token with the given key ID, loading the key set if it
+// is stale or does not hold the key.
+//
+// Only one load runs at a time.  Requests arriving while the
+// key set is reloaded keep using the keys loaded last, and
+// only wait for the load if there are none or the key ID is
+// unknown.
+//
+// Returns a 503 (Service Unavailable) error if the key set
+// was never loaded successfully.
+func (j *JWKS) Keys(ctx context.Context, kid string) ([]Key, error) {
+	now := j.now()
+
+	j.mut.Lock()
+	loaded := j.loaded
+	j.mut.Unlock()
+
+	if loaded.IsZero() || now.Sub(loaded) >= j.refresh {
+		j.reload(ctx, now, loaded.IsZero())
+	}
+
+	out, loaded, err := j.match(kid)
+	if len(out) == 0 && kid != "" {
+		j.reload(ctx, now, true)
+		out, loaded, err = j.match(kid)
+	}
+
+	if loaded.IsZero() {
+		return nil, midl.NewHTTPError(http.StatusServiceUnavailable, "key set unavailable").
+			WithCause(err)
+	}
+
+	return out, nil
+}
+
+// match returns the cached keys for the given key ID along
+// with the time they were loaded and the last load error.
+func (j *JWKS) match(kid string) ([]Key, time.Time, error) {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	return matchKeys(j.keys, kid), j.loaded, j.err
+}
+
+// reload loads the key set, unless it was last attempted
+// less than the minimum refresh interval ago.  If another
+// load is running, reload waits for it when wait is set and
+// otherwise returns immediately.
+func (j *JWKS) reload(ctx context.Context, now time.Time, wait bool) {
+	j.mut.Lock()
+	if done := j.loading; done != nil {
+		j.mut.Unlock()
+		if wait {
+			select {
+			case <-done:
+			case <-ctx.Done():
+			}
+		}
+		return
+	}
+
+	if !j.tried.IsZero() && now.Sub(j.tried) < j.minRefresh {
+		j.mut.Unlock()
+		return
+	}
+
+	done := make(chan struct{})
+	j.tried, j.loading = now, done
+	j.mut.Unlock()
+
+	// The load is shared by every pending request, so it
+	// must not fail because the one triggering it went away.
+	var keys []Key
+	data, err := j.fetch(context.WithoutCancel(ctx))
+	if err == nil {
+		keys, err = ParseJWKS(data)
+	}
+
+	j.mut.Lock()
+	if err == nil {
+		j.keys, j.loaded = keys, now
+	}
+	j.err, j.loading = err, nil
+	j.mut.Unlock()
+
+	close(done)
+}
+
+// fetch reads the key set document.
+func (j *JWKS) fetch(ctx context.Context) ([]byte, error) {
+	if j.url == "" {
+		return os.ReadFile(j.path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching key set: unexpected status %d", res.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(res.Body, maxJWKSSize))
+}
+
+// jwk is a single JSON Web Key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS parses the given JSON Web Key Set.
+//
+// RSA, P-256 EC and symmetric ("oct") signing keys are
+// returned.  Encryption keys and keys of other types or
+// curves are skipped.
+func ParseJWKS(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parsing key set: %w", err)
+	}
+
+	out := make([]Key, 0, len(set.Keys))
+	for i := range set.Keys {
+		k := &set.Keys[i]
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		pub, err := k.key()
+		if err != nil {
+			return nil, fmt.Errorf("parsing key set: key %q: %w", k.Kid, err)
+		}
+		if pub != nil {
+			out = append(out, Key{ID: k.Kid, Algorithm: k.Alg, Key: pub})
+		}
+	}
+
+	return out, nil
+}
+
+// key decodes the verification key of a JWK, returning nil
+// for unsupported key types.
+func (k *jwk) key() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err1 := decodeSegment(k.N)
+		e, err2 := decodeSegment(k.E)
+		if err1 != nil || err2 != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, ErrMalformed
+		}
+
+		exp := new(big.Int).SetBytes(e)
+		if exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, ErrMalformed
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+
+		x, err1 := decodeSegment(k.X)
+		y, err2 := decodeSegment(k.Y)
+		if err1 != nil || err2 != nil || len(x) != 32 || len(y) != 32 {
+			return nil, ErrMalformed
+		}
+
+		// Reject points which are not on the curve.
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, ErrMalformed
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+
+	case "oct":
+		secret, err := decodeSegment(k.K)
+		if err != nil || len(secret) == 0 {
+			return nil, ErrMalformed
+		}
+		return secret, nil
+	}
+
+	return nil, nil
+}
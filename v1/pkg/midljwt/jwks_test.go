@@ -0,0 +1,218 @@
+package midljwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"alg": RS256,
+		"n":   segment(key.N.Bytes()),
+		"e":   segment(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	x, y := make([]byte, 32), make([]byte, 32)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": segment(x), "y": segment(y)}
+}
+
+func jwkSet(keys ...map[string]string) []byte {
+	out, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	return out
+}
+
+func TestParseJWKS(t *testing.T) {
+	c.Convey("parses signing keys", t, func() {
+		keys, err := ParseJWKS(jwkSet(
+			rsaJWK("r", rsaKey),
+			ecJWK("e", ecKey),
+			map[string]string{"kty": "oct", "kid": "h", "k": segment(secret)},
+			map[string]string{"kty": "RSA", "kid": "enc", "use": "enc"},
+			map[string]string{"kty": "EC", "kid": "p384", "crv": "P-384"},
+			map[string]string{"kty": "OKP", "kid": "ed"},
+		))
+
+		c.So(err, c.ShouldBeNil)
+		c.So(len(keys), c.ShouldEqual, 3)
+		c.So(keys[0].Algorithm, c.ShouldEqual, RS256)
+		c.So(keys[0].Key.(*rsa.PublicKey).Equal(&rsaKey.PublicKey), c.ShouldBeTrue)
+		c.So(keys[1].Key.(*ecdsa.PublicKey).Equal(&ecKey.PublicKey), c.ShouldBeTrue)
+		c.So(keys[2].Key, c.ShouldResemble, secret)
+	})
+
+	c.Convey("rejects malformed keys", t, func() {
+		bad := ecJWK("e", ecKey)
+		bad["y"] = bad["x"]
+
+		for _, set := range [][]byte{
+			[]byte(`{"keys":`),
+			jwkSet(bad),
+			jwkSet(map[string]string{"kty": "RSA", "n": "AQAB", "e": "AQ"}),
+			jwkSet(map[string]string{"kty": "oct"}),
+		} {
+			_, err := ParseJWKS(set)
+			c.So(err, c.ShouldNotBeNil)
+		}
+	})
+}
+
+func TestJWKS(t *testing.T) {
+	ctx := context.Background()
+
+	c.Convey("loads keys from a file", t, func() {
+		path := filepath.Join(t.TempDir(), "jwks.json")
+		c.So(os.WriteFile(path, jwkSet(rsaJWK("r", rsaKey)), 0o600), c.ShouldBeNil)
+
+		v := NewVerifier(NewJWKSFile(path))
+		v.now = func() time.Time { return testNow }
+
+		out, err := v.Parse(ctx, sign(RS256, "r", rsaKey, claims(nil)))
+		c.So(err, c.ShouldBeNil)
+		c.So(out.Subject, c.ShouldEqual, "alice")
+	})
+
+	c.Convey("caches and rotates keys from an endpoint", t, func() {
+		var fetches atomic.Int32
+		var set atomic.Value
+		set.Store(jwkSet(rsaJWK("old", rsaKey)))
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches.Add(1)
+			w.Write(set.Load().([]byte))
+		}))
+		defer srv.Close()
+
+		now := testNow
+		keys := NewJWKSURL(srv.URL).Client(srv.Client())
+		keys.now = func() time.Time { return now }
+		v := NewVerifier(keys)
+		v.now = func() time.Time { return testNow }
+
+		_, err := v.Parse(ctx, sign(RS256, "old", rsaKey, claims(nil)))
+		c.So(err, c.ShouldBeNil)
+		_, err = v.Parse(ctx, sign(RS256, "old", rsaKey, claims(nil)))
+		c.So(err, c.ShouldBeNil)
+		c.So(fetches.Load(), c.ShouldEqual, 1)
+
+		set.Store(jwkSet(rsaJWK("old", rsaKey), ecJWK("new", ecKey)))
+
+		// Unknown key IDs reload at most once per MinRefresh.
+		_, err = v.Parse(ctx, sign(ES256, "new", ecKey, claims(nil)))
+		c.So(err, c.ShouldEqual, ErrNoKey)
+		c.So(fetches.Load(), c.ShouldEqual, 1)
+
+		now = now.Add(DefaultJWKSMinRefresh)
+		_, err = v.Parse(ctx, sign(ES256, "new", ecKey, claims(nil)))
+		c.So(err, c.ShouldBeNil)
+		c.So(fetches.Load(), c.ShouldEqual, 2)
+
+		// Stale key sets are reloaded.
+		set.Store(jwkSet(ecJWK("new", ecKey)))
+		now = now.Add(DefaultJWKSRefresh)
+		_, err = v.Parse(ctx, sign(RS256, "old", rsaKey, claims(nil)))
+		c.So(err, c.ShouldEqual, ErrNoKey)
+		c.So(fetches.Load(), c.ShouldEqual, 3)
+	})
+
+	c.Convey("keeps serving keys when reloading fails", t, func() {
+		var fail atomic.Bool
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if fail.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write(jwkSet(rsaJWK("r", rsaKey)))
+		}))
+		defer srv.Close()
+
+		now := testNow
+		keys := NewJWKSURL(srv.URL).Refresh(time.Minute).MinRefresh(time.Second)
+		keys.now = func() time.Time { return now }
+
+		out, err := keys.Keys(ctx, "r")
+		c.So(err, c.ShouldBeNil)
+		c.So(len(out), c.ShouldEqual, 1)
+
+		fail.Store(true)
+		now = now.Add(time.Hour)
+		out, err = keys.Keys(ctx, "r")
+		c.So(err, c.ShouldBeNil)
+		c.So(len(out), c.ShouldEqual, 1)
+	})
+
+	c.Convey("serves cached keys while reloading", t, func() {
+		var fetches atomic.Int32
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if fetches.Add(1) > 1 {
+				close(started)
+				<-release
+			}
+			w.Write(jwkSet(rsaJWK("r", rsaKey)))
+		}))
+		defer srv.Close()
+
+		var mut sync.Mutex
+		now := testNow
+		keys := NewJWKSURL(srv.URL).Client(srv.Client())
+		keys.now = func() time.Time {
+			mut.Lock()
+			defer mut.Unlock()
+			return now
+		}
+
+		_, err := keys.Keys(ctx, "r")
+		c.So(err, c.ShouldBeNil)
+
+		mut.Lock()
+		now = now.Add(DefaultJWKSRefresh)
+		mut.Unlock()
+
+		reloaded := make(chan error)
+		go func() {
+			_, err := keys.Keys(ctx, "r")
+			reloaded <- err
+		}()
+		<-started
+
+		out, err := keys.Keys(ctx, "r")
+		c.So(err, c.ShouldBeNil)
+		c.So(len(out), c.ShouldEqual, 1)
+
+		close(release)
+		c.So(<-reloaded, c.ShouldBeNil)
+		c.So(fetches.Load(), c.ShouldEqual, 2)
+	})
+
+	c.Convey("reports unavailable key sets", t, func() {
+		keys := NewJWKSFile(filepath.Join(t.TempDir(), "missing.json"))
+
+		_, err := keys.Keys(ctx, "")
+		c.So(midl.ResolveStatus(err, http.StatusOK), c.ShouldEqual, http.StatusServiceUnavailable)
+		c.So(errors.Is(err, os.ErrNotExist), c.ShouldBeTrue)
+	})
+}
@@ -0,0 +1,137 @@
+package midljwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"math/big"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	HS384 = "HS384"
+	HS512 = "HS512"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Key is a key which may have signed a token.
+type Key struct {
+
+	// ID is the key ID matched against the "kid" header of
+	// tokens.  Keys without an ID are candidates for any
+	// token.
+	ID string
+
+	// Algorithm restricts the key to a single algorithm.  If
+	// empty, the key is used with any algorithm matching its
+	// type.
+	Algorithm string
+
+	// Key is the verification key: a []byte secret for the
+	// HS algorithms, an *rsa.PublicKey for RS256 or an
+	// *ecdsa.PublicKey on the P-256 curve for ES256.
+	Key interface{}
+}
+
+// KeySource defines a provider of verification keys.
+//
+// Implementations must be safe for concurrent use.
+type KeySource interface {
+
+	// Keys returns the keys which may have signed a token
+	// with the given key ID, which may be empty.
+	Keys(ctx context.Context, kid string) ([]Key, error)
+}
+
+// StaticKeys returns a KeySource providing the given keys.
+//
+//   keys := midljwt.StaticKeys(midljwt.Key{Key: []byte(secret)})
+func StaticKeys(keys ...Key) KeySource {
+	return staticKeys(keys)
+}
+
+type staticKeys []Key
+
+func (s staticKeys) Keys(_ context.Context, kid string) ([]Key, error) {
+	return matchKeys(s, kid), nil
+}
+
+// matchKeys returns the keys which may have signed a token
+// with the given key ID.
+func matchKeys(keys []Key, kid string) []Key {
+	var out []Key
+	for _, key := range keys {
+		if kid == "" || key.ID == "" || key.ID == kid {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// verifySignature returns whether the given signature over
+// the given bytes was made with the given key and algorithm.
+// Keys of a type other than the algorithm requires never
+// verify.
+func verifySignature(alg string, key Key, signed, sig []byte) bool {
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return false
+	}
+
+	switch alg {
+	case HS256:
+		return verifyHMAC(sha256.New, key.Key, signed, sig)
+	case HS384:
+		return verifyHMAC(sha512.New384, key.Key, signed, sig)
+	case HS512:
+		return verifyHMAC(sha512.New, key.Key, signed, sig)
+
+	case RS256:
+		pub, ok := key.Key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		sum := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) == nil
+
+	case ES256:
+		pub, ok := key.Key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() || len(sig) != 64 {
+			return false
+		}
+		sum := sha256.Sum256(signed)
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, sum[:], r, s)
+	}
+
+	return false
+}
+
+func verifyHMAC(fn func() hash.Hash, key interface{}, signed, sig []byte) bool {
+	secret, ok := key.([]byte)
+	if !ok || len(secret) == 0 {
+		return false
+	}
+
+	mac := hmac.New(fn, secret)
+	mac.Write(signed)
+	return hmac.Equal(mac.Sum(nil), sig)
+}
+
+// supportedAlgorithm returns whether the given algorithm is
+// supported.
+func supportedAlgorithm(alg string) bool {
+	switch alg {
+	case HS256, HS384, HS512, RS256, ES256:
+		return true
+	}
+	return false
+}
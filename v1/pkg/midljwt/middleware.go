@@ -0,0 +1,60 @@
+package midljwt
+
+import (
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+	"github.com/vulpine-io/midl/v1/pkg/midlauth"
+)
+
+// ClaimsKey holds the Claims of requests authenticated by a
+// Middleware.
+var ClaimsKey = midl.NewContextKey[*Claims]("midljwt.claims")
+
+// FromRequest returns the Claims of the token the given
+// request was authenticated by.
+//
+// Returns false if the request was not authenticated with a
+// token.
+func FromRequest(req midl.Request) (*Claims, bool) {
+	claims, ok := ClaimsKey.Get(req)
+	return claims, ok && claims != nil
+}
+
+// Middleware is a midl.Middleware authenticating requests
+// with signed tokens sent as Bearer tokens in the
+// Authorization header.
+type Middleware struct {
+	bearer *midlauth.BearerAuth
+}
+
+// New creates a new Middleware for the given realm, checking
+// tokens with the given Verifier.
+//
+// Authenticated requests carry both a midlauth.Principal,
+// identified by the "sub" claim, and the Claims of the token.
+// Rejected requests are answered as by midlauth.Bearer.
+func New(realm string, verifier *Verifier) *Middleware {
+	return &Middleware{bearer: midlauth.Bearer(realm, verifier)}
+}
+
+// Optional sets whether requests without a Bearer token are
+// passed on unauthenticated instead of being rejected.
+func (m *Middleware) Optional(optional bool) *Middleware {
+	m.bearer.Optional(optional)
+	return m
+}
+
+// Handle authenticates the given request, storing the
+// Claims of its token at ClaimsKey.
+func (m *Middleware) Handle(req midl.Request) midl.Response {
+	if res := m.bearer.Handle(req); res != nil {
+		return res
+	}
+
+	if p, ok := midlauth.FromRequest(req); ok {
+		if claims, ok := p.Attributes[claimsAttribute].(*Claims); ok {
+			ClaimsKey.Set(req, claims)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package midljwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+	"github.com/vulpine-io/midl/v1/pkg/midlauth"
+)
+
+// whoami responds with the subject and issuer of the
+// request's claims, or "anonymous".
+var whoami = midl.MiddlewareFunc(func(req midl.Request) midl.Response {
+	claims, ok := FromRequest(req)
+	if !ok {
+		return midl.MakeResponse(http.StatusOK, "anonymous")
+	}
+
+	p, _ := midlauth.FromRequest(req)
+	return midl.MakeResponse(http.StatusOK, p.Scheme+":"+p.ID+"@"+claims.Issuer)
+})
+
+func serve(h http.Handler, auth string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://foo.bar/", nil)
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	verifier := NewVerifier(StaticKeys(Key{ID: "ec", Key: &ecKey.PublicKey})).Audience("api")
+	verifier.now = func() time.Time { return testNow }
+
+	c.Convey("stores the claims of valid tokens", t, func() {
+		w := serve(midl.JSONAdapter(New("api", verifier), whoami),
+			"Bearer "+sign(ES256, "ec", ecKey, claims(nil)))
+
+		c.So(w.Code, c.ShouldEqual, http.StatusOK)
+		c.So(w.Body.String(), c.ShouldEqual, `"Bearer:alice@https://issuer"`)
+	})
+
+	c.Convey("challenges requests without a token", t, func() {
+		w := serve(midl.JSONAdapter(New("api", verifier), whoami), "")
+
+		c.So(w.Code, c.ShouldEqual, http.StatusUnauthorized)
+		c.So(w.Header().Get("WWW-Authenticate"), c.ShouldEqual, `Bearer realm="api"`)
+
+		w = serve(midl.JSONAdapter(New("api", verifier).Optional(true), whoami), "")
+		c.So(w.Body.String(), c.ShouldEqual, `"anonymous"`)
+	})
+
+	c.Convey("rejects invalid tokens", t, func() {
+		for _, tok := range []string{
+			"junk",
+			sign(ES256, "ec", ecKey, claims(map[string]interface{}{"aud": "web"})),
+			sign(ES256, "ec", ecKey, claims(map[string]interface{}{"exp": testNow.Add(-time.Hour).Unix()})),
+		} {
+			w := serve(midl.JSONAdapter(New("api", verifier), whoami), "Bearer "+tok)
+
+			c.So(w.Code, c.ShouldEqual, http.StatusUnauthorized)
+			c.So(w.Body.String(), c.ShouldEqual, `{"error":"invalid credentials"}`)
+			c.So(w.Header().Get("WWW-Authenticate"), c.ShouldEqual, `Bearer realm="api", error="invalid_token"`)
+		}
+	})
+}
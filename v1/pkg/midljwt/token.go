@@ -0,0 +1,230 @@
+package midljwt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMalformed is returned for tokens which are not
+	// valid compact JWS serializations of a JSON claim set.
+	ErrMalformed = errors.New("malformed token")
+
+	// ErrUnsupportedAlgorithm is returned for tokens signed
+	// with an algorithm which is not supported or allowed.
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+	// ErrNoKey is returned for tokens for which no
+	// verification key is known.
+	ErrNoKey = errors.New("no key for token")
+
+	// ErrInvalidSignature is returned for tokens whose
+	// signature does not match any candidate key.
+	ErrInvalidSignature = errors.New("invalid token signature")
+
+	// ErrExpired is returned for tokens past their "exp"
+	// claim.
+	ErrExpired = errors.New("token expired")
+
+	// ErrNotYetValid is returned for tokens before their
+	// "nbf" claim or issued in the future.
+	ErrNotYetValid = errors.New("token not yet valid")
+
+	// ErrInvalidIssuer is returned for tokens whose "iss"
+	// claim is not an accepted issuer.
+	ErrInvalidIssuer = errors.New("invalid token issuer")
+
+	// ErrInvalidAudience is returned for tokens whose "aud"
+	// claim does not name an accepted audience.
+	ErrInvalidAudience = errors.New("invalid token audience")
+)
+
+// NumericDate is a JWT timestamp, encoded as a number of
+// seconds since the Unix epoch.
+type NumericDate struct {
+	time.Time
+}
+
+// NewNumericDate creates a new NumericDate for the given
+// time, truncated to the second.
+func NewNumericDate(t time.Time) *NumericDate {
+	return &NumericDate{t.Truncate(time.Second)}
+}
+
+// MarshalJSON encodes the date as a number of seconds.
+func (n NumericDate) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(n.Unix(), 10)), nil
+}
+
+// UnmarshalJSON decodes the date from a number of seconds,
+// which may be fractional.
+func (n *NumericDate) UnmarshalJSON(in []byte) error {
+	secs, err := strconv.ParseFloat(string(in), 64)
+	if err != nil || math.IsInf(secs, 0) || math.IsNaN(secs) {
+		return ErrMalformed
+	}
+
+	whole, frac := math.Modf(secs)
+	n.Time = time.Unix(int64(whole), int64(frac*float64(time.Second))).UTC()
+	return nil
+}
+
+// Audience is the "aud" claim, encoded as either a single
+// string or an array of strings.
+type Audience []string
+
+// UnmarshalJSON decodes the audience from a string or array.
+func (a *Audience) UnmarshalJSON(in []byte) error {
+	var one string
+	if err := json.Unmarshal(in, &one); err == nil {
+		*a = Audience{one}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(in, &many); err != nil {
+		return ErrMalformed
+	}
+
+	*a = many
+	return nil
+}
+
+// Contains returns whether the audience names the given
+// value.
+func (a Audience) Contains(aud string) bool {
+	for _, val := range a {
+		if val == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Claims holds the claim set of a verified token.
+type Claims struct {
+
+	// Issuer is the "iss" claim.
+	Issuer string `json:"iss,omitempty"`
+
+	// Subject is the "sub" claim.
+	Subject string `json:"sub,omitempty"`
+
+	// Audience is the "aud" claim.
+	Audience Audience `json:"aud,omitempty"`
+
+	// ExpiresAt is the "exp" claim.
+	ExpiresAt *NumericDate `json:"exp,omitempty"`
+
+	// NotBefore is the "nbf" claim.
+	NotBefore *NumericDate `json:"nbf,omitempty"`
+
+	// IssuedAt is the "iat" claim.
+	IssuedAt *NumericDate `json:"iat,omitempty"`
+
+	// ID is the "jti" claim.
+	ID string `json:"jti,omitempty"`
+
+	// KeyID is the "kid" header of the token, identifying the
+	// key which signed it.
+	KeyID string `json:"-"`
+
+	// Algorithm is the "alg" header of the token.
+	Algorithm string `json:"-"`
+
+	raw json.RawMessage
+}
+
+// Raw returns the JSON encoded claim set.
+func (c *Claims) Raw() []byte {
+	return c.raw
+}
+
+// Decode unmarshals the claim set into the given value,
+// typically a struct describing application claims.
+//
+//   var custom struct {
+//       Roles []string `json:"roles"`
+//   }
+//   err := claims.Decode(&custom)
+func (c *Claims) Decode(dst interface{}) error {
+	return json.Unmarshal(c.raw, dst)
+}
+
+// Get returns the value of the named claim, decoded into
+// its generic JSON representation.
+func (c *Claims) Get(name string) (interface{}, bool) {
+	var all map[string]interface{}
+	if err := json.Unmarshal(c.raw, &all); err != nil {
+		return nil, false
+	}
+
+	val, ok := all[name]
+	return val, ok
+}
+
+// header is the JOSE header of a token.
+type header struct {
+	Algorithm string   `json:"alg"`
+	KeyID     string   `json:"kid"`
+	Critical  []string `json:"crit"`
+}
+
+// token is a parsed but not yet verified token.
+type token struct {
+	header    header
+	claims    *Claims
+	signed    []byte
+	signature []byte
+}
+
+// parseToken splits and decodes the given compact token.
+func parseToken(in string) (*token, error) {
+	parts := strings.Split(in, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var out token
+
+	head, err := decodeSegment(parts[0])
+	if err != nil || json.Unmarshal(head, &out.header) != nil {
+		return nil, ErrMalformed
+	}
+
+	// Critical extensions must be understood, and none are.
+	if out.header.Critical != nil {
+		return nil, ErrMalformed
+	}
+
+	body, err := decodeSegment(parts[1])
+	if err != nil || !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return nil, ErrMalformed
+	}
+
+	out.claims = &Claims{
+		KeyID:     out.header.KeyID,
+		Algorithm: out.header.Algorithm,
+		raw:       body,
+	}
+	if err := json.Unmarshal(body, out.claims); err != nil {
+		return nil, ErrMalformed
+	}
+
+	if out.signature, err = decodeSegment(parts[2]); err != nil {
+		return nil, ErrMalformed
+	}
+
+	out.signed = []byte(in[:len(parts[0])+1+len(parts[1])])
+	return &out, nil
+}
+
+func decodeSegment(seg string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(seg)
+}
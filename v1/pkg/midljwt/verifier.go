@@ -0,0 +1,192 @@
+package midljwt
+
+import (
+	"context"
+	"time"
+
+	"github.com/vulpine-io/midl/v1/pkg/midlauth"
+)
+
+// DefaultClockSkew is the default tolerance applied when
+// checking the time based claims of a token.
+const DefaultClockSkew = time.Minute
+
+// claimsAttribute is the Principal attribute holding the
+// Claims of the token a Principal was authenticated by.
+const claimsAttribute = "claims"
+
+// Verifier checks the signature and registered claims of
+// tokens.
+//
+// Verifier implements midlauth.TokenVerifier, and may be used
+// with midlauth.Bearer or midlauth.NewAPIKey directly.
+type Verifier struct {
+	keys      KeySource
+	algs      map[string]bool
+	issuers   []string
+	audiences []string
+	skew      time.Duration
+	expiry    bool
+
+	now func() time.Time
+}
+
+// NewVerifier creates a new Verifier checking signatures
+// with keys from the given source.
+//
+// By default every supported algorithm is accepted, as each
+// Key is only used with the algorithms matching its type,
+// and tokens without an "exp" claim do not expire.
+func NewVerifier(keys KeySource) *Verifier {
+	return &Verifier{
+		keys: keys,
+		skew: DefaultClockSkew,
+		now:  time.Now,
+	}
+}
+
+// Algorithms restricts the signing algorithms accepted.
+//
+//   verifier.Algorithms(midljwt.RS256, midljwt.ES256)
+func (v *Verifier) Algorithms(algs ...string) *Verifier {
+	v.algs = make(map[string]bool, len(algs))
+	for _, alg := range algs {
+		v.algs[alg] = true
+	}
+	return v
+}
+
+// Issuer sets the accepted values of the "iss" claim.
+// Tokens with any other issuer are rejected.
+func (v *Verifier) Issuer(issuers ...string) *Verifier {
+	v.issuers = issuers
+	return v
+}
+
+// Audience sets the accepted values of the "aud" claim.
+// Tokens whose audience names none of them are rejected.
+func (v *Verifier) Audience(audiences ...string) *Verifier {
+	v.audiences = audiences
+	return v
+}
+
+// ClockSkew sets the tolerance applied when checking the
+// "exp", "nbf" and "iat" claims.  Defaults to
+// DefaultClockSkew.
+func (v *Verifier) ClockSkew(skew time.Duration) *Verifier {
+	v.skew = skew
+	return v
+}
+
+// RequireExpiry sets whether tokens without an "exp" claim
+// are rejected.
+func (v *Verifier) RequireExpiry(require bool) *Verifier {
+	v.expiry = require
+	return v
+}
+
+// Parse verifies the given token, returning its claims.
+func (v *Verifier) Parse(ctx context.Context, in string) (*Claims, error) {
+	tok, err := parseToken(in)
+	if err != nil {
+		return nil, err
+	}
+
+	alg := tok.header.Algorithm
+	if !supportedAlgorithm(alg) || (v.algs != nil && !v.algs[alg]) {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	keys, err := v.keys.Keys(ctx, tok.header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKey
+	}
+
+	if !verifyAny(alg, keys, tok) {
+		return nil, ErrInvalidSignature
+	}
+
+	if err := v.check(tok.claims); err != nil {
+		return nil, err
+	}
+
+	return tok.claims, nil
+}
+
+// Verify verifies the given token, returning a Principal
+// identified by its "sub" claim.
+//
+// The Claims of the token are available to requests
+// authenticated through the Middleware with FromRequest.
+func (v *Verifier) Verify(ctx context.Context, token string) (*midlauth.Principal, error) {
+	claims, err := v.Parse(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &midlauth.Principal{
+		ID:         claims.Subject,
+		Attributes: map[string]interface{}{claimsAttribute: claims},
+	}, nil
+}
+
+// check validates the registered claims of a token whose
+// signature was verified.
+func (v *Verifier) check(claims *Claims) error {
+	now := v.now()
+
+	if claims.ExpiresAt == nil {
+		if v.expiry {
+			return ErrExpired
+		}
+	} else if !now.Before(claims.ExpiresAt.Add(v.skew)) {
+		return ErrExpired
+	}
+
+	if claims.NotBefore != nil && now.Add(v.skew).Before(claims.NotBefore.Time) {
+		return ErrNotYetValid
+	}
+
+	if claims.IssuedAt != nil && now.Add(v.skew).Before(claims.IssuedAt.Time) {
+		return ErrNotYetValid
+	}
+
+	if v.issuers != nil && !contains(v.issuers, claims.Issuer) {
+		return ErrInvalidIssuer
+	}
+
+	if v.audiences != nil {
+		ok := false
+		for _, aud := range v.audiences {
+			ok = ok || claims.Audience.Contains(aud)
+		}
+		if !ok {
+			return ErrInvalidAudience
+		}
+	}
+
+	return nil
+}
+
+// verifyAny returns whether any of the given keys signed the
+// given token.
+func verifyAny(alg string, keys []Key, tok *token) bool {
+	for _, key := range keys {
+		if verifySignature(alg, key, tok.signed, tok.signature) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(vals []string, val string) bool {
+	for _, v := range vals {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
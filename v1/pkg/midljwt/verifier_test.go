@@ -0,0 +1,259 @@
+package midljwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"hash"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+var (
+	secret  = []byte("0123456789abcdef0123456789abcdef")
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	testNow = time.Unix(1700000000, 0)
+)
+
+func init() {
+	rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// sign returns a token with the given header fields and
+// claims signed with the given algorithm and key.
+func sign(alg, kid string, key interface{}, claims interface{}) string {
+	head, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+
+	signed := segment(head) + "." + segment(body)
+
+	var sig []byte
+	mac := func(fn func() hash.Hash) []byte {
+		m := hmac.New(fn, key.([]byte))
+		m.Write([]byte(signed))
+		return m.Sum(nil)
+	}
+	sum := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case HS256:
+		sig = mac(sha256.New)
+	case HS384:
+		sig = mac(sha512.New384)
+	case HS512:
+		sig = mac(sha512.New)
+	case RS256:
+		sig, _ = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, sum[:])
+	case ES256:
+		r, s, _ := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), sum[:])
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+
+	return signed + "." + segment(sig)
+}
+
+func segment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func claims(extra map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{
+		"sub": "alice",
+		"iss": "https://issuer",
+		"aud": "api",
+		"iat": testNow.Unix(),
+		"exp": testNow.Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		if v == nil {
+			delete(out, k)
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func newTestVerifier(keys ...Key) *Verifier {
+	v := NewVerifier(StaticKeys(keys...))
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func TestVerifier(t *testing.T) {
+	ctx := context.Background()
+
+	c.Convey("verifies every supported algorithm", t, func() {
+		v := newTestVerifier(
+			Key{ID: "hmac", Key: secret},
+			Key{ID: "rsa", Key: &rsaKey.PublicKey},
+			Key{ID: "ec", Key: &ecKey.PublicKey},
+		)
+
+		for _, tc := range []struct {
+			alg, kid string
+			key      interface{}
+		}{
+			{HS256, "hmac", secret},
+			{HS384, "hmac", secret},
+			{HS512, "hmac", secret},
+			{RS256, "rsa", rsaKey},
+			{ES256, "ec", ecKey},
+		} {
+			out, err := v.Parse(ctx, sign(tc.alg, tc.kid, tc.key, claims(nil)))
+
+			c.So(err, c.ShouldBeNil)
+			c.So(out.Subject, c.ShouldEqual, "alice")
+			c.So(out.Algorithm, c.ShouldEqual, tc.alg)
+			c.So(out.KeyID, c.ShouldEqual, tc.kid)
+			c.So(out.ExpiresAt.Equal(testNow.Add(time.Hour)), c.ShouldBeTrue)
+		}
+	})
+
+	c.Convey("rejects invalid signatures", t, func() {
+		v := newTestVerifier(Key{Key: secret}, Key{Key: &rsaKey.PublicKey})
+		other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+		_, err := v.Parse(ctx, sign(HS256, "", []byte("wrong"), claims(nil)))
+		c.So(err, c.ShouldEqual, ErrInvalidSignature)
+
+		_, err = v.Parse(ctx, sign(ES256, "", other, claims(nil)))
+		c.So(err, c.ShouldEqual, ErrInvalidSignature)
+
+		tok := sign(HS256, "", secret, claims(nil))
+		parts := strings.Split(tok, ".")
+		forged := parts[0] + "." + segment([]byte(`{"sub":"mallory"}`)) + "." + parts[2]
+		_, err = v.Parse(ctx, forged)
+		c.So(err, c.ShouldEqual, ErrInvalidSignature)
+	})
+
+	c.Convey("does not use keys with other algorithms", t, func() {
+		pub, _ := json.Marshal(rsaKey.PublicKey)
+		v := newTestVerifier(Key{Key: &rsaKey.PublicKey}, Key{Algorithm: HS512, Key: secret})
+
+		_, err := v.Parse(ctx, sign(HS256, "", pub, claims(nil)))
+		c.So(err, c.ShouldEqual, ErrInvalidSignature)
+
+		_, err = v.Parse(ctx, sign(HS256, "", secret, claims(nil)))
+		c.So(err, c.ShouldEqual, ErrInvalidSignature)
+	})
+
+	c.Convey("rejects unsupported and disallowed algorithms", t, func() {
+		v := newTestVerifier(Key{Key: secret})
+
+		none := segment([]byte(`{"alg":"none"}`)) + "." + segment([]byte(`{"sub":"a"}`)) + "."
+		_, err := v.Parse(ctx, none)
+		c.So(err, c.ShouldEqual, ErrUnsupportedAlgorithm)
+
+		_, err = v.Algorithms(RS256).Parse(ctx, sign(HS256, "", secret, claims(nil)))
+		c.So(err, c.ShouldEqual, ErrUnsupportedAlgorithm)
+	})
+
+	c.Convey("rejects malformed tokens", t, func() {
+		v := newTestVerifier(Key{Key: secret})
+
+		for _, tok := range []string{
+			"",
+			"a.b",
+			"a.b.c.d",
+			"!!.e30.",
+			segment([]byte(`{"alg":"HS256"}`)) + "." + segment([]byte(`[1]`)) + ".",
+			segment([]byte(`{"alg":"HS256","crit":["exp"]}`)) + "." + segment([]byte(`{}`)) + ".",
+			sign(HS256, "", secret, claims(map[string]interface{}{"exp": "soon"})),
+			sign(HS256, "", secret, claims(map[string]interface{}{"aud": 4})),
+		} {
+			_, err := v.Parse(ctx, tok)
+			c.So(err, c.ShouldEqual, ErrMalformed)
+		}
+	})
+
+	c.Convey("reports missing keys", t, func() {
+		_, err := newTestVerifier().Parse(ctx, sign(HS256, "", secret, claims(nil)))
+		c.So(err, c.ShouldEqual, ErrNoKey)
+
+		_, err = newTestVerifier(Key{ID: "a", Key: secret}).Parse(ctx, sign(HS256, "b", secret, claims(nil)))
+		c.So(err, c.ShouldEqual, ErrNoKey)
+	})
+
+	c.Convey("checks time claims with clock skew", t, func() {
+		v := newTestVerifier(Key{Key: secret})
+		at := func(d time.Duration) int64 { return testNow.Add(d).Unix() }
+		parse := func(extra map[string]interface{}) error {
+			_, err := v.Parse(ctx, sign(HS256, "", secret, claims(extra)))
+			return err
+		}
+
+		c.So(parse(map[string]interface{}{"exp": at(-30 * time.Second)}), c.ShouldBeNil)
+		c.So(parse(map[string]interface{}{"exp": at(-time.Minute)}), c.ShouldEqual, ErrExpired)
+		c.So(parse(map[string]interface{}{"nbf": at(30 * time.Second)}), c.ShouldBeNil)
+		c.So(parse(map[string]interface{}{"nbf": at(2 * time.Minute)}), c.ShouldEqual, ErrNotYetValid)
+		c.So(parse(map[string]interface{}{"iat": at(2 * time.Minute)}), c.ShouldEqual, ErrNotYetValid)
+		c.So(parse(map[string]interface{}{"exp": float64(at(time.Hour)) + 0.5}), c.ShouldBeNil)
+
+		v.ClockSkew(0)
+		c.So(parse(map[string]interface{}{"exp": at(-30 * time.Second)}), c.ShouldEqual, ErrExpired)
+
+		c.So(parse(map[string]interface{}{"exp": nil}), c.ShouldBeNil)
+		v.RequireExpiry(true)
+		c.So(parse(map[string]interface{}{"exp": nil}), c.ShouldEqual, ErrExpired)
+	})
+
+	c.Convey("checks issuer and audience", t, func() {
+		v := newTestVerifier(Key{Key: secret}).
+			Issuer("https://issuer", "https://other").
+			Audience("api", "admin")
+		parse := func(extra map[string]interface{}) error {
+			_, err := v.Parse(ctx, sign(HS256, "", secret, claims(extra)))
+			return err
+		}
+
+		c.So(parse(nil), c.ShouldBeNil)
+		c.So(parse(map[string]interface{}{"aud": []string{"web", "admin"}}), c.ShouldBeNil)
+		c.So(parse(map[string]interface{}{"iss": "https://evil"}), c.ShouldEqual, ErrInvalidIssuer)
+		c.So(parse(map[string]interface{}{"iss": nil}), c.ShouldEqual, ErrInvalidIssuer)
+		c.So(parse(map[string]interface{}{"aud": []string{"web"}}), c.ShouldEqual, ErrInvalidAudience)
+		c.So(parse(map[string]interface{}{"aud": nil}), c.ShouldEqual, ErrInvalidAudience)
+	})
+
+	c.Convey("exposes application claims", t, func() {
+		out, err := newTestVerifier(Key{Key: secret}).Parse(ctx,
+			sign(HS256, "", secret, claims(map[string]interface{}{"roles": []string{"admin"}})))
+		c.So(err, c.ShouldBeNil)
+
+		var custom struct {
+			Roles []string `json:"roles"`
+		}
+		c.So(out.Decode(&custom), c.ShouldBeNil)
+		c.So(custom.Roles, c.ShouldResemble, []string{"admin"})
+
+		val, ok := out.Get("roles")
+		c.So(ok, c.ShouldBeTrue)
+		c.So(val, c.ShouldResemble, []interface{}{"admin"})
+
+		_, ok = out.Get("missing")
+		c.So(ok, c.ShouldBeFalse)
+	})
+
+	c.Convey("implements midlauth.TokenVerifier", t, func() {
+		p, err := newTestVerifier(Key{Key: secret}).Verify(ctx, sign(HS256, "", secret, claims(nil)))
+
+		c.So(err, c.ShouldBeNil)
+		c.So(p.ID, c.ShouldEqual, "alice")
+		c.So(p.Attributes[claimsAttribute].(*Claims).Issuer, c.ShouldEqual, "https://issuer")
+	})
+}
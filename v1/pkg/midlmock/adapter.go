@@ -2,6 +2,7 @@ package midlmock
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/vulpine-io/midl/v1/pkg/midl"
 )
@@ -14,6 +15,15 @@ type Adapter struct {
 	ContentTypeFunc     func(string)
 	ErrorSerializerFunc func(midl.ErrorSerializer)
 	SerializerFunc      func(midl.Serializer)
+	PanicHandlerFunc    func(midl.PanicHandler)
+	ErrorStatusFunc     func(error, int)
+	MaxBodySizeFunc     func(int64)
+	FormOptionsFunc     func(midl.FormOptions)
+	CompressionFunc     func(*midl.Compression)
+	DecompressionFunc   func(*midl.Decompression)
+	ConditionalFunc     func(midl.ConditionalMode)
+	CacheFunc           func(*midl.Cache)
+	CORSFunc            func(*midl.CORS)
 	AddHandlerFunc      func(...midl.Middleware)
 	SetHandlerFunc      func(...midl.Middleware)
 	AddWrapperFunc      func(...midl.RequestWrapper)
@@ -58,6 +68,78 @@ func (a *Adapter) Serializer(in midl.Serializer) midl.Adapter {
 	return a
 }
 
+// PanicHandler is a passthrough for the function stored in
+// the Adapter.PanicHandlerFunc property.
+// Returns the current Adapter instance.
+func (a *Adapter) PanicHandler(in midl.PanicHandler) midl.Adapter {
+	a.PanicHandlerFunc(in)
+	return a
+}
+
+// ErrorStatus is a passthrough for the function stored in
+// the Adapter.ErrorStatusFunc property.
+// Returns the current Adapter instance.
+func (a *Adapter) ErrorStatus(target error, code int) midl.Adapter {
+	a.ErrorStatusFunc(target, code)
+	return a
+}
+
+// MaxBodySize is a passthrough for the function stored in
+// the Adapter.MaxBodySizeFunc property.
+// Returns the current Adapter instance.
+func (a *Adapter) MaxBodySize(in int64) midl.Adapter {
+	a.MaxBodySizeFunc(in)
+	return a
+}
+
+// FormOptions is a passthrough for the function stored in
+// the Adapter.FormOptionsFunc property.
+// Returns the current Adapter instance.
+func (a *Adapter) FormOptions(in midl.FormOptions) midl.Adapter {
+	a.FormOptionsFunc(in)
+	return a
+}
+
+// Compression is a passthrough for the function stored in
+// the Adapter.CompressionFunc property.
+// Returns the current Adapter instance.
+func (a *Adapter) Compression(in *midl.Compression) midl.Adapter {
+	a.CompressionFunc(in)
+	return a
+}
+
+// Decompression is a passthrough for the function stored in
+// the Adapter.DecompressionFunc property.
+// Returns the current Adapter instance.
+func (a *Adapter) Decompression(in *midl.Decompression) midl.Adapter {
+	a.DecompressionFunc(in)
+	return a
+}
+
+// Conditional is a passthrough for the function stored in
+// the Adapter.ConditionalFunc property.
+// Returns the current Adapter instance.
+func (a *Adapter) Conditional(in midl.ConditionalMode) midl.Adapter {
+	a.ConditionalFunc(in)
+	return a
+}
+
+// Cache is a passthrough for the function stored in the
+// Adapter.CacheFunc property.
+// Returns the current Adapter instance.
+func (a *Adapter) Cache(in *midl.Cache) midl.Adapter {
+	a.CacheFunc(in)
+	return a
+}
+
+// CORS is a passthrough for the function stored in the
+// Adapter.CORSFunc property.
+// Returns the current Adapter instance.
+func (a *Adapter) CORS(in *midl.CORS) midl.Adapter {
+	a.CORSFunc(in)
+	return a
+}
+
 // AddHandlers is a passthrough for the function stored in
 // the Adapter.AddHandlersFunc property.
 // Returns the current Adapter instance.
@@ -83,3 +165,110 @@ func (a *Adapter) SetWrappers(wrap ...midl.RequestWrapper) midl.Adapter {
 	a.SetWrapperFunc(wrap...)
 	return a
 }
+
+// NegotiatingAdapter is a configurable mock implementation
+// of the midl.NegotiatingAdapter interface.
+//
+// Adapter methods are passed through to the embedded
+// Adapter mock's function properties.
+type NegotiatingAdapter struct {
+	Adapter
+
+	RegisterFunc func(string, midl.Serializer, midl.ErrorSerializer)
+}
+
+// Register is a passthrough for the function stored in the
+// NegotiatingAdapter.RegisterFunc property.
+// Returns the current NegotiatingAdapter instance.
+func (a *NegotiatingAdapter) Register(
+	mediaType string,
+	ser midl.Serializer,
+	err midl.ErrorSerializer,
+) midl.NegotiatingAdapter {
+	a.RegisterFunc(mediaType, ser, err)
+	return a
+}
+
+// EventAdapter is a configurable mock implementation of the
+// midl.EventAdapter interface.
+//
+// Adapter methods are passed through to the embedded
+// Adapter mock's function properties.
+type EventAdapter struct {
+	Adapter
+
+	HeartbeatFunc  func(time.Duration)
+	StreamHookFunc func(midl.StreamHook)
+}
+
+// Heartbeat is a passthrough for the function stored in the
+// EventAdapter.HeartbeatFunc property.
+// Returns the current EventAdapter instance.
+func (a *EventAdapter) Heartbeat(interval time.Duration) midl.EventAdapter {
+	a.HeartbeatFunc(interval)
+	return a
+}
+
+// StreamHook is a passthrough for the function stored in
+// the EventAdapter.StreamHookFunc property.
+// Returns the current EventAdapter instance.
+func (a *EventAdapter) StreamHook(hook midl.StreamHook) midl.EventAdapter {
+	a.StreamHookFunc(hook)
+	return a
+}
+
+// JSONStreamAdapter is a configurable mock implementation of
+// the midl.JSONStreamAdapter interface.
+//
+// Adapter methods are passed through to the embedded
+// Adapter mock's function properties.
+type JSONStreamAdapter struct {
+	Adapter
+
+	FormatFunc        func(midl.JSONStreamFormat)
+	FlushIntervalFunc func(time.Duration)
+}
+
+// Format is a passthrough for the function stored in the
+// JSONStreamAdapter.FormatFunc property.
+// Returns the current JSONStreamAdapter instance.
+func (a *JSONStreamAdapter) Format(format midl.JSONStreamFormat) midl.JSONStreamAdapter {
+	a.FormatFunc(format)
+	return a
+}
+
+// FlushInterval is a passthrough for the function stored in
+// the JSONStreamAdapter.FlushIntervalFunc property.
+// Returns the current JSONStreamAdapter instance.
+func (a *JSONStreamAdapter) FlushInterval(interval time.Duration) midl.JSONStreamAdapter {
+	a.FlushIntervalFunc(interval)
+	return a
+}
+
+// StreamingAdapter is a configurable mock implementation of
+// the midl.StreamingAdapter interface.
+//
+// Adapter methods are passed through to the embedded
+// Adapter mock's function properties.
+type StreamingAdapter struct {
+	Adapter
+
+	FlushPolicyFunc func(midl.FlushPolicy)
+	StreamHookFunc  func(midl.StreamHook)
+}
+
+// FlushPolicy is a passthrough for the function stored in
+// the StreamingAdapter.FlushPolicyFunc property.
+// Returns the current StreamingAdapter instance.
+func (a *StreamingAdapter) FlushPolicy(policy midl.FlushPolicy) midl.StreamingAdapter {
+	a.FlushPolicyFunc(policy)
+	return a
+}
+
+// StreamHook is a passthrough for the function stored in
+// the StreamingAdapter.StreamHookFunc property.
+// Returns the current StreamingAdapter instance.
+func (a *StreamingAdapter) StreamHook(hook midl.StreamHook) midl.StreamingAdapter {
+	a.StreamHookFunc(hook)
+	return a
+}
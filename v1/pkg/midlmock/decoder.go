@@ -0,0 +1,13 @@
+package midlmock
+
+// Decoder is a configurable mock implementation of the
+// midl.Decoder interface.
+type Decoder struct {
+	DecodeFunc func([]byte, interface{}) error
+}
+
+// Decode is a passthrough for the function stored at the
+// Decoder.DecodeFunc property.
+func (d Decoder) Decode(in []byte, out interface{}) error {
+	return d.DecodeFunc(in, out)
+}
@@ -15,3 +15,15 @@ type EmptyHandler struct {
 func (e EmptyHandler) Handle(q midl.Request, s midl.Response) []byte {
 	return e.HandleFunc(q, s)
 }
+
+// PanicHandler is a configurable mock implementation of the
+// midl.PanicHandler interface.
+type PanicHandler struct {
+	HandleFunc func(*midl.PanicError, midl.Request)
+}
+
+// Handle is a passthrough for the function stored in the
+// PanicHandler.HandleFunc property.
+func (p PanicHandler) Handle(e *midl.PanicError, q midl.Request) {
+	p.HandleFunc(e, q)
+}
@@ -0,0 +1,25 @@
+package midlmock
+
+import "github.com/vulpine-io/midl/v1/pkg/midl"
+
+// Problem is a configurable mock implementation of the
+// midl.Problem interface.
+//
+// As problems are carried through error values, Problem
+// also implements the error interface.
+type Problem struct {
+	ErrorFunc   func() string
+	ProblemFunc func() midl.ProblemDetails
+}
+
+// Error is a passthrough for the function stored at the
+// Problem.ErrorFunc property.
+func (p Problem) Error() string {
+	return p.ErrorFunc()
+}
+
+// Problem is a passthrough for the function stored at the
+// Problem.ProblemFunc property.
+func (p Problem) Problem() midl.ProblemDetails {
+	return p.ProblemFunc()
+}
@@ -1,7 +1,10 @@
 package midlmock
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/vulpine-io/midl/v1/pkg/midl"
 )
@@ -11,15 +14,39 @@ import (
 type Request struct {
 	HeaderFunc            func(string) (string, bool)
 	HeadersFunc           func(string) ([]string, bool)
+	CookieFunc            func(string) (*http.Cookie, bool)
+	CookiesFunc           func() []*http.Cookie
 	BodyFunc              func() []byte
+	BodyReaderFunc        func() io.Reader
 	HostFunc              func() string
 	ParameterFunc         func(string) (string, bool)
 	ParametersFunc        func(string) ([]string, bool)
+	FormValueFunc         func(string) (string, bool)
+	FormValuesFunc        func(string) ([]string, bool)
+	FileFunc              func(string) (*midl.FormFile, bool)
+	FilesFunc             func(string) ([]*midl.FormFile, bool)
+	PathParamFunc         func(string) string
 	RawRequestFunc        func() *http.Request
 	ErrorFunc             func() error
 	AdditionalContextFunc func() map[interface{}]interface{}
+	ContextFunc           func() context.Context
+
+	ParameterIntFunc              func(string, int) int
+	ParameterIntRequiredFunc      func(string) int
+	ParameterBoolFunc             func(string, bool) bool
+	ParameterBoolRequiredFunc     func(string) bool
+	ParameterFloatFunc            func(string, float64) float64
+	ParameterFloatRequiredFunc    func(string) float64
+	ParameterTimeFunc             func(string, string, time.Time) time.Time
+	ParameterTimeRequiredFunc     func(string, string) time.Time
+	ParameterDurationFunc         func(string, time.Duration) time.Duration
+	ParameterDurationRequiredFunc func(string) time.Duration
+	ParameterEnumFunc             func(string, string, ...string) string
+	ParameterEnumRequiredFunc     func(string, ...string) string
 
 	ProcessBodyFunc func(midl.BodyProcessor)
+	WithContextFunc func(context.Context)
+	BindFunc        func(interface{})
 }
 
 func (r Request) AdditionalContext() map[interface{}]interface{} {
@@ -38,12 +65,30 @@ func (r Request) Headers(key string) ([]string, bool) {
 	return r.HeadersFunc(key)
 }
 
+// Cookie is a passthrough for the function stored at the
+// Request.CookieFunc property.
+func (r Request) Cookie(name string) (*http.Cookie, bool) {
+	return r.CookieFunc(name)
+}
+
+// Cookies is a passthrough for the function stored at the
+// Request.CookiesFunc property.
+func (r Request) Cookies() []*http.Cookie {
+	return r.CookiesFunc()
+}
+
 // Body is a passthrough for the function stored at the
 // Request.BodyFunc property.
 func (r Request) Body() []byte {
 	return r.BodyFunc()
 }
 
+// BodyReader is a passthrough for the function stored at
+// the Request.BodyReaderFunc property.
+func (r Request) BodyReader() io.Reader {
+	return r.BodyReaderFunc()
+}
+
 // Host is a passthrough for the function stored at the
 // Request.HostFunc property.
 func (r Request) Host() string {
@@ -62,6 +107,109 @@ func (r Request) Parameters(key string) ([]string, bool) {
 	return r.ParametersFunc(key)
 }
 
+// ParameterInt is a passthrough for the function stored at
+// the Request.ParameterIntFunc property.
+func (r Request) ParameterInt(key string, def int) int {
+	return r.ParameterIntFunc(key, def)
+}
+
+// ParameterIntRequired is a passthrough for the function
+// stored at the Request.ParameterIntRequiredFunc property.
+func (r Request) ParameterIntRequired(key string) int {
+	return r.ParameterIntRequiredFunc(key)
+}
+
+// ParameterBool is a passthrough for the function stored at
+// the Request.ParameterBoolFunc property.
+func (r Request) ParameterBool(key string, def bool) bool {
+	return r.ParameterBoolFunc(key, def)
+}
+
+// ParameterBoolRequired is a passthrough for the function
+// stored at the Request.ParameterBoolRequiredFunc property.
+func (r Request) ParameterBoolRequired(key string) bool {
+	return r.ParameterBoolRequiredFunc(key)
+}
+
+// ParameterFloat is a passthrough for the function stored at
+// the Request.ParameterFloatFunc property.
+func (r Request) ParameterFloat(key string, def float64) float64 {
+	return r.ParameterFloatFunc(key, def)
+}
+
+// ParameterFloatRequired is a passthrough for the function
+// stored at the Request.ParameterFloatRequiredFunc property.
+func (r Request) ParameterFloatRequired(key string) float64 {
+	return r.ParameterFloatRequiredFunc(key)
+}
+
+// ParameterTime is a passthrough for the function stored at
+// the Request.ParameterTimeFunc property.
+func (r Request) ParameterTime(key, layout string, def time.Time) time.Time {
+	return r.ParameterTimeFunc(key, layout, def)
+}
+
+// ParameterTimeRequired is a passthrough for the function
+// stored at the Request.ParameterTimeRequiredFunc property.
+func (r Request) ParameterTimeRequired(key, layout string) time.Time {
+	return r.ParameterTimeRequiredFunc(key, layout)
+}
+
+// ParameterDuration is a passthrough for the function stored
+// at the Request.ParameterDurationFunc property.
+func (r Request) ParameterDuration(key string, def time.Duration) time.Duration {
+	return r.ParameterDurationFunc(key, def)
+}
+
+// ParameterDurationRequired is a passthrough for the function
+// stored at the Request.ParameterDurationRequiredFunc
+// property.
+func (r Request) ParameterDurationRequired(key string) time.Duration {
+	return r.ParameterDurationRequiredFunc(key)
+}
+
+// ParameterEnum is a passthrough for the function stored at
+// the Request.ParameterEnumFunc property.
+func (r Request) ParameterEnum(key, def string, options ...string) string {
+	return r.ParameterEnumFunc(key, def, options...)
+}
+
+// ParameterEnumRequired is a passthrough for the function
+// stored at the Request.ParameterEnumRequiredFunc property.
+func (r Request) ParameterEnumRequired(key string, options ...string) string {
+	return r.ParameterEnumRequiredFunc(key, options...)
+}
+
+// FormValue is a passthrough for the function stored at the
+// Request.FormValueFunc property.
+func (r Request) FormValue(key string) (string, bool) {
+	return r.FormValueFunc(key)
+}
+
+// FormValues is a passthrough for the function stored at
+// the Request.FormValuesFunc property.
+func (r Request) FormValues(key string) ([]string, bool) {
+	return r.FormValuesFunc(key)
+}
+
+// File is a passthrough for the function stored at the
+// Request.FileFunc property.
+func (r Request) File(key string) (*midl.FormFile, bool) {
+	return r.FileFunc(key)
+}
+
+// Files is a passthrough for the function stored at the
+// Request.FilesFunc property.
+func (r Request) Files(key string) ([]*midl.FormFile, bool) {
+	return r.FilesFunc(key)
+}
+
+// PathParam is a passthrough for the function stored at the
+// Request.PathParamFunc property.
+func (r Request) PathParam(name string) string {
+	return r.PathParamFunc(name)
+}
+
 // RawRequest is a passthrough for the function stored at
 // the Request.RawRequestFunc property.
 func (r Request) RawRequest() *http.Request {
@@ -81,3 +229,25 @@ func (r *Request) ProcessBody(in midl.BodyProcessor) midl.Request {
 	r.ProcessBodyFunc(in)
 	return r
 }
+
+// Context is a passthrough for the function stored at the
+// Request.ContextFunc property.
+func (r Request) Context() context.Context {
+	return r.ContextFunc()
+}
+
+// WithContext is a passthrough for the function stored at
+// the Request.WithContextFunc property.
+// Returns the current Request instance.
+func (r *Request) WithContext(ctx context.Context) midl.Request {
+	r.WithContextFunc(ctx)
+	return r
+}
+
+// Bind is a passthrough for the function stored at the
+// Request.BindFunc property.
+// Returns the current Request instance.
+func (r *Request) Bind(dst interface{}) midl.Request {
+	r.BindFunc(dst)
+	return r
+}
@@ -2,6 +2,7 @@ package midlmock
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/vulpine-io/midl/v1/pkg/midl"
 )
@@ -25,6 +26,12 @@ type Response struct {
 	RawHeadersFunc func() http.Header
 	CallbackFunc   func(f func())
 	CallbacksFunc  func() []func()
+
+	SetCookieFunc   func(cookie *http.Cookie)
+	ClearCookieFunc func(cookie *http.Cookie)
+
+	SetETagFunc         func(tag string, weak bool)
+	SetLastModifiedFunc func(modified time.Time)
 }
 
 func (r *Response) Callback(f func()) midl.Response {
@@ -127,3 +134,35 @@ func (r *Response) SetHeaders(key string, values []string) midl.Response {
 func (r Response) RawHeaders() http.Header {
 	return r.RawHeadersFunc()
 }
+
+// SetCookie is a passthrough for the function stored at the
+// Response.SetCookieFunc property.
+// Returns the current Response instance.
+func (r *Response) SetCookie(cookie *http.Cookie) midl.Response {
+	r.SetCookieFunc(cookie)
+	return r
+}
+
+// ClearCookie is a passthrough for the function stored at
+// the Response.ClearCookieFunc property.
+// Returns the current Response instance.
+func (r *Response) ClearCookie(cookie *http.Cookie) midl.Response {
+	r.ClearCookieFunc(cookie)
+	return r
+}
+
+// SetETag is a passthrough for the function stored at the
+// Response.SetETagFunc property.
+// Returns the current Response instance.
+func (r *Response) SetETag(tag string, weak bool) midl.Response {
+	r.SetETagFunc(tag, weak)
+	return r
+}
+
+// SetLastModified is a passthrough for the function stored
+// at the Response.SetLastModifiedFunc property.
+// Returns the current Response instance.
+func (r *Response) SetLastModified(modified time.Time) midl.Response {
+	r.SetLastModifiedFunc(modified)
+	return r
+}
@@ -0,0 +1,37 @@
+package midlmock
+
+import (
+	"net/http"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+// Router is a configurable mock implementation of the
+// midl.Router interface.
+type Router struct {
+	ServeHTTPFunc func(http.ResponseWriter, *http.Request)
+	HandleFunc    func(method, pattern string, handler http.Handler)
+	RouteFunc     func(method, pattern string, handlers ...midl.Middleware)
+}
+
+// ServeHTTP is a passthrough for the function stored in the
+// Router.ServeHTTPFunc property.
+func (r *Router) ServeHTTP(w http.ResponseWriter, q *http.Request) {
+	r.ServeHTTPFunc(w, q)
+}
+
+// Handle is a passthrough for the function stored in the
+// Router.HandleFunc property.
+// Returns the current Router instance.
+func (r *Router) Handle(method, pattern string, handler http.Handler) midl.Router {
+	r.HandleFunc(method, pattern, handler)
+	return r
+}
+
+// Route is a passthrough for the function stored in the
+// Router.RouteFunc property.
+// Returns the current Router instance.
+func (r *Router) Route(method, pattern string, handlers ...midl.Middleware) midl.Router {
+	r.RouteFunc(method, pattern, handlers...)
+	return r
+}
@@ -0,0 +1,41 @@
+/*
+Package midlrate provides a midl.Middleware which throttles
+requests per client.
+
+Usage
+
+A Middleware pairs a Limiter, which tracks the quota of
+each client, with a KeyFunc identifying the client making a
+request.
+
+  limit := midlrate.New(midlrate.NewTokenBucket(100, time.Minute), midlrate.ByIP())
+
+  http.Handle("/", midl.JSONAdapter(limit, NewController()))
+
+Requests over the limit are answered with a 429 (Too Many
+Requests) rendered by the Adapter's ErrorSerializer, with
+Retry-After, RateLimit-Limit, RateLimit-Remaining,
+RateLimit-Reset and RateLimit-Policy headers.  Requests for
+which the KeyFunc returns an empty key are not limited.
+
+Algorithms
+
+NewTokenBucket allows bursts of up to its limit, refilled
+evenly over its period.  NewSlidingWindow allows up to its
+limit within any window of the given length, estimated from
+the counts of the current and previous fixed windows.
+
+Both keep their state in memory, split across shards which
+are locked independently so that concurrent requests for
+different clients rarely contend.  Other backends may be
+used by implementing Limiter.
+
+Keys
+
+Clients may be identified by their address (ByIP,
+ByForwardedIP), an API key sent in a header or query
+parameter (ByHeader, ByQuery), a value stored in the request
+context such as an authenticated principal (ByValue), or any
+custom KeyFunc.
+*/
+package midlrate
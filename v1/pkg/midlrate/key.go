@@ -0,0 +1,129 @@
+package midlrate
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+// KeyFunc identifies the client making a request.  Requests
+// for which the empty string is returned are not limited.
+type KeyFunc func(midl.Request) string
+
+// ByIP identifies clients by the IP address of the remote end
+// of their connection.
+func ByIP() KeyFunc {
+	return func(req midl.Request) string {
+		addr, ok := remoteAddr(req)
+		if !ok {
+			return ""
+		}
+		return addr.String()
+	}
+}
+
+// ByForwardedIP identifies clients by the address they
+// connected to the first of the given trusted proxies from.
+//
+// When the remote end of the connection is a trusted proxy,
+// the X-Forwarded-For header is read from right to left,
+// skipping trusted addresses, and the first untrusted
+// address is used.  Otherwise the remote address is used as
+// with ByIP.
+func ByForwardedIP(trusted ...netip.Prefix) KeyFunc {
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range trusted {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(req midl.Request) string {
+		addr, ok := remoteAddr(req)
+		if !ok {
+			return ""
+		}
+
+		if !isTrusted(addr) {
+			return addr.String()
+		}
+
+		hops, _ := req.Headers("X-Forwarded-For")
+		for i := len(hops) - 1; i >= 0; i-- {
+			parts := strings.Split(hops[i], ",")
+			for j := len(parts) - 1; j >= 0; j-- {
+				hop, err := netip.ParseAddr(strings.TrimSpace(parts[j]))
+				if err != nil {
+					return addr.String()
+				}
+
+				addr = hop.Unmap()
+				if !isTrusted(addr) {
+					return addr.String()
+				}
+			}
+		}
+
+		return addr.String()
+	}
+}
+
+// ByHeader identifies clients by the value of the given
+// request header, such as an API key.
+func ByHeader(name string) KeyFunc {
+	name = http.CanonicalHeaderKey(name)
+
+	return func(req midl.Request) string {
+		val, _ := req.Header(name)
+		return val
+	}
+}
+
+// ByQuery identifies clients by the value of the given query
+// parameter, such as an API key.
+func ByQuery(name string) KeyFunc {
+	return func(req midl.Request) string {
+		val, _ := req.Parameter(name)
+		return val
+	}
+}
+
+// ByValue identifies clients by a value stored in the
+// request context by an earlier Middleware, such as an
+// authenticated principal.
+//
+//   limit := midlrate.New(limiter, midlrate.ByValue(UserKey, func(u *User) string {
+//       return u.ID
+//   }))
+//
+// Requests without a value are not limited.
+func ByValue[T any](key *midl.ContextKey[T], id func(T) string) KeyFunc {
+	return func(req midl.Request) string {
+		val, ok := key.Get(req)
+		if !ok {
+			return ""
+		}
+		return id(val)
+	}
+}
+
+func remoteAddr(req midl.Request) (netip.Addr, bool) {
+	raw := req.RawRequest().RemoteAddr
+
+	host, _, err := net.SplitHostPort(raw)
+	if err != nil {
+		host = raw
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
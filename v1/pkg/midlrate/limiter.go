@@ -0,0 +1,204 @@
+package midlrate
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Decision is the outcome of taking a request from a
+// client's quota.
+type Decision struct {
+
+	// Allowed is whether the request is within the quota.
+	Allowed bool
+
+	// Limit is the number of requests allowed per Window.
+	Limit int
+
+	// Window is the period over which Limit applies.
+	Window time.Duration
+
+	// Remaining is the number of requests left in the quota.
+	Remaining int
+
+	// Reset is the time until the quota is fully restored.
+	Reset time.Duration
+
+	// RetryAfter is the time until the next request would be
+	// allowed, or zero if the request was allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter defines a store of per client rate limit state.
+//
+// Implementations must be safe for concurrent use.
+type Limiter interface {
+
+	// Take counts a request against the quota of the given
+	// key, returning whether it is allowed.
+	Take(ctx context.Context, key string) (Decision, error)
+}
+
+// TokenBucket is an in-memory Limiter allowing bursts of up
+// to its limit, with tokens refilled evenly over its period.
+type TokenBucket struct {
+	limit  int
+	period time.Duration
+	state  *shardedState[tokenBucket]
+	now    func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a new TokenBucket allowing limit
+// requests per period for each key.
+//
+// Panics if limit or period are not positive.
+func NewTokenBucket(limit int, period time.Duration) *TokenBucket {
+	if limit <= 0 || period <= 0 {
+		panic("midlrate: token bucket limit and period must be positive")
+	}
+
+	out := &TokenBucket{limit: limit, period: period, now: time.Now}
+	out.state = newShardedState(func(b *tokenBucket, now time.Time) bool {
+		return now.Sub(b.last) >= period
+	})
+
+	return out
+}
+
+func (t *TokenBucket) Take(_ context.Context, key string) (Decision, error) {
+	now := t.now()
+	limit := float64(t.limit)
+	rate := limit / float64(t.period)
+
+	out := Decision{Limit: t.limit, Window: t.period}
+
+	t.state.with(key, now, func(b *tokenBucket) {
+		if b.last.IsZero() {
+			b.tokens = limit
+		} else if elapsed := now.Sub(b.last); elapsed > 0 {
+			b.tokens = math.Min(limit, b.tokens+float64(elapsed)*rate)
+		}
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			out.Allowed = true
+		} else {
+			out.RetryAfter = ceilDuration((1 - b.tokens) / rate)
+		}
+
+		out.Remaining = int(b.tokens)
+		out.Reset = ceilDuration((limit - b.tokens) / rate)
+	})
+
+	return out, nil
+}
+
+// SlidingWindow is an in-memory Limiter allowing up to its
+// limit within any window of its length.
+//
+// The number of requests in the sliding window is estimated
+// from the counts of the current fixed window and the
+// overlapping share of the previous one.
+type SlidingWindow struct {
+	limit  int
+	window time.Duration
+	state  *shardedState[slidingWindow]
+	now    func() time.Time
+}
+
+type slidingWindow struct {
+	start time.Time
+	prev  int
+	cur   int
+}
+
+// NewSlidingWindow creates a new SlidingWindow allowing
+// limit requests per window for each key.
+//
+// Panics if limit or window are not positive.
+func NewSlidingWindow(limit int, window time.Duration) *SlidingWindow {
+	if limit <= 0 || window <= 0 {
+		panic("midlrate: sliding window limit and length must be positive")
+	}
+
+	out := &SlidingWindow{limit: limit, window: window, now: time.Now}
+	out.state = newShardedState(func(w *slidingWindow, now time.Time) bool {
+		return now.Sub(w.start) >= 2*window
+	})
+
+	return out
+}
+
+func (s *SlidingWindow) Take(_ context.Context, key string) (Decision, error) {
+	now := s.now()
+	start := now.Truncate(s.window)
+	limit := float64(s.limit)
+
+	out := Decision{Limit: s.limit, Window: s.window}
+
+	s.state.with(key, now, func(w *slidingWindow) {
+		switch {
+		case w.start.Equal(start):
+		case w.start.Add(s.window).Equal(start):
+			w.prev, w.cur, w.start = w.cur, 0, start
+		default:
+			w.prev, w.cur, w.start = 0, 0, start
+		}
+
+		elapsed := now.Sub(start)
+		weight := 1 - float64(elapsed)/float64(s.window)
+		count := float64(w.prev)*weight + float64(w.cur)
+
+		if count+1 <= limit {
+			w.cur++
+			count++
+			out.Allowed = true
+		} else {
+			out.RetryAfter = s.retryAfter(w, elapsed)
+		}
+
+		out.Remaining = int(math.Max(0, limit-math.Ceil(count)))
+
+		// Requests counted in the current window still affect
+		// the estimate throughout the next one.
+		switch {
+		case w.cur > 0:
+			out.Reset = 2*s.window - elapsed
+		case w.prev > 0:
+			out.Reset = s.window - elapsed
+		}
+	})
+
+	return out, nil
+}
+
+// retryAfter returns the time until the estimated count of
+// the given window drops enough to allow another request.
+func (s *SlidingWindow) retryAfter(w *slidingWindow, elapsed time.Duration) time.Duration {
+	limit := float64(s.limit)
+
+	// The previous window's share must shrink until the
+	// current count and the new request fit.
+	if w.cur+1 <= s.limit {
+		share := (limit - float64(w.cur) - 1) / float64(w.prev)
+		return ceilDuration((1-share)*float64(s.window)) - elapsed
+	}
+
+	// Otherwise the current window becomes the previous one
+	// and its share must shrink in turn.
+	share := (limit - 1) / float64(w.cur)
+	return s.window - elapsed + ceilDuration((1-share)*float64(s.window))
+}
+
+// ceilDuration converts a fractional number of nanoseconds
+// to a duration, rounding up.
+func ceilDuration(ns float64) time.Duration {
+	return time.Duration(math.Ceil(ns))
+}
@@ -0,0 +1,178 @@
+package midlrate
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (t *testClock) Now() time.Time {
+	return t.now
+}
+
+func (t *testClock) Add(d time.Duration) {
+	t.now = t.now.Add(d)
+}
+
+func newTestClock() *testClock {
+	return &testClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func take(l Limiter, key string, n int) (allowed int, last Decision) {
+	for i := 0; i < n; i++ {
+		last, _ = l.Take(context.Background(), key)
+		if last.Allowed {
+			allowed++
+		}
+	}
+	return
+}
+
+func TestTokenBucket(t *testing.T) {
+	c.Convey("allows bursts up to the limit", t, func() {
+		clock := newTestClock()
+		bucket := NewTokenBucket(10, 10*time.Second)
+		bucket.now = clock.Now
+
+		allowed, last := take(bucket, "a", 12)
+
+		c.So(allowed, c.ShouldEqual, 10)
+		c.So(last.Allowed, c.ShouldBeFalse)
+		c.So(last.Limit, c.ShouldEqual, 10)
+		c.So(last.Window, c.ShouldEqual, 10*time.Second)
+		c.So(last.Remaining, c.ShouldEqual, 0)
+		c.So(last.RetryAfter, c.ShouldEqual, time.Second)
+		c.So(last.Reset, c.ShouldEqual, 10*time.Second)
+	})
+
+	c.Convey("refills tokens over the period", t, func() {
+		clock := newTestClock()
+		bucket := NewTokenBucket(10, 10*time.Second)
+		bucket.now = clock.Now
+
+		take(bucket, "a", 10)
+		clock.Add(2500 * time.Millisecond)
+
+		allowed, last := take(bucket, "a", 3)
+		c.So(allowed, c.ShouldEqual, 2)
+		c.So(last.RetryAfter, c.ShouldEqual, 500*time.Millisecond)
+
+		clock.Add(time.Hour)
+		allowed, last = take(bucket, "a", 1)
+		c.So(allowed, c.ShouldEqual, 1)
+		c.So(last.Remaining, c.ShouldEqual, 9)
+		c.So(last.RetryAfter, c.ShouldEqual, 0)
+	})
+
+	c.Convey("tracks keys separately", t, func() {
+		bucket := NewTokenBucket(1, time.Minute)
+
+		a, _ := take(bucket, "a", 2)
+		b, _ := take(bucket, "b", 2)
+		c.So(a, c.ShouldEqual, 1)
+		c.So(b, c.ShouldEqual, 1)
+	})
+
+	c.Convey("panics on invalid settings", t, func() {
+		c.So(func() { NewTokenBucket(0, time.Second) }, c.ShouldPanic)
+		c.So(func() { NewTokenBucket(1, 0) }, c.ShouldPanic)
+	})
+}
+
+func TestSlidingWindow(t *testing.T) {
+	c.Convey("allows up to the limit per window", t, func() {
+		clock := newTestClock()
+		window := NewSlidingWindow(10, time.Minute)
+		window.now = clock.Now
+
+		allowed, last := take(window, "a", 12)
+
+		c.So(allowed, c.ShouldEqual, 10)
+		c.So(last.Allowed, c.ShouldBeFalse)
+		c.So(last.Remaining, c.ShouldEqual, 0)
+		c.So(last.Reset, c.ShouldEqual, 2*time.Minute)
+		c.So(last.RetryAfter, c.ShouldEqual, 66*time.Second)
+	})
+
+	c.Convey("weights the previous window by its overlap", t, func() {
+		clock := newTestClock()
+		window := NewSlidingWindow(10, time.Minute)
+		window.now = clock.Now
+
+		take(window, "a", 10)
+		clock.Add(90 * time.Second)
+
+		// Half of the previous window's 10 requests remain.
+		allowed, last := take(window, "a", 6)
+		c.So(allowed, c.ShouldEqual, 5)
+		c.So(last.Allowed, c.ShouldBeFalse)
+		c.So(last.RetryAfter, c.ShouldEqual, 6*time.Second)
+
+		clock.Add(last.RetryAfter)
+		allowed, _ = take(window, "a", 1)
+		c.So(allowed, c.ShouldEqual, 1)
+	})
+
+	c.Convey("forgets windows older than the previous one", t, func() {
+		clock := newTestClock()
+		window := NewSlidingWindow(2, time.Minute)
+		window.now = clock.Now
+
+		take(window, "a", 2)
+		clock.Add(2 * time.Minute)
+
+		allowed, last := take(window, "a", 1)
+		c.So(allowed, c.ShouldEqual, 1)
+		c.So(last.Remaining, c.ShouldEqual, 1)
+	})
+
+	c.Convey("panics on invalid settings", t, func() {
+		c.So(func() { NewSlidingWindow(0, time.Second) }, c.ShouldPanic)
+		c.So(func() { NewSlidingWindow(1, 0) }, c.ShouldPanic)
+	})
+}
+
+func TestShardedState(t *testing.T) {
+	c.Convey("removes stale entries", t, func() {
+		state := newShardedState(func(v *int, _ time.Time) bool { return *v == 0 })
+
+		for i := 0; i < sweepInterval*shardCount; i++ {
+			state.with(fmt.Sprint(i), time.Now(), func(*int) {})
+		}
+
+		var held int
+		for i := range state.shards {
+			held += len(state.shards[i].items)
+		}
+		c.So(held, c.ShouldBeLessThan, sweepInterval*shardCount)
+	})
+
+	c.Convey("is safe for concurrent use", t, func() {
+		bucket := NewTokenBucket(1000, time.Hour)
+		var wg sync.WaitGroup
+		var mut sync.Mutex
+		var total int
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				allowed, _ := take(bucket, "shared", 200)
+				mut.Lock()
+				total += allowed
+				mut.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		c.So(total, c.ShouldEqual, 1000)
+	})
+}
@@ -0,0 +1,99 @@
+package midlrate
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+// ErrLimited is the cause of the errors returned for
+// requests over their rate limit.
+var ErrLimited = errors.New("rate limit exceeded")
+
+// DecisionKey holds the Decision made for requests allowed
+// by a Middleware.
+var DecisionKey = midl.NewContextKey[Decision]("midlrate.decision")
+
+// Middleware is a midl.Middleware rejecting requests over the
+// rate limit of the client making them.
+type Middleware struct {
+	limiter  Limiter
+	key      KeyFunc
+	failOpen bool
+}
+
+// New creates a new Middleware taking requests from the
+// quota of the client identified by the given KeyFunc.
+func New(limiter Limiter, key KeyFunc) *Middleware {
+	return &Middleware{limiter: limiter, key: key}
+}
+
+// FailOpen sets whether requests are allowed when the
+// Limiter returns an error.  By default such requests are
+// answered with a 500 wrapping the error.
+func (m *Middleware) FailOpen(open bool) *Middleware {
+	m.failOpen = open
+	return m
+}
+
+// Handle passes requests within their quota on to the next
+// Middleware, storing the Decision at DecisionKey, and
+// answers all others with a 429 (Too Many Requests).
+func (m *Middleware) Handle(req midl.Request) midl.Response {
+	key := m.key(req)
+	if key == "" {
+		return nil
+	}
+
+	dec, err := m.limiter.Take(req.Context(), key)
+	if err != nil {
+		if m.failOpen {
+			return nil
+		}
+		return midl.MakeErrorResponse(http.StatusInternalServerError,
+			midl.NewHTTPError(http.StatusInternalServerError, "rate limiter unavailable").WithCause(err))
+	}
+
+	if dec.Allowed {
+		DecisionKey.Set(req, dec)
+		return nil
+	}
+
+	res := midl.MakeErrorResponse(http.StatusTooManyRequests,
+		midl.NewHTTPError(http.StatusTooManyRequests, ErrLimited.Error()).WithCause(ErrLimited))
+	SetHeaders(res, dec)
+
+	return res
+}
+
+// SetHeaders sets the Retry-After and RateLimit-* headers
+// describing the given decision on the given response.
+//
+// Handlers may use this to report the remaining quota on
+// their own responses.
+//
+//   if dec, ok := midlrate.DecisionKey.Get(req); ok {
+//       midlrate.SetHeaders(res, dec)
+//   }
+func SetHeaders(res midl.Response, dec Decision) midl.Response {
+	res.SetHeader("RateLimit-Limit", strconv.Itoa(dec.Limit)).
+		SetHeader("RateLimit-Remaining", strconv.Itoa(dec.Remaining)).
+		SetHeader("RateLimit-Reset", seconds(dec.Reset)).
+		SetHeader("RateLimit-Policy", strconv.Itoa(dec.Limit)+";w="+seconds(dec.Window))
+
+	if !dec.Allowed {
+		res.SetHeader("Retry-After", seconds(dec.RetryAfter))
+	}
+
+	return res
+}
+
+// seconds formats the given duration as a whole number of
+// seconds, rounding up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
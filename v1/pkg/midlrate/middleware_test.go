@@ -0,0 +1,144 @@
+package midlrate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+type limiterFunc func(ctx context.Context, key string) (Decision, error)
+
+func (l limiterFunc) Take(ctx context.Context, key string) (Decision, error) {
+	return l(ctx, key)
+}
+
+func serve(h http.Handler, edit func(*http.Request)) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://foo.bar/?key=q", nil)
+	if edit != nil {
+		edit(r)
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func newRequest(edit func(*http.Request)) midl.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://foo.bar/?key=q", nil)
+	if edit != nil {
+		edit(r)
+	}
+	req, _ := midl.NewRequest(r)
+	return req
+}
+
+func TestMiddleware(t *testing.T) {
+	ok := midl.MiddlewareFunc(func(req midl.Request) midl.Response {
+		res := midl.MakeResponse(http.StatusOK, "ok")
+		if dec, ok := DecisionKey.Get(req); ok {
+			SetHeaders(res, dec)
+		}
+		return res
+	})
+
+	c.Convey("answers requests over the limit with a 429", t, func() {
+		h := midl.JSONAdapter(New(NewTokenBucket(2, time.Minute), ByIP()), ok)
+
+		first := serve(h, nil)
+		serve(h, nil)
+		w := serve(h, nil)
+
+		c.So(first.Code, c.ShouldEqual, http.StatusOK)
+		c.So(first.Header().Get("RateLimit-Remaining"), c.ShouldEqual, "1")
+		c.So(first.Header().Get("Retry-After"), c.ShouldEqual, "")
+
+		c.So(w.Code, c.ShouldEqual, http.StatusTooManyRequests)
+		c.So(w.Body.String(), c.ShouldEqual, `{"error":"rate limit exceeded"}`)
+		c.So(w.Header().Get("Retry-After"), c.ShouldEqual, "30")
+		c.So(w.Header().Get("RateLimit-Limit"), c.ShouldEqual, "2")
+		c.So(w.Header().Get("RateLimit-Remaining"), c.ShouldEqual, "0")
+		c.So(w.Header().Get("RateLimit-Reset"), c.ShouldEqual, "60")
+		c.So(w.Header().Get("RateLimit-Policy"), c.ShouldEqual, "2;w=60")
+	})
+
+	c.Convey("limits each client separately", t, func() {
+		h := midl.JSONAdapter(New(NewTokenBucket(1, time.Minute), ByIP()), ok)
+
+		c.So(serve(h, nil).Code, c.ShouldEqual, http.StatusOK)
+		c.So(serve(h, func(r *http.Request) { r.RemoteAddr = "10.0.0.1:1234" }).Code, c.ShouldEqual, http.StatusOK)
+		c.So(serve(h, nil).Code, c.ShouldEqual, http.StatusTooManyRequests)
+	})
+
+	c.Convey("does not limit requests without a key", t, func() {
+		h := midl.JSONAdapter(New(NewTokenBucket(1, time.Minute), ByHeader("X-Api-Key")), ok)
+
+		c.So(serve(h, nil).Code, c.ShouldEqual, http.StatusOK)
+		c.So(serve(h, nil).Code, c.ShouldEqual, http.StatusOK)
+	})
+
+	c.Convey("handles limiter errors", t, func() {
+		failing := limiterFunc(func(context.Context, string) (Decision, error) {
+			return Decision{}, errors.New("backend down")
+		})
+
+		w := serve(midl.JSONAdapter(New(failing, ByIP()), ok), nil)
+		c.So(w.Code, c.ShouldEqual, http.StatusInternalServerError)
+		c.So(w.Body.String(), c.ShouldContainSubstring, "rate limiter unavailable")
+		c.So(w.Body.String(), c.ShouldNotContainSubstring, "backend down")
+
+		c.So(serve(midl.JSONAdapter(New(failing, ByIP()).FailOpen(true), ok), nil).Code,
+			c.ShouldEqual, http.StatusOK)
+	})
+}
+
+func TestKeys(t *testing.T) {
+	c.Convey("ByIP uses the remote address", t, func() {
+		c.So(ByIP()(newRequest(func(r *http.Request) { r.RemoteAddr = "[::ffff:10.1.2.3]:80" })), c.ShouldEqual, "10.1.2.3")
+		c.So(ByIP()(newRequest(func(r *http.Request) { r.RemoteAddr = "bad" })), c.ShouldEqual, "")
+	})
+
+	c.Convey("ByForwardedIP skips trusted proxies", t, func() {
+		key := ByForwardedIP(netip.MustParsePrefix("10.0.0.0/8"))
+
+		c.So(key(newRequest(func(r *http.Request) {
+			r.RemoteAddr = "10.0.0.1:80"
+			r.Header.Add("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+			r.Header.Add("X-Forwarded-For", "10.0.0.2")
+		})), c.ShouldEqual, "2.2.2.2")
+
+		c.So(key(newRequest(func(r *http.Request) {
+			r.RemoteAddr = "3.3.3.3:80"
+			r.Header.Set("X-Forwarded-For", "1.1.1.1")
+		})), c.ShouldEqual, "3.3.3.3")
+
+		c.So(key(newRequest(func(r *http.Request) {
+			r.RemoteAddr = "10.0.0.1:80"
+			r.Header.Set("X-Forwarded-For", "junk, 10.0.0.3")
+		})), c.ShouldEqual, "10.0.0.3")
+	})
+
+	c.Convey("ByHeader and ByQuery read API keys", t, func() {
+		req := newRequest(func(r *http.Request) { r.Header.Set("X-Api-Key", "h") })
+
+		c.So(ByHeader("X-Api-Key")(req), c.ShouldEqual, "h")
+		c.So(ByHeader("x-api-key")(req), c.ShouldEqual, "h")
+		c.So(ByQuery("key")(req), c.ShouldEqual, "q")
+	})
+
+	c.Convey("ByValue reads context values", t, func() {
+		userKey := midl.NewContextKey[string]("user")
+		key := ByValue(userKey, func(u string) string { return "user:" + u })
+		req := newRequest(nil)
+
+		c.So(key(req), c.ShouldEqual, "")
+		userKey.Set(req, "bob")
+		c.So(key(req), c.ShouldEqual, "user:bob")
+	})
+}
@@ -0,0 +1,77 @@
+package midlrate
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// shardCount is the number of independently locked
+	// shards state is split across.
+	shardCount = 64
+
+	// sweepInterval is the number of operations on a shard
+	// between removals of its stale entries.
+	sweepInterval = 1024
+)
+
+// shardedState holds per key limiter state, split across
+// shards by a hash of the key.
+type shardedState[T any] struct {
+	shards [shardCount]stateShard[T]
+	stale  func(*T, time.Time) bool
+}
+
+type stateShard[T any] struct {
+	mut   sync.Mutex
+	items map[string]*T
+	ops   int
+}
+
+// newShardedState creates a new shardedState removing the
+// entries for which stale returns true.  Stale entries must
+// be equivalent to the zero value of T.
+func newShardedState[T any](stale func(*T, time.Time) bool) *shardedState[T] {
+	return &shardedState[T]{stale: stale}
+}
+
+// with calls fn with the state of the given key while
+// holding its shard's lock, creating a zero state if none
+// exists.
+func (s *shardedState[T]) with(key string, now time.Time, fn func(*T)) {
+	shard := &s.shards[hashKey(key)%shardCount]
+
+	shard.mut.Lock()
+	defer shard.mut.Unlock()
+
+	if shard.items == nil {
+		shard.items = map[string]*T{}
+	}
+
+	if shard.ops++; shard.ops >= sweepInterval {
+		shard.ops = 0
+		for k, v := range shard.items {
+			if s.stale(v, now) {
+				delete(shard.items, k)
+			}
+		}
+	}
+
+	item, ok := shard.items[key]
+	if !ok {
+		item = new(T)
+		shard.items[key] = item
+	}
+
+	fn(item)
+}
+
+// hashKey returns the 32 bit FNV-1a hash of the given key.
+func hashKey(key string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return hash
+}
@@ -0,0 +1,29 @@
+/*
+Package midlschema provides midl.Middleware implementations
+which validate request bodies and query parameters against
+JSON Schema documents.
+
+Usage
+
+Schema documents may be loaded from strings, files or any
+fs.FS such as an embed.FS.
+
+  //go:embed schemas
+  var schemas embed.FS
+
+  router.Route(http.MethodPost, "/users",
+      midlschema.MustBody(midlschema.FS(schemas, "schemas/user.json")),
+      midlschema.MustQuery(midlschema.String(`{"properties":{"dry":{"type":"boolean"}}}`)),
+      NewUserController())
+
+Compiled schemas are cached by document content, so the
+same document is only compiled once regardless of how many
+middlewares use it.
+
+Invalid input is reported as a *midl.ValidationError with
+one midl.FieldError per violation.  Body violations are
+identified by a JSON pointer into the request body, and
+query violations by a JSON pointer built from the parameter
+name.
+*/
+package midlschema
@@ -0,0 +1,107 @@
+package midlschema
+
+import (
+	"net/http"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+// Body returns a midl.Middleware which validates request
+// bodies against the schema document provided by the given
+// Source.
+//
+// Valid requests are passed on to the next Middleware.
+// Invalid requests are answered with a *midl.ValidationError
+// (400) rendered by the Adapter's ErrorSerializer, and
+// bodies which cannot be read with the error encountered
+// reading them (400, or 413 if the body is too large).
+//
+// Returns an error if the schema cannot be loaded or
+// compiled.
+func Body(src Source) (midl.Middleware, error) {
+	schema, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return bodyValidator{schema}, nil
+}
+
+// MustBody is like Body but panics if the schema cannot be
+// loaded or compiled.
+func MustBody(src Source) midl.Middleware {
+	out, err := Body(src)
+	if err != nil {
+		panic(err)
+	}
+
+	return out
+}
+
+// Query returns a midl.Middleware which validates request
+// query parameters against the schema document provided by
+// the given Source.
+//
+// The query parameters are validated as a JSON object.
+// Parameters whose top level property is declared as an
+// array receive every value, all others their first value.
+// Values declared as numbers, integers or booleans are
+// converted before validation.
+//
+// Invalid requests are answered with a *midl.ValidationError
+// (400) rendered by the Adapter's ErrorSerializer.
+//
+// Returns an error if the schema cannot be loaded or
+// compiled.
+func Query(src Source) (midl.Middleware, error) {
+	schema, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return queryValidator{schema}, nil
+}
+
+// MustQuery is like Query but panics if the schema cannot be
+// loaded or compiled.
+func MustQuery(src Source) midl.Middleware {
+	out, err := Query(src)
+	if err != nil {
+		panic(err)
+	}
+
+	return out
+}
+
+type bodyValidator struct {
+	schema *Schema
+}
+
+func (v bodyValidator) Handle(req midl.Request) midl.Response {
+	body := req.Body()
+	if err := req.Error(); err != nil {
+		return midl.MakeErrorResponse(http.StatusBadRequest, err)
+	}
+
+	return invalid(v.schema.ValidateJSON("body", body))
+}
+
+type queryValidator struct {
+	schema *Schema
+}
+
+func (v queryValidator) Handle(req midl.Request) midl.Response {
+	query := req.RawRequest().URL.Query()
+
+	return invalid(v.schema.ValidateValue("query", queryDocument(query, v.schema.params)))
+}
+
+// invalid returns an error response for the given field
+// errors, or nil if there are none.
+func invalid(fields []midl.FieldError) midl.Response {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return midl.MakeErrorResponse(http.StatusBadRequest, &midl.ValidationError{Fields: fields})
+}
@@ -0,0 +1,101 @@
+package midlschema
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+func serve(method, url, body string, validators ...midl.Middleware) (*httptest.ResponseRecorder, map[string]interface{}) {
+	ok := midl.MiddlewareFunc(func(midl.Request) midl.Response {
+		return midl.MakeResponse(http.StatusOK, "ok")
+	})
+
+	rec := httptest.NewRecorder()
+	midl.JSONAdapter(append(validators, ok)...).
+		ServeHTTP(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+
+	var out map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec, out
+}
+
+func TestBody(t *testing.T) {
+	validator := MustBody(File("testdata/user.json"))
+
+	c.Convey("passes valid bodies on", t, func() {
+		rec, _ := serve(http.MethodPost, "/", `{"name":"foo"}`, validator)
+
+		c.So(rec.Code, c.ShouldEqual, http.StatusOK)
+	})
+
+	c.Convey("renders violations through the error serializer", t, func() {
+		rec, out := serve(http.MethodPost, "/", `{"name":""}`, validator)
+
+		c.So(rec.Code, c.ShouldEqual, http.StatusBadRequest)
+		c.So(out["code"], c.ShouldEqual, "invalid_request")
+		c.So(out["details"], c.ShouldHaveLength, 1)
+		c.So(out["details"].([]interface{})[0].(map[string]interface{})["field"], c.ShouldEqual, "/name")
+	})
+
+	c.Convey("reports oversized bodies as 413", t, func() {
+		rec := httptest.NewRecorder()
+		midl.JSONAdapter(validator).MaxBodySize(4).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"foo"}`)))
+
+		c.So(rec.Code, c.ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+
+	c.Convey("returns schema errors on construction", t, func() {
+		_, err := Body(String("nope"))
+
+		c.So(err, c.ShouldBeError)
+		c.So(func() { MustBody(String("nope")) }, c.ShouldPanic)
+	})
+}
+
+func TestQuery(t *testing.T) {
+	validator := MustQuery(String(`{
+		"properties": {
+			"page": {"type": "integer", "minimum": 1},
+			"debug": {"type": "boolean"},
+			"tag": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+		},
+		"required": ["page"]
+	}`))
+
+	c.Convey("converts and validates query parameters", t, func() {
+		rec, _ := serve(http.MethodGet, "/?page=2&debug=true&tag=a&tag=b", "", validator)
+
+		c.So(rec.Code, c.ShouldEqual, http.StatusOK)
+	})
+
+	c.Convey("reports invalid query parameters", t, func() {
+		rec, out := serve(http.MethodGet, "/?page=x&debug=maybe&tag=a&tag=b&tag=c", "", validator)
+
+		c.So(rec.Code, c.ShouldEqual, http.StatusBadRequest)
+
+		fields := map[string]string{}
+		for _, f := range out["details"].([]interface{}) {
+			f := f.(map[string]interface{})
+			c.So(f["in"], c.ShouldEqual, "query")
+			fields[f["field"].(string)] = f["message"].(string)
+		}
+		c.So(fields, c.ShouldContainKey, "/page")
+		c.So(fields, c.ShouldContainKey, "/debug")
+		c.So(fields, c.ShouldContainKey, "/tag")
+	})
+
+	c.Convey("reports missing query parameters", t, func() {
+		rec, out := serve(http.MethodGet, "/", "", validator)
+
+		c.So(rec.Code, c.ShouldEqual, http.StatusBadRequest)
+		c.So(out["details"].([]interface{})[0].(map[string]interface{})["field"], c.ShouldEqual, "/page")
+	})
+}
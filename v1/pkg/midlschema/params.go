@@ -0,0 +1,116 @@
+package midlschema
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// paramType holds the declared JSON types of a top level
+// schema property, used to convert query parameter strings
+// before validation.
+type paramType struct {
+	types []string
+	items []string
+}
+
+func (p paramType) is(kind string) bool {
+	return hasType(p.types, kind)
+}
+
+// parseParamTypes reads the declared types of the top level
+// properties of the given schema document.
+func parseParamTypes(doc []byte) map[string]paramType {
+	type typed struct {
+		Type json.RawMessage `json:"type"`
+	}
+
+	var raw struct {
+		Properties map[string]struct {
+			typed
+			Items *typed `json:"items"`
+		} `json:"properties"`
+	}
+
+	if json.Unmarshal(doc, &raw) != nil {
+		return nil
+	}
+
+	out := make(map[string]paramType, len(raw.Properties))
+	for name, prop := range raw.Properties {
+		param := paramType{types: parseTypes(prop.Type)}
+		if prop.Items != nil {
+			param.items = parseTypes(prop.Items.Type)
+		}
+		out[name] = param
+	}
+
+	return out
+}
+
+func parseTypes(raw json.RawMessage) []string {
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return []string{one}
+	}
+
+	var many []string
+	_ = json.Unmarshal(raw, &many)
+	return many
+}
+
+func hasType(types []string, kind string) bool {
+	for _, t := range types {
+		if t == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// queryDocument converts the given query parameters into a
+// JSON object for validation.
+//
+// Parameters declared as arrays become arrays of every
+// value, all others use their first value.  Values declared
+// as numbers, integers or booleans are converted when they
+// parse as such; values which do not parse are left as
+// strings so that the schema reports the type mismatch.
+func queryDocument(query url.Values, params map[string]paramType) map[string]interface{} {
+	out := make(map[string]interface{}, len(query))
+
+	for name, values := range query {
+		param := params[name]
+
+		if param.is("array") {
+			items := make([]interface{}, len(values))
+			for i, value := range values {
+				items[i] = convertParam(value, param.items)
+			}
+			out[name] = items
+			continue
+		}
+
+		out[name] = convertParam(values[0], param.types)
+	}
+
+	return out
+}
+
+func convertParam(value string, types []string) interface{} {
+	if hasType(types, "integer") || hasType(types, "number") {
+		var num float64
+		if json.Unmarshal([]byte(value), &num) == nil {
+			return num
+		}
+	}
+
+	if hasType(types, "boolean") {
+		if val, err := strconv.ParseBool(value); err == nil {
+			return val
+		}
+	}
+
+	return value
+}
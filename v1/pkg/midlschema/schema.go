@@ -0,0 +1,138 @@
+package midlschema
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	gjs "github.com/xeipuuv/gojsonschema"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+// cache holds compiled schemas keyed by the SHA-256 sum of
+// their source document.
+var cache sync.Map
+
+// Schema is a compiled JSON Schema document.
+type Schema struct {
+	schema *gjs.Schema
+	params map[string]paramType
+}
+
+// Compile loads and compiles the schema document provided
+// by the given Source.
+//
+// Compiled schemas are cached by document content; compiling
+// an identical document again returns the cached Schema.
+func Compile(src Source) (*Schema, error) {
+	doc, err := src.Load()
+	if err != nil {
+		return nil, fmt.Errorf("midlschema: failed to load schema: %w", err)
+	}
+
+	key := sha256.Sum256(doc)
+	if out, ok := cache.Load(key); ok {
+		return out.(*Schema), nil
+	}
+
+	schema, err := gjs.NewSchema(gjs.NewBytesLoader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("midlschema: failed to compile schema: %w", err)
+	}
+
+	out := &Schema{schema: schema, params: parseParamTypes(doc)}
+	actual, _ := cache.LoadOrStore(key, out)
+
+	return actual.(*Schema), nil
+}
+
+// MustCompile is like Compile but panics if the schema
+// cannot be loaded or compiled.
+func MustCompile(src Source) *Schema {
+	out, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+
+	return out
+}
+
+// ValidateJSON validates the given raw JSON document against
+// this Schema.
+//
+// Returns one FieldError per violation, with the Field set
+// to a JSON pointer identifying the invalid value, and the
+// In set to the given source name.  A document which is not
+// valid JSON is reported as a single FieldError for the
+// document root.
+func (s *Schema) ValidateJSON(in string, doc []byte) []midl.FieldError {
+	var val interface{}
+	if err := json.Unmarshal(doc, &val); err != nil {
+		return []midl.FieldError{{In: in, Field: "", Message: "must be valid JSON"}}
+	}
+
+	return s.validate(in, gjs.NewGoLoader(val))
+}
+
+// ValidateValue validates the given Go value against this
+// Schema.  The value is converted with encoding/json before
+// validation.
+//
+// Returns one FieldError per violation as with ValidateJSON.
+func (s *Schema) ValidateValue(in string, val interface{}) []midl.FieldError {
+	return s.validate(in, gjs.NewGoLoader(val))
+}
+
+func (s *Schema) validate(in string, loader gjs.JSONLoader) []midl.FieldError {
+	res, err := s.schema.Validate(loader)
+	if err != nil {
+		return []midl.FieldError{{In: in, Field: "", Message: "must be valid JSON"}}
+	}
+
+	out := make([]midl.FieldError, 0, len(res.Errors()))
+	for _, e := range res.Errors() {
+		out = append(out, midl.FieldError{
+			In:      in,
+			Field:   pointer(e),
+			Message: e.Description(),
+		})
+	}
+
+	return out
+}
+
+// pointer builds an RFC 6901 JSON pointer identifying the
+// value which caused the given validation error.
+//
+// Missing required properties are identified by the pointer
+// to where the property should have been.
+func pointer(e gjs.ResultError) string {
+	var parts []string
+
+	if e.Context() != nil {
+		parts = strings.Split(e.Context().String("\x00"), "\x00")[1:]
+	}
+
+	if e.Type() == "required" {
+		if prop, ok := e.Details()["property"].(string); ok {
+			parts = append(parts, prop)
+		}
+	}
+
+	var out strings.Builder
+	for _, part := range parts {
+		out.WriteByte('/')
+		out.WriteString(escapePointer(part))
+	}
+
+	return out.String()
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escapePointer(in string) string {
+	return pointerEscaper.Replace(in)
+}
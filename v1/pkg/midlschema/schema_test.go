@@ -0,0 +1,81 @@
+package midlschema
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	c "github.com/smartystreets/goconvey/convey"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+func TestCompile(t *testing.T) {
+	c.Convey("loads schemas from files", t, func() {
+		schema, err := Compile(File("testdata/user.json"))
+
+		c.So(err, c.ShouldBeNil)
+		c.So(schema, c.ShouldNotBeNil)
+	})
+
+	c.Convey("loads schemas from file systems", t, func() {
+		doc, _ := os.ReadFile("testdata/user.json")
+		fsys := fstest.MapFS{"user.json": {Data: doc}}
+
+		schema, err := Compile(FS(fsys, "user.json"))
+
+		c.So(err, c.ShouldBeNil)
+		c.So(schema, c.ShouldEqual, MustCompile(File("testdata/user.json")))
+	})
+
+	c.Convey("caches schemas by content", t, func() {
+		a := MustCompile(String(`{"type":"string"}`))
+		b := MustCompile(String(`{"type":"string"}`))
+		d := MustCompile(String(`{"type":"number"}`))
+
+		c.So(a, c.ShouldEqual, b)
+		c.So(a, c.ShouldNotEqual, d)
+	})
+
+	c.Convey("returns load errors", t, func() {
+		_, err := Compile(File("testdata/missing.json"))
+
+		c.So(errors.Is(err, os.ErrNotExist), c.ShouldBeTrue)
+	})
+
+	c.Convey("returns compile errors", t, func() {
+		_, err := Compile(String(`{"type":`))
+
+		c.So(err, c.ShouldBeError)
+		c.So(func() { MustCompile(String(`{"type":`)) }, c.ShouldPanic)
+	})
+}
+
+func TestSchema_ValidateJSON(t *testing.T) {
+	schema := MustCompile(File("testdata/user.json"))
+
+	c.Convey("accepts valid documents", t, func() {
+		c.So(schema.ValidateJSON("body", []byte(`{"name":"foo","tags":["a"]}`)), c.ShouldBeEmpty)
+	})
+
+	c.Convey("reports violations as JSON pointers", t, func() {
+		out := schema.ValidateJSON("body", []byte(`{"tags":["a",1],"a/b":"x"}`))
+
+		c.So(out, c.ShouldHaveLength, 3)
+		fields := map[string]midl.FieldError{}
+		for _, f := range out {
+			fields[f.Field] = f
+		}
+		c.So(fields, c.ShouldContainKey, "/name")
+		c.So(fields, c.ShouldContainKey, "/tags/1")
+		c.So(fields, c.ShouldContainKey, "/a~1b")
+		c.So(fields["/name"].In, c.ShouldEqual, "body")
+		c.So(fields["/name"].Message, c.ShouldEqual, "name is required")
+	})
+
+	c.Convey("reports malformed documents", t, func() {
+		c.So(schema.ValidateJSON("body", []byte(`{`)), c.ShouldResemble,
+			[]midl.FieldError{{In: "body", Message: "must be valid JSON"}})
+	})
+}
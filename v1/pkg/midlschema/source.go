@@ -0,0 +1,47 @@
+package midlschema
+
+import (
+	"io/fs"
+	"os"
+)
+
+// Source defines a provider of a raw JSON Schema document.
+type Source interface {
+
+	// Load returns the raw schema document.
+	Load() ([]byte, error)
+}
+
+// SourceFunc is a convenience wrapper which allows the use
+// of a function as a Source implementation.
+type SourceFunc func() ([]byte, error)
+
+// Load is a simple passthrough for the wrapped function.
+func (s SourceFunc) Load() ([]byte, error) {
+	return s()
+}
+
+// String returns a Source providing the given schema
+// document.
+func String(doc string) Source {
+	return SourceFunc(func() ([]byte, error) {
+		return []byte(doc), nil
+	})
+}
+
+// File returns a Source which reads the schema document
+// from the file at the given path.
+func File(path string) Source {
+	return SourceFunc(func() ([]byte, error) {
+		return os.ReadFile(path)
+	})
+}
+
+// FS returns a Source which reads the schema document from
+// the named file in the given file system, such as an
+// embed.FS.
+func FS(fsys fs.FS, name string) Source {
+	return SourceFunc(func() ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	})
+}
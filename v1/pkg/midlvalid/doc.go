@@ -0,0 +1,58 @@
+/*
+Package midlvalid provides validation of decoded request
+input using struct tags.
+
+Usage
+
+Rules are listed in a "validate" struct tag, separated by
+commas, with an optional parameter following an equals
+sign.
+
+  type User struct {
+      Name     string            `json:"name" validate:"required,min=1,max=64"`
+      Email    string            `json:"email" validate:"required,email"`
+      Role     string            `json:"role" validate:"oneof=admin user"`
+      Password string            `json:"password" validate:"required,min=8"`
+      Confirm  string            `json:"confirm" validate:"eqfield=Password"`
+      Tags     []string          `json:"tags" validate:"max=8,dive,min=1"`
+      Address  *Address          `json:"address" validate:"required"`
+      Labels   map[string]string `json:"labels"`
+  }
+
+Nested structs, including those held in slices, arrays,
+maps and pointers, are validated recursively.  The "dive"
+rule applies the rules following it to every element of a
+slice, array or map instead of the value itself.
+
+Validation is typically chained after decoding with
+Request.ProcessBody:
+
+  err := req.ProcessBody(midl.BodyProcessorFunc(func(in []byte) error {
+      return json.Unmarshal(in, &user)
+  })).ProcessBody(midlvalid.Struct(&user)).Error()
+
+Invalid values are reported as a *midl.ValidationError with
+status 422 (Unprocessable Entity), containing one
+midl.FieldError per invalid field identified by its path,
+such as "address.city" or "tags[2]".  Field names are taken
+from "json" struct tags where present.
+
+Built-in rules
+
+  required          value must not be zero, empty or nil
+  required_with=F   value is required if sibling field F is not zero
+  omitempty         skip the remaining rules if the value is zero
+  min=N / max=N     minimum / maximum number, length or item count
+  len=N             exact length or item count
+  email             value must be a bare email address
+  oneof=A B C       value must be one of the space separated options
+  eqfield=F         value must equal sibling field F
+  nefield=F         value must not equal sibling field F
+  gtfield=F         value must be greater than sibling field F
+  ltfield=F         value must be less than sibling field F
+  dive              apply the remaining rules to each element
+
+Custom rules may be registered on a Validator with the Rule
+method.
+*/
+package midlvalid
@@ -0,0 +1,356 @@
+package midlvalid
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Field describes a value being checked by a Rule.
+type Field struct {
+
+	// Name is the Go name of the struct field.
+	Name string
+
+	// Path is the path of the value reported in errors, such
+	// as "address.city" or "tags[2]".
+	Path string
+
+	// Value is the value being checked.  Pointers are
+	// dereferenced before any rule other than a presence rule
+	// such as "required" is run.
+	Value reflect.Value
+
+	// Param is the parameter given to the rule in the
+	// validate tag, or the empty string.
+	Param string
+
+	// Parent is the struct containing the field, for use by
+	// cross-field rules.
+	Parent reflect.Value
+}
+
+// Sibling returns the value of the named field of the struct
+// containing this Field, or false if there is no such field.
+func (f Field) Sibling(name string) (reflect.Value, bool) {
+	if !f.Parent.IsValid() {
+		return reflect.Value{}, false
+	}
+
+	out := f.Parent.FieldByName(name)
+	return out, out.IsValid()
+}
+
+// Rule defines a single validation rule usable in validate
+// struct tags.
+type Rule interface {
+
+	// Check returns an error describing why the given field
+	// is invalid, or nil if it is valid.  The error message
+	// is reported to the client.
+	Check(Field) error
+}
+
+// RuleFunc is a convenience wrapper which allows the use of a
+// function as a Rule implementation.
+type RuleFunc func(Field) error
+
+// Check is a simple passthrough for the wrapped function.
+func (r RuleFunc) Check(f Field) error {
+	return r(f)
+}
+
+// presenceRules names the built-in rules which are checked
+// against the raw field value before nil pointers are
+// skipped and omitempty is applied.
+var presenceRules = map[string]struct{}{
+	"required":      {},
+	"required_with": {},
+}
+
+var builtins = map[string]Rule{
+	"required":      RuleFunc(required),
+	"required_with": RuleFunc(requiredWith),
+	"min":           RuleFunc(minimum),
+	"max":           RuleFunc(maximum),
+	"len":           RuleFunc(length),
+	"email":         RuleFunc(email),
+	"oneof":         RuleFunc(oneOf),
+	"eqfield":       fieldRule(func(c int) bool { return c == 0 }, "must equal %s"),
+	"nefield":       fieldRule(func(c int) bool { return c != 0 }, "must not equal %s"),
+	"gtfield":       fieldRule(func(c int) bool { return c > 0 }, "must be greater than %s"),
+	"ltfield":       fieldRule(func(c int) bool { return c < 0 }, "must be less than %s"),
+}
+
+// paramCheck validates the parameter of a rule applied to a
+// value of the given type, in a struct of the given parent
+// type, when the validate tag is parsed.
+type paramCheck func(parent, typ reflect.Type, param string) error
+
+// paramChecks holds the parse time checks of the built-in
+// rules.  Rules replaced with Validator.Rule lose their
+// check.
+var paramChecks = map[string]paramCheck{
+	"required_with": checkSibling,
+	"min":           checkBound,
+	"max":           checkBound,
+	"len":           checkBound,
+	"eqfield":       checkSibling,
+	"nefield":       checkSibling,
+	"gtfield":       checkSibling,
+	"ltfield":       checkSibling,
+}
+
+func required(f Field) error {
+	if isMissing(f.Value) {
+		return errors.New("is required")
+	}
+	return nil
+}
+
+func requiredWith(f Field) error {
+	other, ok := f.Sibling(f.Param)
+	if !ok {
+		return fmt.Errorf("unknown field %s", f.Param)
+	}
+
+	if !isMissing(other) && isMissing(f.Value) {
+		return fmt.Errorf("is required when %s is present", fieldName(f, f.Param))
+	}
+	return nil
+}
+
+// isMissing returns whether the given value is nil or, for
+// non-pointer values, empty.  Non-nil pointers are always
+// present.
+func isMissing(val reflect.Value) bool {
+	if val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		return val.IsNil()
+	}
+
+	return isEmpty(val)
+}
+
+func minimum(f Field) error {
+	return bound(f, "min", func(c int) bool { return c >= 0 }, "at least")
+}
+
+func maximum(f Field) error {
+	return bound(f, "max", func(c int) bool { return c <= 0 }, "at most")
+}
+
+func length(f Field) error {
+	return bound(f, "len", func(c int) bool { return c == 0 }, "exactly")
+}
+
+// bound compares the size of the given field with the rule
+// parameter, where size is the rune count of strings, the
+// length of collections and the value of numbers.
+func bound(f Field, rule string, ok func(int) bool, desc string) error {
+	switch f.Value.Kind() {
+	case reflect.String:
+		limit, err := strconv.Atoi(f.Param)
+		if err != nil {
+			return fmt.Errorf("invalid %s parameter %q", rule, f.Param)
+		}
+		if !ok(compareInts(utf8.RuneCountInString(f.Value.String()), limit)) {
+			return fmt.Errorf("must be %s %d characters long", desc, limit)
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		limit, err := strconv.Atoi(f.Param)
+		if err != nil {
+			return fmt.Errorf("invalid %s parameter %q", rule, f.Param)
+		}
+		if !ok(compareInts(f.Value.Len(), limit)) {
+			return fmt.Errorf("must contain %s %d items", desc, limit)
+		}
+	default:
+		limit, err := parseLimit(f.Value.Type(), f.Param)
+		if err != nil {
+			return fmt.Errorf("invalid %s parameter %q", rule, f.Param)
+		}
+		cmp, comparable := compare(f.Value, limit)
+		if !comparable {
+			return fmt.Errorf("%s cannot be applied to %s", rule, f.Value.Type())
+		}
+		if !ok(cmp) {
+			return fmt.Errorf("must be %s %s", desc, f.Param)
+		}
+	}
+
+	return nil
+}
+
+// checkBound checks that the parameter of a min, max or len
+// rule is a valid limit for the given type.  Interface types
+// are only known when validating and are not checked.
+func checkBound(_, typ reflect.Type, param string) error {
+	switch {
+	case typ.Kind() == reflect.Interface:
+		return nil
+	case typ.Kind() == reflect.String, typ.Kind() == reflect.Slice,
+		typ.Kind() == reflect.Array, typ.Kind() == reflect.Map:
+		if _, err := strconv.Atoi(param); err != nil {
+			return fmt.Errorf("invalid parameter %q", param)
+		}
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Float64:
+		if _, err := parseLimit(typ, param); err != nil {
+			return fmt.Errorf("invalid parameter %q", param)
+		}
+	default:
+		return fmt.Errorf("cannot be applied to %s", typ)
+	}
+
+	return nil
+}
+
+// checkSibling checks that the parameter of a cross-field
+// rule names a field of the parent struct.
+func checkSibling(parent, _ reflect.Type, param string) error {
+	if _, ok := parent.FieldByName(param); !ok {
+		return fmt.Errorf("unknown field %q", param)
+	}
+	return nil
+}
+
+func email(f Field) error {
+	if f.Value.Kind() != reflect.String {
+		return fmt.Errorf("email cannot be applied to %s", f.Value.Type())
+	}
+
+	addr, err := mail.ParseAddress(f.Value.String())
+	if err != nil || addr.Name != "" || addr.Address != f.Value.String() {
+		return errors.New("must be a valid email address")
+	}
+	return nil
+}
+
+func oneOf(f Field) error {
+	options := strings.Fields(f.Param)
+	value := fmt.Sprint(f.Value.Interface())
+
+	for _, option := range options {
+		if option == value {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("must be one of: %s", strings.Join(options, ", "))
+}
+
+// fieldRule returns a Rule comparing the field value with a
+// sibling field named by the rule parameter.
+func fieldRule(ok func(int) bool, message string) Rule {
+	return RuleFunc(func(f Field) error {
+		other, found := f.Sibling(f.Param)
+		if !found {
+			return fmt.Errorf("unknown field %s", f.Param)
+		}
+
+		other = deref(other)
+		if !other.IsValid() {
+			return nil
+		}
+
+		cmp, comparable := compare(f.Value, other)
+		if !comparable {
+			cmp = 1
+			if reflect.DeepEqual(f.Value.Interface(), other.Interface()) {
+				cmp = 0
+			}
+		}
+
+		if !ok(cmp) {
+			return fmt.Errorf(message, fieldName(f, f.Param))
+		}
+		return nil
+	})
+}
+
+// fieldName returns the reported name of the named sibling
+// field of the given Field.
+func fieldName(f Field, name string) string {
+	if field, ok := f.Parent.Type().FieldByName(name); ok {
+		if json := jsonName(field); json != "" && json != "-" {
+			return json
+		}
+	}
+	return name
+}
+
+// parseLimit parses a rule parameter into a value comparable
+// with values of the given type.
+func parseLimit(typ reflect.Type, param string) (reflect.Value, error) {
+	switch {
+	case typ == durationType:
+		val, err := time.ParseDuration(param)
+		return reflect.ValueOf(val), err
+	case typ.Kind() >= reflect.Int && typ.Kind() <= reflect.Int64:
+		val, err := strconv.ParseInt(param, 10, 64)
+		return reflect.ValueOf(val), err
+	case typ.Kind() >= reflect.Uint && typ.Kind() <= reflect.Uint64:
+		val, err := strconv.ParseUint(param, 10, 64)
+		return reflect.ValueOf(val), err
+	}
+
+	val, err := strconv.ParseFloat(param, 64)
+	return reflect.ValueOf(val), err
+}
+
+// compare orders two numbers, strings or times.  Returns
+// false if the values cannot be ordered.
+func compare(a, b reflect.Value) (int, bool) {
+	switch {
+	case a.Type() == timeType && b.Type() == timeType:
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time)), true
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return strings.Compare(a.String(), b.String()), true
+	}
+
+	x, ok := toFloat(a)
+	if !ok {
+		return 0, false
+	}
+
+	y, ok := toFloat(b)
+	if !ok {
+		return 0, false
+	}
+
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+func toFloat(val reflect.Value) (float64, bool) {
+	switch {
+	case val.Kind() >= reflect.Int && val.Kind() <= reflect.Int64:
+		return float64(val.Int()), true
+	case val.Kind() >= reflect.Uint && val.Kind() <= reflect.Uintptr:
+		return float64(val.Uint()), true
+	case val.Kind() == reflect.Float32 || val.Kind() == reflect.Float64:
+		return val.Float(), true
+	}
+	return 0, false
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package midlvalid
+
+import (
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRules(t *testing.T) {
+	c.Convey("min and max compare numbers", t, func() {
+		type in struct {
+			Age   int           `json:"age" validate:"min=18,max=130"`
+			Score float64       `json:"score" validate:"max=1.5"`
+			Wait  time.Duration `json:"wait" validate:"max=1m"`
+		}
+
+		c.So(Validate(in{Age: 18, Score: 1.5, Wait: time.Minute}), c.ShouldBeNil)
+		c.So(fieldErrors(Validate(in{Age: 17, Score: 2, Wait: time.Hour})), c.ShouldResemble, map[string]string{
+			"age":   "must be at least 18",
+			"score": "must be at most 1.5",
+			"wait":  "must be at most 1m",
+		})
+	})
+
+	c.Convey("len checks exact lengths", t, func() {
+		type in struct {
+			Code string `json:"code" validate:"len=3"`
+		}
+
+		c.So(Validate(in{Code: "äbc"}), c.ShouldBeNil)
+		c.So(fieldErrors(Validate(in{Code: "ab"})), c.ShouldResemble,
+			map[string]string{"code": "must be exactly 3 characters long"})
+	})
+
+	c.Convey("omitempty skips zero values", t, func() {
+		type in struct {
+			Email *string `json:"email" validate:"omitempty,email"`
+			Size  int     `json:"size" validate:"omitempty,min=10"`
+		}
+
+		empty := ""
+		c.So(Validate(in{}), c.ShouldBeNil)
+		c.So(Validate(in{Email: &empty}), c.ShouldBeNil)
+		c.So(Validate(in{Size: 5}), c.ShouldBeError)
+	})
+
+	c.Convey("required_with requires values alongside siblings", t, func() {
+		type in struct {
+			Street string `json:"street"`
+			City   string `json:"city" validate:"required_with=Street"`
+		}
+
+		c.So(Validate(in{}), c.ShouldBeNil)
+		c.So(Validate(in{Street: "a", City: "b"}), c.ShouldBeNil)
+		c.So(fieldErrors(Validate(in{Street: "a"})), c.ShouldResemble,
+			map[string]string{"city": "is required when street is present"})
+	})
+
+	c.Convey("field comparisons order numbers and times", t, func() {
+		now := time.Now()
+		type in struct {
+			Low   int       `json:"low"`
+			High  int       `json:"high" validate:"gtfield=Low"`
+			Start time.Time `json:"start"`
+			End   time.Time `json:"end" validate:"gtfield=Start"`
+			Prev  string    `json:"prev"`
+			Next  string    `json:"next" validate:"nefield=Prev"`
+		}
+
+		c.So(Validate(in{Low: 1, High: 2, Start: now, End: now.Add(time.Second), Next: "a"}), c.ShouldBeNil)
+		c.So(fieldErrors(Validate(in{Low: 2, High: 2, Start: now, End: now, Prev: "a", Next: "a"})),
+			c.ShouldResemble, map[string]string{
+				"high": "must be greater than low",
+				"end":  "must be greater than start",
+				"next": "must not equal prev",
+			})
+	})
+
+	c.Convey("oneof matches numbers", t, func() {
+		type in struct {
+			Level int `json:"level" validate:"oneof=1 2 3"`
+		}
+
+		c.So(Validate(in{Level: 2}), c.ShouldBeNil)
+		c.So(Validate(in{Level: 4}), c.ShouldBeError)
+	})
+}
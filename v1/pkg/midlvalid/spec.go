@@ -0,0 +1,158 @@
+package midlvalid
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// fieldSpec holds the parsed validate tag of a struct field.
+type fieldSpec struct {
+	index     []int
+	name      string
+	goName    string
+	embedded  bool
+	omitEmpty bool
+	presence  []ruleCall
+	rules     []ruleCall
+	dive      []ruleCall
+}
+
+// ruleCall is a rule paired with the parameter it was given
+// in a validate tag.
+type ruleCall struct {
+	rule  Rule
+	param string
+}
+
+// spec returns the parsed field specs for the given struct
+// type, parsing and caching them on first use.
+func (v *Validator) spec(typ reflect.Type) ([]fieldSpec, error) {
+	if out, ok := v.types.Load(typ); ok {
+		return out.([]fieldSpec), nil
+	}
+
+	out := make([]fieldSpec, 0, typ.NumField())
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("validate")
+
+		if tag == "-" {
+			continue
+		}
+
+		name := jsonName(field)
+		if name == "-" {
+			name = field.Name
+		}
+
+		if field.Anonymous && name == "" && isStructType(field.Type) {
+			out = append(out, fieldSpec{index: field.Index, goName: field.Name, embedded: true})
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		spec := fieldSpec{index: field.Index, name: name, goName: field.Name}
+		if err := v.parseTag(&spec, tag, typ, field.Type); err != nil {
+			return nil, fmt.Errorf("midlvalid: invalid tag on %s.%s: %w", typ, field.Name, err)
+		}
+
+		out = append(out, spec)
+	}
+
+	v.types.Store(typ, out)
+	return out, nil
+}
+
+// parseTag parses a validate tag into the given field spec,
+// checking the parameters of built-in rules against the
+// struct and field types.
+func (v *Validator) parseTag(spec *fieldSpec, tag string, parent, typ reflect.Type) error {
+	if tag == "" {
+		return nil
+	}
+
+	target := &spec.rules
+	valueType := derefType(typ)
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		switch {
+		case name == "":
+			continue
+		case name == "omitempty":
+			spec.omitEmpty = true
+			continue
+		case name == "dive":
+			if spec.dive != nil {
+				return fmt.Errorf("dive may only be used once")
+			}
+			spec.dive = []ruleCall{}
+			target = &spec.dive
+			valueType = elemType(valueType)
+			continue
+		}
+
+		rule, ok := v.rules[name]
+		if !ok {
+			return fmt.Errorf("unknown rule %q", name)
+		}
+
+		if check, ok := v.checks[name]; ok && valueType != nil {
+			if err := check(parent, valueType, param); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+
+		call := ruleCall{rule: rule, param: param}
+		if _, ok := presenceRules[name]; ok && target == &spec.rules {
+			spec.presence = append(spec.presence, call)
+		} else {
+			*target = append(*target, call)
+		}
+	}
+
+	return nil
+}
+
+// jsonName returns the name given to the field by its json
+// struct tag, if any.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// derefType returns the type pointed to by the given type,
+// following any number of pointers.
+func derefType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// elemType returns the dereferenced element type of the
+// given collection type, or nil if it is not a collection.
+func elemType(typ reflect.Type) reflect.Type {
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return derefType(typ.Elem())
+	}
+	return nil
+}
+
+func isStructType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	return typ.Kind() == reflect.Struct
+}
@@ -0,0 +1,303 @@
+package midlvalid
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Default is the Validator used by the package level
+// functions.
+var Default = New()
+
+// Validator evaluates "validate" struct tags.
+//
+// Rules must be registered before the Validator is first
+// used; struct tags are parsed once per type and the result
+// is cached.
+type Validator struct {
+	rules  map[string]Rule
+	checks map[string]paramCheck
+	types  sync.Map
+}
+
+// New creates a new Validator instance with the built-in
+// rules registered.
+func New() *Validator {
+	out := &Validator{
+		rules:  make(map[string]Rule, len(builtins)),
+		checks: make(map[string]paramCheck, len(paramChecks)),
+	}
+
+	for name, rule := range builtins {
+		out.rules[name] = rule
+	}
+	for name, check := range paramChecks {
+		out.checks[name] = check
+	}
+
+	return out
+}
+
+// Rule registers a custom rule under the given name,
+// replacing any existing rule with that name.
+//
+// Returns the current Validator instance.
+func (v *Validator) Rule(name string, rule Rule) *Validator {
+	v.rules[name] = rule
+	delete(v.checks, name)
+	return v
+}
+
+// Validate evaluates the validate tags of the given struct
+// or struct pointer.
+//
+// Returns a *midl.ValidationError with status 422 listing
+// every invalid field, nil if all fields are valid, or a
+// plain error if the value is not a struct or its tags are
+// malformed, such as a "min" rule with a non-numeric
+// parameter or a cross-field rule naming an unknown field.
+func (v *Validator) Validate(val interface{}) error {
+	rv := reflect.ValueOf(val)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("midlvalid: cannot validate %T, expected a struct", val)
+	}
+
+	run := run{validator: v, errs: &midl.ValidationError{Status: http.StatusUnprocessableEntity}}
+	if err := run.validateStruct(rv, ""); err != nil {
+		return err
+	}
+
+	if run.errs.Empty() {
+		return nil
+	}
+
+	return run.errs
+}
+
+// Struct returns a midl.BodyProcessor which validates the
+// given struct pointer, ignoring the body bytes passed to
+// it.  It is meant to follow the BodyProcessor which decodes
+// the body into the same struct.
+func (v *Validator) Struct(target interface{}) midl.BodyProcessor {
+	return midl.BodyProcessorFunc(func([]byte) error {
+		return v.Validate(target)
+	})
+}
+
+// Decode returns a midl.BodyProcessor which decodes the body
+// into the given struct pointer using the given Decoder and
+// then validates it.
+//
+// Decode failures are returned as a *midl.HTTPError with
+// status 400 (Bad Request).
+func (v *Validator) Decode(dec midl.Decoder, target interface{}) midl.BodyProcessor {
+	return midl.BodyProcessorFunc(func(in []byte) error {
+		if err := dec.Decode(in, target); err != nil {
+			return midl.NewHTTPError(http.StatusBadRequest, "invalid request body").WithCause(err)
+		}
+
+		return v.Validate(target)
+	})
+}
+
+// Validate evaluates the validate tags of the given struct
+// using the Default Validator.
+func Validate(val interface{}) error {
+	return Default.Validate(val)
+}
+
+// Struct returns a midl.BodyProcessor which validates the
+// given struct pointer using the Default Validator.
+func Struct(target interface{}) midl.BodyProcessor {
+	return Default.Struct(target)
+}
+
+// Decode returns a midl.BodyProcessor which decodes and then
+// validates the given struct pointer using the Default
+// Validator.
+func Decode(dec midl.Decoder, target interface{}) midl.BodyProcessor {
+	return Default.Decode(dec, target)
+}
+
+// run holds the state of a single Validate call.
+type run struct {
+	validator *Validator
+	errs      *midl.ValidationError
+}
+
+func (r *run) validateStruct(val reflect.Value, path string) error {
+	fields, err := r.validator.spec(val.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range fields {
+		if err := r.validateField(val, spec, joinPath(path, spec.name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *run) validateField(parent reflect.Value, spec fieldSpec, path string) error {
+	raw := parent.FieldByIndex(spec.index)
+	field := Field{Name: spec.goName, Path: path, Value: raw, Parent: parent}
+
+	if spec.embedded {
+		return r.descend(deref(raw), path)
+	}
+
+	for _, call := range spec.presence {
+		if r.check(field, call) {
+			return nil
+		}
+	}
+
+	val := deref(raw)
+	if !val.IsValid() || (spec.omitEmpty && isEmpty(val)) {
+		return nil
+	}
+
+	field.Value = val
+	for _, call := range spec.rules {
+		if r.check(field, call) {
+			return nil
+		}
+	}
+
+	if spec.dive != nil {
+		r.dive(field, spec.dive)
+	}
+
+	return r.descend(val, path)
+}
+
+// check runs a single rule, recording its error.  Returns
+// whether the rule failed.
+func (r *run) check(field Field, call ruleCall) bool {
+	field.Param = call.param
+
+	if err := call.rule.Check(field); err != nil {
+		r.errs.Add("body", field.Path, err.Error())
+		return true
+	}
+
+	return false
+}
+
+// dive runs the given rules against every element of the
+// given field's slice, array or map value.
+func (r *run) dive(field Field, rules []ruleCall) {
+	eachElem(field.Value, field.Path, func(elem reflect.Value, path string) {
+		elem = deref(elem)
+		if !elem.IsValid() {
+			return
+		}
+
+		item := field
+		item.Path = path
+		item.Value = elem
+		for _, call := range rules {
+			if r.check(item, call) {
+				return
+			}
+		}
+	})
+}
+
+// descend validates nested structs held directly or as
+// elements of the given value.
+func (r *run) descend(val reflect.Value, path string) (err error) {
+	switch {
+	case !val.IsValid():
+	case val.Kind() == reflect.Struct && val.Type() != timeType:
+		err = r.validateStruct(val, path)
+	case val.Kind() == reflect.Slice, val.Kind() == reflect.Array, val.Kind() == reflect.Map:
+		eachElem(val, path, func(elem reflect.Value, path string) {
+			elem = deref(elem)
+			if err == nil && elem.IsValid() && elem.Kind() == reflect.Struct && elem.Type() != timeType {
+				err = r.validateStruct(elem, path)
+			}
+		})
+	}
+
+	return
+}
+
+// eachElem calls the given function with every element of
+// the given slice, array or map value along with its path.
+// Map entries are visited in key order.
+func eachElem(val reflect.Value, path string, fn func(reflect.Value, string)) {
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			fn(val.Index(i), path+"["+strconv.Itoa(i)+"]")
+		}
+	case reflect.Map:
+		keys := val.MapKeys()
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = fmt.Sprint(key.Interface())
+		}
+
+		order := make([]int, len(keys))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return names[order[a]] < names[order[b]] })
+
+		for _, i := range order {
+			fn(val.MapIndex(keys[i]), path+"["+names[i]+"]")
+		}
+	}
+}
+
+// deref follows pointers and interfaces, returning the
+// invalid Value if a nil is encountered.
+func deref(val reflect.Value) reflect.Value {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return reflect.Value{}
+		}
+		val = val.Elem()
+	}
+
+	return val
+}
+
+// isEmpty returns whether the given value is zero, nil, or
+// an empty string, slice or map.
+func isEmpty(val reflect.Value) bool {
+	val = deref(val)
+
+	switch {
+	case !val.IsValid():
+		return true
+	case val.Kind() == reflect.Slice, val.Kind() == reflect.Map, val.Kind() == reflect.String:
+		return val.Len() == 0
+	}
+
+	return val.IsZero()
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" || name == "" {
+		return parent + name
+	}
+
+	return parent + "." + name
+}
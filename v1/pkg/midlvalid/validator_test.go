@@ -0,0 +1,242 @@
+package midlvalid
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+
+	"github.com/vulpine-io/midl/v1/pkg/midl"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type audit struct {
+	Note string `json:"note" validate:"max=4"`
+}
+
+type user struct {
+	audit
+
+	Name     string             `json:"name" validate:"required,min=2,max=8"`
+	Email    string             `json:"email" validate:"omitempty,email"`
+	Role     string             `json:"role" validate:"oneof=admin user"`
+	Password string             `json:"password"`
+	Confirm  string             `json:"confirm" validate:"eqfield=Password"`
+	Tags     []string           `json:"tags" validate:"max=2,dive,min=1"`
+	Home     *address           `json:"home" validate:"required"`
+	Work     *address           `json:"work"`
+	Previous []address          `json:"previous"`
+	Labels   map[string]address `json:"labels"`
+	Ignored  string             `validate:"-"`
+}
+
+func validUser() user {
+	return user{
+		Name:     "foo",
+		Role:     "admin",
+		Password: "secret",
+		Confirm:  "secret",
+		Home:     &address{City: "here"},
+	}
+}
+
+func fieldErrors(err error) map[string]string {
+	var val *midl.ValidationError
+	if !errors.As(err, &val) {
+		return nil
+	}
+
+	out := map[string]string{}
+	for _, f := range val.Fields {
+		out[f.Field] = f.Message
+	}
+	return out
+}
+
+func TestValidator_Validate(t *testing.T) {
+	c.Convey("accepts valid structs", t, func() {
+		in := validUser()
+
+		c.So(Validate(in), c.ShouldBeNil)
+		c.So(Validate(&in), c.ShouldBeNil)
+	})
+
+	c.Convey("reports invalid fields with status 422", t, func() {
+		in := validUser()
+		in.Name = "x"
+		in.Email = "Foo <foo@bar.baz>"
+		in.Role = "root"
+		in.Confirm = "other"
+		in.Home = nil
+		in.Note = "too long"
+
+		err := Validate(&in)
+		var val *midl.ValidationError
+		c.So(errors.As(err, &val), c.ShouldBeTrue)
+		c.So(val.StatusCode(), c.ShouldEqual, http.StatusUnprocessableEntity)
+		c.So(val.Fields[0].In, c.ShouldEqual, "body")
+		c.So(fieldErrors(err), c.ShouldResemble, map[string]string{
+			"note":    "must be at most 4 characters long",
+			"name":    "must be at least 2 characters long",
+			"email":   "must be a valid email address",
+			"role":    "must be one of: admin, user",
+			"confirm": "must equal password",
+			"home":    "is required",
+		})
+	})
+
+	c.Convey("validates nested structs, slices and maps", t, func() {
+		in := validUser()
+		in.Home.City = ""
+		in.Work = &address{}
+		in.Previous = []address{{City: "a"}, {}}
+		in.Labels = map[string]address{"b": {}, "a": {City: "a"}}
+
+		c.So(fieldErrors(Validate(&in)), c.ShouldResemble, map[string]string{
+			"home.city":        "is required",
+			"work.city":        "is required",
+			"previous[1].city": "is required",
+			"labels[b].city":   "is required",
+		})
+	})
+
+	c.Convey("applies rules after dive to each element", t, func() {
+		in := validUser()
+		in.Tags = []string{"a", ""}
+
+		c.So(fieldErrors(Validate(&in)), c.ShouldResemble, map[string]string{
+			"tags[1]": "must be at least 1 characters long",
+		})
+
+		in.Tags = []string{"a", "b", "c"}
+		c.So(fieldErrors(Validate(&in)), c.ShouldResemble, map[string]string{
+			"tags": "must contain at most 2 items",
+		})
+	})
+
+	c.Convey("rejects non struct values", t, func() {
+		err := Validate("foo")
+
+		c.So(err, c.ShouldBeError)
+		c.So(fieldErrors(err), c.ShouldBeNil)
+	})
+
+	c.Convey("rejects unknown rules", t, func() {
+		err := Validate(struct {
+			Name string `validate:"bogus"`
+		}{})
+
+		c.So(err, c.ShouldBeError)
+		c.So(err.Error(), c.ShouldContainSubstring, `unknown rule "bogus"`)
+	})
+
+	c.Convey("rejects malformed rule parameters when parsing tags", t, func() {
+		for _, tc := range []struct {
+			val  interface{}
+			want string
+		}{
+			{struct {
+				Name string `validate:"min=abc"`
+			}{Name: "foo"}, `min: invalid parameter "abc"`},
+			{struct {
+				Age *uint `validate:"omitempty,max=-1"`
+			}{}, `max: invalid parameter "-1"`},
+			{struct {
+				Tags []int `validate:"dive,len=x"`
+			}{}, `len: invalid parameter "x"`},
+			{struct {
+				On bool `validate:"min=1"`
+			}{}, `min: cannot be applied to bool`},
+			{struct {
+				End int `validate:"gtfield=Start"`
+			}{}, `gtfield: unknown field "Start"`},
+		} {
+			err := Validate(tc.val)
+
+			c.So(err, c.ShouldBeError)
+			c.So(fieldErrors(err), c.ShouldBeNil)
+			c.So(err.Error(), c.ShouldContainSubstring, tc.want)
+		}
+	})
+
+	c.Convey("supports custom rules", t, func() {
+		v := New().Rule("even", RuleFunc(func(f Field) error {
+			if f.Value.Int()%2 != 0 {
+				return errors.New("must be even")
+			}
+			return nil
+		}))
+
+		type in struct {
+			N int `json:"n" validate:"even"`
+		}
+
+		c.So(v.Validate(in{N: 2}), c.ShouldBeNil)
+		c.So(fieldErrors(v.Validate(in{N: 3})), c.ShouldResemble, map[string]string{"n": "must be even"})
+		c.So(Validate(in{N: 3}), c.ShouldBeError)
+	})
+
+	c.Convey("supports custom cross-field rules", t, func() {
+		v := New().Rule("after", RuleFunc(func(f Field) error {
+			other, _ := f.Sibling(f.Param)
+			if f.Value.Int() <= other.Int() {
+				return errors.New("must be after " + f.Param)
+			}
+			return nil
+		}))
+
+		type in struct {
+			Start int `json:"start"`
+			End   int `json:"end" validate:"after=Start"`
+		}
+
+		c.So(v.Validate(in{Start: 1, End: 2}), c.ShouldBeNil)
+		c.So(fieldErrors(v.Validate(in{Start: 2, End: 1})), c.ShouldResemble,
+			map[string]string{"end": "must be after Start"})
+	})
+}
+
+func TestDecode(t *testing.T) {
+	handler := midl.MiddlewareFunc(func(req midl.Request) midl.Response {
+		var in user
+		err := req.ProcessBody(Decode(midl.DefaultDecoders()["application/json"], &in)).Error()
+		if err != nil {
+			return midl.MakeErrorResponse(http.StatusInternalServerError, err)
+		}
+		return midl.MakeResponse(http.StatusOK, in.Name)
+	})
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		midl.JSONAdapter(handler).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body)))
+		return rec
+	}
+
+	c.Convey("decodes then validates bodies", t, func() {
+		rec := serve(`{"name":"foo","role":"user","home":{"city":"here"}}`)
+
+		c.So(rec.Code, c.ShouldEqual, http.StatusOK)
+	})
+
+	c.Convey("renders validation errors as 422", t, func() {
+		rec := serve(`{"name":"foo","role":"user","home":{}}`)
+
+		var out map[string]interface{}
+		_ = json.Unmarshal(rec.Body.Bytes(), &out)
+		c.So(rec.Code, c.ShouldEqual, http.StatusUnprocessableEntity)
+		c.So(out["details"], c.ShouldResemble, []interface{}{map[string]interface{}{
+			"in": "body", "field": "home.city", "message": "is required"}})
+	})
+
+	c.Convey("renders decode errors as 400", t, func() {
+		c.So(serve(`{`).Code, c.ShouldEqual, http.StatusBadRequest)
+	})
+}